@@ -0,0 +1,89 @@
+// Package aitest 提供模拟 OpenAI 兼容 /chat/completions 接口的测试服务器
+package aitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Message 对话消息
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request 收到的对话请求
+type Request struct {
+	Model          string    `json:"model"`
+	Messages       []Message `json:"messages"`
+	MaxTokens      int       `json:"max_tokens,omitempty"`
+	ResponseFormat *struct {
+		Type string `json:"type"`
+	} `json:"response_format,omitempty"`
+}
+
+// UserPrompt 返回最后一条 user 消息的内容
+func (r Request) UserPrompt() string {
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		if r.Messages[i].Role == "user" {
+			return r.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// Responder 根据请求生成模型回复
+type Responder func(req Request) string
+
+// Server 模拟 OpenAI 兼容接口的测试服务器，测试结束时自动关闭
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	respond  Responder
+	requests []Request
+}
+
+// NewServer 创建并启动测试服务器，respond 为每个请求生成回复
+func NewServer(t testing.TB, respond Responder) *Server {
+	s := &Server{respond: respond}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /chat/completions", s.handleChat)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests 返回已收到的请求
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	reply := s.respond(req)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"object": "chat.completion",
+		"model":  req.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       Message{Role: "assistant", Content: reply},
+			"finish_reason": "stop",
+		}},
+	})
+}
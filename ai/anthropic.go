@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+
+	"hacker-news-daily/retry"
+)
+
+const (
+	anthropicVersion          = "2023-06-01"
+	defaultAnthropicMaxTokens = 4096 // Messages API 要求必须指定 max_tokens
+)
+
+type anthropicRequest struct {
+	Model      string               `json:"model"`
+	System     string               `json:"system,omitempty"`
+	Messages   []ChatMessage        `json:"messages"`
+	MaxTokens  int                  `json:"max_tokens"`
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+}
+
+// AnthropicProvider Anthropic Messages API
+type AnthropicProvider struct {
+	httpClient *resty.Client
+	baseURL    string
+	model      string
+	maxTokens  int
+}
+
+func NewAnthropicProvider(baseURL, apiKey, model string, maxTokens int) *AnthropicProvider {
+	if maxTokens <= 0 {
+		maxTokens = defaultAnthropicMaxTokens
+	}
+
+	client := resty.New().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("x-api-key", apiKey).
+		SetHeader("anthropic-version", anthropicVersion)
+
+	return &AnthropicProvider{
+		httpClient: client,
+		baseURL:    baseURL,
+		model:      model,
+		maxTokens:  maxTokens,
+	}
+}
+
+// SetTransport 设置底层 HTTP Transport，用于录制和回放请求
+func (p *AnthropicProvider) SetTransport(transport http.RoundTripper) {
+	p.httpClient.SetTransport(transport)
+}
+
+// Chat 调用 /messages 接口，system 消息通过单独的 system 参数传递
+func (p *AnthropicProvider) Chat(ctx context.Context, messages []ChatMessage) (string, error) {
+	return p.chat(ctx, messages, nil)
+}
+
+// ChatJSON 强制模型调用以 schema 为参数的工具，返回工具调用的参数 JSON
+func (p *AnthropicProvider) ChatJSON(ctx context.Context, messages []ChatMessage, schema JSONSchema) (string, error) {
+	return p.chat(ctx, messages, &schema)
+}
+
+func (p *AnthropicProvider) chat(ctx context.Context, messages []ChatMessage, schema *JSONSchema) (string, error) {
+	systemPrompt, chatMessages := splitSystemPrompt(messages)
+	request := anthropicRequest{
+		Model:     p.model,
+		System:    systemPrompt,
+		Messages:  chatMessages,
+		MaxTokens: p.maxTokens,
+	}
+	if schema != nil {
+		request.Tools = []anthropicTool{{Name: schema.Name, Description: "按要求的结构输出结果", InputSchema: schema.Schema}}
+		request.ToolChoice = &anthropicToolChoice{Type: "tool", Name: schema.Name}
+	}
+
+	var response anthropicResponse
+	resp, err := p.httpClient.R().
+		SetContext(ctx).
+		SetBody(request).
+		SetResult(&response).
+		Post(p.baseURL + "/messages")
+
+	if err != nil {
+		return "", retry.Network(fmt.Errorf("failed to call AI API: %w", err), true)
+	}
+
+	if resp.StatusCode() != 200 {
+		return "", retry.Status(fmt.Errorf("AI API returned status code: %d, body: %s", resp.StatusCode(), resp.String()), resp.StatusCode(), resp.Header(), true)
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		switch {
+		case schema != nil && block.Type == "tool_use" && block.Name == schema.Name:
+			return string(block.Input), nil
+		case block.Type == "text":
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no response from AI")
+	}
+
+	return text.String(), nil
+}
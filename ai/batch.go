@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"hacker-news-daily/hackernews"
+)
+
+const (
+	promptOverheadTokens = 200 // 用户 prompt 固定说明文字和消息格式的预留
+	separatorTokens      = 5   // 故事之间分隔符的预留
+	outputTokensPerStory = 400 // 每个故事段落总结的预估输出长度
+)
+
+// EstimateTokens 粗略估算文本的 token 数：中日韩字符按每字 1 个 token，其余按每 4 个字符 1 个 token
+func EstimateTokens(text string) int {
+	var cjk, other int
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// truncateToTokens 将文本截断到估算 token 数不超过 maxTokens
+func truncateToTokens(text string, maxTokens int) string {
+	if EstimateTokens(text) <= maxTokens {
+		return text
+	}
+
+	const suffix = "……（内容已截断）"
+	limit := float64(maxTokens - EstimateTokens(suffix))
+	var used float64
+	for i, r := range text {
+		if isCJK(r) {
+			used++
+		} else {
+			used += 0.25
+		}
+		if used > limit {
+			return strings.TrimSpace(text[:i]) + suffix
+		}
+	}
+	return text
+}
+
+// splitIntoBatches 按 token 预算把故事分批，每批估算 token 数不超过 budget，
+// maxPerBatch 大于 0 时同时限制每批故事数量；超出预算的单个故事会被截断
+func splitIntoBatches(stories []string, budget, maxPerBatch int) [][]string {
+	var batches [][]string
+	var current []string
+	var currentTokens int
+
+	for _, story := range stories {
+		tokens := EstimateTokens(story) + separatorTokens
+		if tokens > budget {
+			story = truncateToTokens(story, budget-separatorTokens)
+			tokens = EstimateTokens(story) + separatorTokens
+		}
+
+		if len(current) > 0 && (currentTokens+tokens > budget || (maxPerBatch > 0 && len(current) >= maxPerBatch)) {
+			batches = append(batches, current)
+			current = nil
+			currentTokens = 0
+		}
+		current = append(current, story)
+		currentTokens += tokens
+	}
+
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// batchStories 根据上下文窗口把故事分批，未配置上下文窗口时全部放在一批
+func (c *Client) batchStories(systemPrompt string, stories []string) [][]string {
+	if c.contextLimit <= 0 {
+		return [][]string{stories}
+	}
+
+	budget := c.contextLimit - c.maxOutputTokens - EstimateTokens(systemPrompt) - promptOverheadTokens
+	if budget <= 0 {
+		return [][]string{stories}
+	}
+
+	var maxPerBatch int
+	if c.maxOutputTokens > 0 {
+		maxPerBatch = max(1, c.maxOutputTokens/outputTokensPerStory)
+	}
+	return splitIntoBatches(stories, budget, maxPerBatch)
+}
+
+// mergeStorySummaries 合并各批次的总结，按编号排序并去除重复编号
+func mergeStorySummaries(summaries []hackernews.StoryWithNumber) []hackernews.StoryWithNumber {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Number < summaries[j].Number
+	})
+
+	merged := make([]hackernews.StoryWithNumber, 0, len(summaries))
+	for _, summary := range summaries {
+		if len(merged) > 0 && merged[len(merged)-1].Number == summary.Number {
+			continue
+		}
+		merged = append(merged, summary)
+	}
+	return merged
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
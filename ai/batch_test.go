@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/cache"
+	"hacker-news-daily/hackernews"
+)
+
+// fakeProvider 按 prompt 中出现的故事编号返回 JSON 格式的总结，并记录调用次数
+type fakeProvider struct {
+	calls int
+}
+
+var storyNumberPattern = regexp.MustCompile(`故事 (\d+):`)
+
+func (p *fakeProvider) Chat(_ context.Context, messages []ChatMessage) (string, error) {
+	p.calls++
+	var items []string
+	for _, match := range storyNumberPattern.FindAllStringSubmatch(messages[len(messages)-1].Content, -1) {
+		items = append(items, fmt.Sprintf(`{"number": %s, "title": "标题", "summary": "第 %s 个故事的总结"}`, match[1], match[1]))
+	}
+	return fmt.Sprintf(`{"stories": [%s]}`, strings.Join(items, ",")), nil
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 4, EstimateTokens("中文测试"))
+	assert.Equal(t, 2, EstimateTokens("abcdefgh"))
+	assert.Equal(t, 3, EstimateTokens("中文abcd"))
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	assert.Equal(t, "short", truncateToTokens("short", 10))
+
+	truncated := truncateToTokens(strings.Repeat("长", 100), 30)
+	assert.LessOrEqual(t, EstimateTokens(truncated), 30)
+	assert.True(t, strings.HasSuffix(truncated, "（内容已截断）"))
+}
+
+func TestSplitIntoBatches(t *testing.T) {
+	stories := []string{strings.Repeat("a", 400), strings.Repeat("b", 400), strings.Repeat("c", 400)}
+
+	// 每个故事约 105 token，预算 250 时每批最多两个
+	batches := splitIntoBatches(stories, 250, 0)
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], 2)
+	assert.Len(t, batches[1], 1)
+
+	// 限制每批故事数量
+	assert.Len(t, splitIntoBatches(stories, 10000, 1), 3)
+
+	// 超出预算的单个故事会被截断
+	batches = splitIntoBatches([]string{strings.Repeat("长", 1000)}, 100, 0)
+	require.Len(t, batches, 1)
+	assert.LessOrEqual(t, EstimateTokens(batches[0][0]), 100)
+}
+
+func TestMergeStorySummaries(t *testing.T) {
+	merged := mergeStorySummaries([]hackernews.StoryWithNumber{
+		{Number: 3, Summary: "c"},
+		{Number: 1, Summary: "a"},
+		{Number: 3, Summary: "duplicate"},
+		{Number: 2, Summary: "b"},
+	})
+
+	require.Len(t, merged, 3)
+	for i, summary := range merged {
+		assert.Equal(t, i+1, summary.Number)
+	}
+	assert.Equal(t, "c", merged[2].Summary)
+}
+
+// TestSummarizeStoriesWithNumbersBatching 测试超出上下文窗口时分批请求并合并结果
+func TestSummarizeStoriesWithNumbersBatching(t *testing.T) {
+	var contents []string
+	var stories []hackernews.Story
+	for i := 1; i <= 5; i++ {
+		contents = append(contents, strings.Repeat("评论内容", 200))
+		stories = append(stories, hackernews.Story{ID: 100 + i, Title: fmt.Sprintf("Story %d", i)})
+	}
+
+	provider := &fakeProvider{}
+	client := NewClientWithProvider(provider)
+	client.SetTokenBudget(3000, 400)
+
+	summary, err := client.SummarizeStoriesWithNumbers(context.Background(), contents, stories, "2024-01-15")
+	require.NoError(t, err)
+
+	assert.Greater(t, provider.calls, 1, "内容超出上下文窗口时应分批请求")
+	require.Len(t, summary.StorySummaries, 5)
+	for i, storySummary := range summary.StorySummaries {
+		assert.Equal(t, i+1, storySummary.Number)
+		assert.Equal(t, stories[i].ID, storySummary.StoryID)
+	}
+
+	// 未配置上下文窗口时只请求一次
+	provider.calls = 0
+	client.SetTokenBudget(0, 400)
+	_, err = client.SummarizeStoriesWithNumbers(context.Background(), contents, stories, "2024-01-15")
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestGenerateDetailedSummaryUsesCache(t *testing.T) {
+	detailedCache, err := cache.New(time.Hour, "")
+	require.NoError(t, err)
+
+	provider := &fakeProvider{}
+	client := NewClientWithProvider(provider)
+	client.SetCache(detailedCache, "openai/gpt-4o")
+
+	story := hackernews.Story{ID: 1, Title: "Go 1.22"}
+	for range 2 {
+		_, err := client.GenerateDetailedSummary(context.Background(), story, "内容")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, provider.calls)
+
+	// 不同模型不复用缓存
+	other := NewClientWithProvider(provider)
+	other.SetCache(detailedCache, "anthropic/claude")
+	_, err = other.GenerateDetailedSummary(context.Background(), story, "内容")
+	require.NoError(t, err)
+	assert.Equal(t, 2, provider.calls)
+}
@@ -1,59 +1,92 @@
 package ai
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"hacker-news-daily/cache"
 	"hacker-news-daily/hackernews"
-
-	"github.com/go-resty/resty/v2"
+	"hacker-news-daily/retry"
 )
 
+// defaultRequestTimeout 单次模型请求的默认超时时间
+const defaultRequestTimeout = 3 * time.Minute
+
+// Summarizer 故事总结接口，telegram.Bot 通过该接口生成总结
+type Summarizer interface {
+	// SummarizeStoriesWithNumbers 生成带编号的故事总结
+	SummarizeStoriesWithNumbers(ctx context.Context, stories []string, storiesInfo []hackernews.Story, date string) (*hackernews.DailySummaryWithNumbers, error)
+	// GenerateDetailedSummary 生成单个故事的详细总结
+	GenerateDetailedSummary(ctx context.Context, story hackernews.Story, content string) (string, error)
+	// TranslateSummaries 将故事总结翻译为指定语言
+	TranslateSummaries(ctx context.Context, summaries []hackernews.StoryWithNumber, language string) ([]hackernews.StoryWithNumber, error)
+	// SummarizePeriod 根据一段时期内热门故事的总结生成趋势概述
+	SummarizePeriod(ctx context.Context, summaries []hackernews.StoryWithNumber, period string) (string, error)
+}
+
 type Client struct {
-	httpClient *resty.Client
-	baseURL    string
-	apiKey     string
-	model      string
-	maxTokens  int
+	provider        Provider
+	contextLimit    int // 模型上下文窗口 token 数，为 0 时不分批
+	maxOutputTokens int // 每次请求预留的输出 token 数
+	cache           *cache.Cache
+	cacheModel      string // 缓存键中的模型标识，切换模型后不复用旧结果
+	retrier         *retry.Retrier
+	requestTimeout  time.Duration // 单次模型请求的超时时间，重试时每次请求单独计时
 }
 
-type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// NewClient 创建使用 OpenAI 兼容接口的客户端
+func NewClient(baseURL, apiKey, model string, maxTokens int) *Client {
+	return NewClientWithProvider(NewOpenAIProvider(baseURL, apiKey, model, maxTokens))
 }
 
-type ChatRequest struct {
-	Model     string        `json:"model"`
-	Messages  []ChatMessage `json:"messages"`
-	MaxTokens int           `json:"max_tokens,omitempty"`
+// NewClientWithProvider 创建使用指定大模型后端的客户端
+func NewClientWithProvider(provider Provider) *Client {
+	return &Client{
+		provider:       provider,
+		retrier:        retry.New("ai", retry.Config{}),
+		requestTimeout: defaultRequestTimeout,
+	}
 }
 
-type ChatResponse struct {
-	Choices []struct {
-		Message ChatMessage `json:"message"`
-	} `json:"choices"`
-	Usage struct {
-		TotalTokens int `json:"total_tokens"`
-	} `json:"usage"`
+// SetTokenBudget 设置模型上下文窗口和输出预留，故事内容超出窗口时分批总结
+func (c *Client) SetTokenBudget(contextLimit, maxOutputTokens int) {
+	c.contextLimit = contextLimit
+	c.maxOutputTokens = maxOutputTokens
 }
 
-func NewClient(baseURL, apiKey, model string, maxTokens int) *Client {
-	client := resty.New().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", "Bearer "+apiKey)
+// SetCache 设置详细总结缓存，model 作为缓存键的一部分
+func (c *Client) SetCache(summaryCache *cache.Cache, model string) {
+	c.cache = summaryCache
+	c.cacheModel = model
+}
 
-	return &Client{
-		httpClient: client,
-		baseURL:    baseURL,
-		apiKey:     apiKey,
-		model:      model,
-		maxTokens:  maxTokens,
+// SetRetryConfig 设置模型请求失败时的重试和熔断策略
+func (c *Client) SetRetryConfig(config retry.Config) {
+	c.retrier = retry.New("ai", config)
+}
+
+// SetTransport 设置模型请求使用的 HTTP Transport，用于录制和回放请求；后端不支持时忽略
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	if provider, ok := c.provider.(interface{ SetTransport(http.RoundTripper) }); ok {
+		provider.SetTransport(transport)
+	}
+}
+
+// SetRequestTimeout 设置单次模型请求的超时时间，不大于 0 时使用默认值
+func (c *Client) SetRequestTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
 	}
+	c.requestTimeout = timeout
 }
 
 // SummarizeStories 总结多个故事
-func (c *Client) SummarizeStories(stories []string, date string) (string, error) {
+func (c *Client) SummarizeStories(ctx context.Context, stories []string, date string) (string, error) {
 	systemPrompt := `你是 Hacker News 中文播客的编辑，擅长将技术文章和讨论整理成引人入胜的内容。
 
 工作目标：
@@ -75,38 +108,11 @@ func (c *Client) SummarizeStories(stories []string, date string) (string, error)
 	userPrompt := fmt.Sprintf("请为以下 %s 的 Hacker News 热门故事分别生成独立的段落总结。每个故事应该生成一个完整的段落，包含标题、内容要点和评论精华：\n\n%s",
 		date, strings.Join(stories, "\n\n---\n\n"))
 
-	request := ChatRequest{
-		Model: c.model,
-		Messages: []ChatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		MaxTokens: c.maxTokens,
-	}
-
-	var response ChatResponse
-	resp, err := c.httpClient.R().
-		SetBody(request).
-		SetResult(&response).
-		Post(c.baseURL + "/chat/completions")
-
-	if err != nil {
-		return "", fmt.Errorf("failed to call AI API: %w", err)
-	}
-
-	if resp.StatusCode() != 200 {
-		return "", fmt.Errorf("AI API returned status code: %d, body: %s", resp.StatusCode(), resp.String())
-	}
-
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response from AI")
-	}
-
-	return response.Choices[0].Message.Content, nil
+	return c.chat(ctx, systemPrompt, userPrompt)
 }
 
 // CreateDailySummary 创建每日总结
-func (c *Client) CreateDailySummary(storySummaries string, date string) (string, error) {
+func (c *Client) CreateDailySummary(ctx context.Context, storySummaries string, date string) (string, error) {
 	systemPrompt := `你是 Hacker News 每日总结的编辑，负责将已经按故事分段的内容整合为一份完整的每日报告。
 
 工作目标：
@@ -124,38 +130,11 @@ func (c *Client) CreateDailySummary(storySummaries string, date string) (string,
 
 	userPrompt := fmt.Sprintf("请将以下 %s 的故事段落总结整合为一份完整的每日报告。请保持每个故事段落的完整性，并在开头添加适当的介绍：\n\n%s", date, storySummaries)
 
-	request := ChatRequest{
-		Model: c.model,
-		Messages: []ChatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		MaxTokens: c.maxTokens,
-	}
-
-	var response ChatResponse
-	resp, err := c.httpClient.R().
-		SetBody(request).
-		SetResult(&response).
-		Post(c.baseURL + "/chat/completions")
-
-	if err != nil {
-		return "", fmt.Errorf("failed to call AI API: %w", err)
-	}
-
-	if resp.StatusCode() != 200 {
-		return "", fmt.Errorf("AI API returned status code: %d, body: %s", resp.StatusCode(), resp.String())
-	}
-
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response from AI")
-	}
-
-	return response.Choices[0].Message.Content, nil
+	return c.chat(ctx, systemPrompt, userPrompt)
 }
 
 // SummarizeStoriesWithNumbers 生成带编号的故事总结
-func (c *Client) SummarizeStoriesWithNumbers(stories []string, storiesInfo []hackernews.Story, date string) (*hackernews.DailySummaryWithNumbers, error) {
+func (c *Client) SummarizeStoriesWithNumbers(ctx context.Context, stories []string, storiesInfo []hackernews.Story, date string) (*hackernews.DailySummaryWithNumbers, error) {
 	systemPrompt := `你是 Hacker News 中文播客的编辑，擅长将技术文章和讨论整理成引人入胜的内容。
 
 工作目标：
@@ -166,65 +145,66 @@ func (c *Client) SummarizeStoriesWithNumbers(stories []string, storiesInfo []hac
 - 用简洁明了的中文呈现，专业术语可保留英文
 
 输出要求：
-- 每个故事生成一个独立的段落，段落之间用空行分隔
-- 每个段落以故事编号开头，格式为：[编号] **标题名称**
-- 段落内容应该是连贯的文字描述，不使用列表或子标题
+- 只输出一个 JSON 对象，不要输出 markdown 代码块或任何其他文字
+- JSON 格式为：{"stories": [{"number": 1, "story_id": 12345, "title": "标题名称", "summary": "段落总结", "tags": ["标签"]}]}
+- number 和 story_id 必须与输入中的故事编号和 ID 一致，每个输入的故事都必须出现且只出现一次
+- title 为简洁的中文标题，专业名词可保留英文
+- summary 为连贯的文字描述，不使用列表或子标题，不重复标题
+- tags 为 1-3 个简短的主题标签
 - 内容要有洞察力和可读性，适合技术从业者快速了解
 - 避免政治敏感内容
 - 重点关注技术趋势、产品发布、行业动态、开发者讨论等
-- 每个段落长度控制在150-300字之间`
+- 每个 summary 长度控制在150-300字之间`
 
 	// 构建包含故事信息的prompt
 	var storiesWithInfo []string
 	for i, story := range stories {
-		storyInfo := fmt.Sprintf("故事 %d:\n标题: %s\nURL: %s\n分数: %d\n作者: %s\n内容:\n%s",
-			i+1, storiesInfo[i].Title, storiesInfo[i].URL, storiesInfo[i].Score, storiesInfo[i].By, story)
+		storyInfo := fmt.Sprintf("故事 %d:\nID: %d\n标题: %s\nURL: %s\n分数: %d\n评论数: %d\n作者: %s\n内容:\n%s",
+			i+1, storiesInfo[i].ID, storiesInfo[i].Title, storiesInfo[i].URL, storiesInfo[i].Score, storiesInfo[i].NumComments, storiesInfo[i].By, story)
 		storiesWithInfo = append(storiesWithInfo, storyInfo)
 	}
 
-	userPrompt := fmt.Sprintf("请为以下 %s 的 Hacker News 热门故事分别生成带编号的段落总结。每个故事应该生成一个完整的段落，包含编号、标题、内容要点和评论精华：\n\n%s",
-		date, strings.Join(storiesWithInfo, "\n\n---\n\n"))
-
-	request := ChatRequest{
-		Model: c.model,
-		Messages: []ChatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		MaxTokens: c.maxTokens,
-	}
-
-	var response ChatResponse
-	resp, err := c.httpClient.R().
-		SetBody(request).
-		SetResult(&response).
-		Post(c.baseURL + "/chat/completions")
+	// 按 token 预算分批生成总结，再按编号合并
+	batches := c.batchStories(systemPrompt, storiesWithInfo)
+	var storySummaries []hackernews.StoryWithNumber
+	var offset int
+	for i, batch := range batches {
+		if len(batches) > 1 {
+			log.Printf("Summarizing batch %d/%d with %d stories", i+1, len(batches), len(batch))
+		}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to call AI API: %w", err)
-	}
+		expected := make([]int, len(batch))
+		for j := range batch {
+			expected[j] = offset + j + 1
+		}
+		offset += len(batch)
 
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("AI API returned status code: %d, body: %s", resp.StatusCode(), resp.String())
-	}
+		// 按编号生成请求内容，缺少故事时只重新请求缺少的部分
+		userPrompt := func(numbers []int) string {
+			selected := make([]string, len(numbers))
+			for j, number := range numbers {
+				selected[j] = storiesWithInfo[number-1]
+			}
+			return fmt.Sprintf("请为以下 %s 的 Hacker News 热门故事分别生成带编号的段落总结，并按要求的 JSON 格式输出。每个故事应该生成一个完整的段落，包含内容要点和评论精华：\n\n%s",
+				date, strings.Join(selected, "\n\n---\n\n"))
+		}
 
-	if len(response.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
+		summaries, err := c.summarizeBatch(ctx, systemPrompt, userPrompt, storiesInfo, expected)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize batch %d/%d: %w", i+1, len(batches), err)
+		}
+		storySummaries = append(storySummaries, summaries...)
 	}
 
-	// 解析AI返回的带编号总结
-	summaryText := response.Choices[0].Message.Content
-	storySummaries := c.parseNumberedSummaries(summaryText, storiesInfo)
-
 	return &hackernews.DailySummaryWithNumbers{
 		Date:           date,
 		Stories:        storiesInfo,
-		StorySummaries: storySummaries,
+		StorySummaries: mergeStorySummaries(storySummaries),
 	}, nil
 }
 
 // GenerateDetailedSummary 生成单个故事的详细总结
-func (c *Client) GenerateDetailedSummary(story hackernews.Story, content string) (string, error) {
+func (c *Client) GenerateDetailedSummary(ctx context.Context, story hackernews.Story, content string) (string, error) {
 	systemPrompt := `你是 Hacker News 深度分析专家，擅长对技术故事进行深入剖析和详细总结。
 
 工作目标：
@@ -258,34 +238,33 @@ URL: %s
 
 请按照要求的结构生成详细的技术分析总结。`, story.Title, story.URL, story.Score, story.By, content)
 
-	request := ChatRequest{
-		Model: c.model,
-		Messages: []ChatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		MaxTokens: c.maxTokens,
-	}
-
-	var response ChatResponse
-	resp, err := c.httpClient.R().
-		SetBody(request).
-		SetResult(&response).
-		Post(c.baseURL + "/chat/completions")
-
-	if err != nil {
-		return "", fmt.Errorf("failed to call AI API: %w", err)
-	}
-
-	if resp.StatusCode() != 200 {
-		return "", fmt.Errorf("AI API returned status code: %d, body: %s", resp.StatusCode(), resp.String())
-	}
+	// 同一故事的并发请求只调用一次模型
+	key := fmt.Sprintf("ai:detailed:%s:%d", c.cacheModel, story.ID)
+	return cache.Load(c.cache, key, func() (string, error) {
+		return c.chat(ctx, systemPrompt, userPrompt)
+	})
+}
 
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response from AI")
-	}
+// chat 发送 system 和 user 消息并返回模型回复
+func (c *Client) chat(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return c.send(ctx, c.provider.Chat, []ChatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	})
+}
 
-	return response.Choices[0].Message.Content, nil
+// send 调用模型后端，每次请求单独设置超时，后端返回临时错误时按重试策略重试
+func (c *Client) send(ctx context.Context, call func(context.Context, []ChatMessage) (string, error), messages []ChatMessage) (string, error) {
+	var reply string
+	err := c.retrier.Do(ctx, func() error {
+		requestCtx, cancel := context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+
+		var err error
+		reply, err = call(requestCtx, messages)
+		return err
+	})
+	return reply, err
 }
 
 // parseNumberedSummaries 解析AI返回的带编号总结
@@ -342,7 +321,8 @@ func (c *Client) parseNumberedSummaries(summaryText string, stories []hackernews
 
 // isNumberedStoryLine 检查是否是带编号的故事行
 func (c *Client) isNumberedStoryLine(line string) []int {
-	// 匹配格式: [1] **标题** 或 [1] 标题
+	// 匹配格式: [1] **标题**、[1] 标题 或 **[1]** 标题
+	line = strings.TrimLeft(line, "*# ")
 	if strings.HasPrefix(line, "[") {
 		parts := strings.SplitN(line, "]", 2)
 		if len(parts) == 2 {
@@ -357,9 +337,9 @@ func (c *Client) isNumberedStoryLine(line string) []int {
 // cleanNumberedLine 清理编号行的格式
 func (c *Client) cleanNumberedLine(line string, _ int) string {
 	// 去除编号部分，保留标题和内容
-	parts := strings.SplitN(line, "]", 2)
+	parts := strings.SplitN(strings.TrimLeft(line, "*# "), "]", 2)
 	if len(parts) == 2 {
-		return strings.TrimSpace(parts[1])
+		return strings.TrimSpace(strings.TrimPrefix(parts[1], "**"))
 	}
 	return line
 }
@@ -1,15 +1,19 @@
 package ai
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/ai/aitest"
 )
 
 // TestSummarizeStoriesInputValidation 测试SummarizeStories输入验证
 func TestSummarizeStoriesInputValidation(t *testing.T) {
-	// 创建客户端但不进行实际API调用
-	client := NewClient("", "", "gpt-4o", 2000)
+	server := aitest.NewServer(t, func(aitest.Request) string { return "**Go 1.21 发布**\n\n总结内容" })
+	client := NewClient(server.URL, "", "gpt-4o", 2000)
 
 	tests := []struct {
 		name    string
@@ -52,11 +56,18 @@ func TestSummarizeStoriesInputValidation(t *testing.T) {
 				t.Skip("跳过实际API调用测试")
 			}
 
-			// 这里可以添加实际的测试逻辑，但目前跳过
-			data, err := client.SummarizeStories(tt.stories, tt.date)
-
-			assert.NoError(t, err, "预期正常输入不会出错")
-			t.Logf("生成的总结内容:\n%s", data)
+			data, err := client.SummarizeStories(context.Background(), tt.stories, tt.date)
+			require.NoError(t, err, "预期正常输入不会出错")
+			assert.Equal(t, "**Go 1.21 发布**\n\n总结内容", data)
+
+			// 提示词包含日期和所有故事
+			requests := server.Requests()
+			require.NotEmpty(t, requests)
+			prompt := requests[len(requests)-1].UserPrompt()
+			assert.Contains(t, prompt, tt.date)
+			for _, story := range tt.stories {
+				assert.Contains(t, prompt, story)
+			}
 
 		})
 	}
@@ -64,8 +75,8 @@ func TestSummarizeStoriesInputValidation(t *testing.T) {
 
 // TestCreateDailySummaryInputValidation 测试CreateDailySummary输入验证
 func TestCreateDailySummaryInputValidation(t *testing.T) {
-	// 创建客户端但不进行实际API调用
-	client := NewClient("", "", "gpt-4o", 2000)
+	server := aitest.NewServer(t, func(aitest.Request) string { return "今日要点\n\n测试总结内容" })
+	client := NewClient(server.URL, "", "gpt-4o", 2000)
 
 	tests := []struct {
 		name           string
@@ -105,11 +116,13 @@ Go语言发布了1.21版本，带来了多项重要更新...
 				t.Skip("跳过实际API调用测试")
 			}
 
-			// 这里可以添加实际的测试逻辑，但目前跳过
-			data, err := client.CreateDailySummary(tt.storySummaries, tt.date)
+			data, err := client.CreateDailySummary(context.Background(), tt.storySummaries, tt.date)
+			require.NoError(t, err, "预期正常输入不会出错")
+			assert.Equal(t, "今日要点\n\n测试总结内容", data)
 
-			assert.NoError(t, err, "预期正常输入不会出错")
-			t.Logf("生成的每日总结内容:\n%s", data)
+			requests := server.Requests()
+			require.NotEmpty(t, requests)
+			assert.Contains(t, requests[len(requests)-1].UserPrompt(), tt.storySummaries)
 		})
 	}
 }
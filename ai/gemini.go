@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+
+	"hacker-news-daily/retry"
+)
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent `json:"contents"`
+	GenerationConfig  struct {
+		MaxOutputTokens    int            `json:"maxOutputTokens,omitempty"`
+		ResponseMimeType   string         `json:"responseMimeType,omitempty"`
+		ResponseJSONSchema map[string]any `json:"responseJsonSchema,omitempty"`
+	} `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+}
+
+// GeminiProvider Google Gemini generateContent 接口
+type GeminiProvider struct {
+	httpClient *resty.Client
+	baseURL    string
+	model      string
+	maxTokens  int
+}
+
+func NewGeminiProvider(baseURL, apiKey, model string, maxTokens int) *GeminiProvider {
+	client := resty.New().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("x-goog-api-key", apiKey)
+
+	return &GeminiProvider{
+		httpClient: client,
+		baseURL:    baseURL,
+		model:      model,
+		maxTokens:  maxTokens,
+	}
+}
+
+// SetTransport 设置底层 HTTP Transport，用于录制和回放请求
+func (p *GeminiProvider) SetTransport(transport http.RoundTripper) {
+	p.httpClient.SetTransport(transport)
+}
+
+// Chat 调用 /models/{model}:generateContent 接口，assistant 角色在 Gemini 中称为 model
+func (p *GeminiProvider) Chat(ctx context.Context, messages []ChatMessage) (string, error) {
+	return p.chat(ctx, messages, nil)
+}
+
+// ChatJSON 通过 responseMimeType 和 responseJsonSchema 要求模型按 schema 返回 JSON
+func (p *GeminiProvider) ChatJSON(ctx context.Context, messages []ChatMessage, schema JSONSchema) (string, error) {
+	return p.chat(ctx, messages, schema.Schema)
+}
+
+func (p *GeminiProvider) chat(ctx context.Context, messages []ChatMessage, schema map[string]any) (string, error) {
+	systemPrompt, chatMessages := splitSystemPrompt(messages)
+
+	var request geminiRequest
+	if systemPrompt != "" {
+		request.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: systemPrompt}}}
+	}
+	for _, message := range chatMessages {
+		role := message.Role
+		if role == "assistant" {
+			role = "model"
+		}
+		request.Contents = append(request.Contents, geminiContent{
+			Role:  role,
+			Parts: []geminiPart{{Text: message.Content}},
+		})
+	}
+	request.GenerationConfig.MaxOutputTokens = p.maxTokens
+	if schema != nil {
+		request.GenerationConfig.ResponseMimeType = "application/json"
+		request.GenerationConfig.ResponseJSONSchema = schema
+	}
+
+	var response geminiResponse
+	resp, err := p.httpClient.R().
+		SetContext(ctx).
+		SetBody(request).
+		SetResult(&response).
+		SetPathParam("model", p.model).
+		Post(p.baseURL + "/models/{model}:generateContent")
+
+	if err != nil {
+		return "", retry.Network(fmt.Errorf("failed to call AI API: %w", err), true)
+	}
+
+	if resp.StatusCode() != 200 {
+		return "", retry.Status(fmt.Errorf("AI API returned status code: %d, body: %s", resp.StatusCode(), resp.String()), resp.StatusCode(), resp.Header(), true)
+	}
+
+	if len(response.Candidates) == 0 {
+		return "", fmt.Errorf("no response from AI")
+	}
+
+	var text strings.Builder
+	for _, part := range response.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+
+	return text.String(), nil
+}
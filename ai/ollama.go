@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+
+	"hacker-news-daily/retry"
+)
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   any           `json:"format,omitempty"`
+	Options  struct {
+		NumPredict int `json:"num_predict,omitempty"`
+	} `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message ChatMessage `json:"message"`
+	Error   string      `json:"error"`
+}
+
+// OllamaProvider 本地 Ollama 的 /api/chat 接口
+type OllamaProvider struct {
+	httpClient *resty.Client
+	baseURL    string
+	model      string
+	maxTokens  int
+}
+
+func NewOllamaProvider(baseURL, model string, maxTokens int) *OllamaProvider {
+	client := resty.New().
+		SetHeader("Content-Type", "application/json")
+
+	return &OllamaProvider{
+		httpClient: client,
+		baseURL:    baseURL,
+		model:      model,
+		maxTokens:  maxTokens,
+	}
+}
+
+// SetTransport 设置底层 HTTP Transport，用于录制和回放请求
+func (p *OllamaProvider) SetTransport(transport http.RoundTripper) {
+	p.httpClient.SetTransport(transport)
+}
+
+// Chat 以非流式方式调用 /api/chat 接口
+func (p *OllamaProvider) Chat(ctx context.Context, messages []ChatMessage) (string, error) {
+	return p.chat(ctx, messages, nil)
+}
+
+// ChatJSON 通过 format 参数传入 JSON Schema，要求模型按 schema 返回 JSON
+func (p *OllamaProvider) ChatJSON(ctx context.Context, messages []ChatMessage, schema JSONSchema) (string, error) {
+	return p.chat(ctx, messages, schema.Schema)
+}
+
+func (p *OllamaProvider) chat(ctx context.Context, messages []ChatMessage, format map[string]any) (string, error) {
+	request := ollamaRequest{
+		Model:    p.model,
+		Messages: messages,
+		Stream:   false,
+	}
+	if format != nil {
+		request.Format = format
+	}
+	request.Options.NumPredict = p.maxTokens
+
+	var response ollamaResponse
+	resp, err := p.httpClient.R().
+		SetContext(ctx).
+		SetBody(request).
+		SetResult(&response).
+		Post(p.baseURL + "/api/chat")
+
+	if err != nil {
+		return "", retry.Network(fmt.Errorf("failed to call AI API: %w", err), true)
+	}
+
+	if resp.StatusCode() != 200 {
+		return "", retry.Status(fmt.Errorf("AI API returned status code: %d, body: %s", resp.StatusCode(), resp.String()), resp.StatusCode(), resp.Header(), true)
+	}
+
+	if response.Error != "" {
+		return "", fmt.Errorf("ollama returned error: %s", response.Error)
+	}
+
+	if response.Message.Content == "" {
+		return "", fmt.Errorf("no response from AI")
+	}
+
+	return response.Message.Content, nil
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+
+	"hacker-news-daily/retry"
+)
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ChatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []ChatMessage   `json:"messages"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+type ResponseFormat struct {
+	Type       string              `json:"type"`
+	JSONSchema *ResponseJSONSchema `json:"json_schema,omitempty"`
+}
+
+// ResponseJSONSchema 结构化输出使用的 schema，strict 模式下模型输出必须完全符合 schema
+type ResponseJSONSchema struct {
+	Name   string         `json:"name"`
+	Strict bool           `json:"strict"`
+	Schema map[string]any `json:"schema"`
+}
+
+type ChatResponse struct {
+	Choices []struct {
+		Message ChatMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// OpenAIProvider OpenAI 兼容的 /chat/completions 接口
+type OpenAIProvider struct {
+	httpClient *resty.Client
+	baseURL    string
+	model      string
+	maxTokens  int
+}
+
+func NewOpenAIProvider(baseURL, apiKey, model string, maxTokens int) *OpenAIProvider {
+	client := resty.New().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", "Bearer "+apiKey)
+
+	return &OpenAIProvider{
+		httpClient: client,
+		baseURL:    baseURL,
+		model:      model,
+		maxTokens:  maxTokens,
+	}
+}
+
+// SetTransport 设置底层 HTTP Transport，用于录制和回放请求
+func (p *OpenAIProvider) SetTransport(transport http.RoundTripper) {
+	p.httpClient.SetTransport(transport)
+}
+
+// Chat 调用 /chat/completions 接口
+func (p *OpenAIProvider) Chat(ctx context.Context, messages []ChatMessage) (string, error) {
+	return p.chat(ctx, messages, nil)
+}
+
+// ChatJSON 通过 json_schema 类型的 response_format 要求模型按 schema 返回 JSON
+func (p *OpenAIProvider) ChatJSON(ctx context.Context, messages []ChatMessage, schema JSONSchema) (string, error) {
+	return p.chat(ctx, messages, &ResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &ResponseJSONSchema{Name: schema.Name, Strict: true, Schema: schema.Schema},
+	})
+}
+
+func (p *OpenAIProvider) chat(ctx context.Context, messages []ChatMessage, responseFormat *ResponseFormat) (string, error) {
+	request := ChatRequest{
+		Model:          p.model,
+		Messages:       messages,
+		MaxTokens:      p.maxTokens,
+		ResponseFormat: responseFormat,
+	}
+
+	var response ChatResponse
+	resp, err := p.httpClient.R().
+		SetContext(ctx).
+		SetBody(request).
+		SetResult(&response).
+		Post(p.baseURL + "/chat/completions")
+
+	if err != nil {
+		return "", retry.Network(fmt.Errorf("failed to call AI API: %w", err), true)
+	}
+
+	if resp.StatusCode() != 200 {
+		return "", retry.Status(fmt.Errorf("AI API returned status code: %d, body: %s", resp.StatusCode(), resp.String()), resp.StatusCode(), resp.Header(), true)
+	}
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("no response from AI")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"hacker-news-daily/hackernews"
+)
+
+// SummarizePeriod 根据一段时期内最热门故事的总结生成趋势概述，用于周报和月报
+func (c *Client) SummarizePeriod(ctx context.Context, summaries []hackernews.StoryWithNumber, period string) (string, error) {
+	if len(summaries) == 0 {
+		return "", fmt.Errorf("no story summaries to review")
+	}
+
+	systemPrompt := `你是 Hacker News 中文周报和月报的主编，负责从一段时期的热门故事中提炼技术趋势。
+
+工作目标：
+- 找出这一时期反复出现的主题、正在升温的技术方向和引发最多讨论的话题
+- 把相关的故事联系起来，说明它们共同反映的趋势，而不是逐条复述
+- 点出值得技术从业者关注的变化和争议
+
+输出要求：
+- 输出 2-4 个连贯的段落，段落之间用空行分隔
+- 提到具体故事时使用方括号编号引用，如 [3]
+- 不使用标题、列表或 markdown 格式符号
+- 避免政治敏感内容
+- 总长度控制在300-500字之间`
+
+	stories := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		stories = append(stories, fmt.Sprintf("[%d] %s\n%s", summary.Number, summary.Title, summary.Summary))
+	}
+	input := strings.Join(stories, "\n\n")
+
+	// 故事较多时截断输入，保留排名靠前的故事
+	if c.contextLimit > 0 {
+		budget := c.contextLimit - c.maxOutputTokens - EstimateTokens(systemPrompt) - promptOverheadTokens
+		if budget > 0 {
+			input = truncateToTokens(input, budget)
+		}
+	}
+
+	userPrompt := fmt.Sprintf("以下是 %s 期间 Hacker News 最热门的 %d 个故事及其总结，按热度排序。请撰写这一时期的趋势概述：\n\n%s",
+		period, len(summaries), input)
+
+	overview, err := c.chat(ctx, systemPrompt, userPrompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(overview), nil
+}
@@ -0,0 +1,33 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/ai/aitest"
+	"hacker-news-daily/hackernews"
+)
+
+func TestSummarizePeriod(t *testing.T) {
+	server := aitest.NewServer(t, func(aitest.Request) string { return "\n本周 Rust 持续升温 [1]。\n" })
+	client := NewClient(server.URL, "", "gpt-4o", 2000)
+
+	summaries := []hackernews.StoryWithNumber{
+		{Number: 1, StoryID: 10, Title: "Rust in the kernel", Summary: "内核开始接受 Rust 驱动"},
+		{Number: 2, StoryID: 20, Title: "SQLite turns 25", Summary: "SQLite 迎来 25 周年"},
+	}
+	overview, err := client.SummarizePeriod(context.Background(), summaries, "2025-01-06~2025-01-12")
+	require.NoError(t, err)
+	assert.Equal(t, "本周 Rust 持续升温 [1]。", overview)
+
+	prompt := server.Requests()[0].UserPrompt()
+	assert.Contains(t, prompt, "2025-01-06~2025-01-12")
+	assert.Contains(t, prompt, "[1] Rust in the kernel\n内核开始接受 Rust 驱动")
+	assert.Contains(t, prompt, "[2] SQLite turns 25")
+
+	_, err = client.SummarizePeriod(context.Background(), nil, "2025-01-06~2025-01-12")
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultOpenAIBaseURL    = "https://api.openai.com/v1"
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	defaultGeminiBaseURL    = "https://generativelanguage.googleapis.com/v1beta"
+	defaultOllamaBaseURL    = "http://localhost:11434"
+)
+
+// Provider 大模型后端接口，负责把对话消息发送给具体的模型服务
+type Provider interface {
+	// Chat 发送对话消息并返回模型回复的文本
+	Chat(ctx context.Context, messages []ChatMessage) (string, error)
+}
+
+// JSONProvider 支持按 JSON Schema 约束输出的后端，未实现该接口的后端仅通过 prompt 约束输出格式
+type JSONProvider interface {
+	// ChatJSON 发送对话消息并要求模型只返回符合 schema 的 JSON
+	ChatJSON(ctx context.Context, messages []ChatMessage, schema JSONSchema) (string, error)
+}
+
+// JSONSchema 带名称的 JSON Schema，名称用于 OpenAI 的 json_schema 和 Anthropic 的工具名
+type JSONSchema struct {
+	Name   string
+	Schema map[string]any
+}
+
+// NewProvider 根据后端类型创建对应的 Provider，baseURL 为空时使用各后端的默认地址
+func NewProvider(providerType, baseURL, apiKey, model string, maxTokens int) (Provider, error) {
+	switch providerType {
+	case "", "openai":
+		return NewOpenAIProvider(withDefault(baseURL, defaultOpenAIBaseURL), apiKey, model, maxTokens), nil
+	case "anthropic":
+		return NewAnthropicProvider(withDefault(baseURL, defaultAnthropicBaseURL), apiKey, model, maxTokens), nil
+	case "gemini":
+		return NewGeminiProvider(withDefault(baseURL, defaultGeminiBaseURL), apiKey, model, maxTokens), nil
+	case "ollama":
+		return NewOllamaProvider(withDefault(baseURL, defaultOllamaBaseURL), model, maxTokens), nil
+	default:
+		return nil, fmt.Errorf("unsupported AI provider: %s", providerType)
+	}
+}
+
+// splitSystemPrompt 将 system 消息与对话消息分开，供单独传 system 参数的后端使用
+func splitSystemPrompt(messages []ChatMessage) (string, []ChatMessage) {
+	var systemPrompts []string
+	chatMessages := make([]ChatMessage, 0, len(messages))
+	for _, message := range messages {
+		if message.Role == "system" {
+			systemPrompts = append(systemPrompts, message.Content)
+			continue
+		}
+		chatMessages = append(chatMessages, message)
+	}
+	return strings.Join(systemPrompts, "\n\n"), chatMessages
+}
+
+func withDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return strings.TrimSuffix(value, "/")
+}
@@ -0,0 +1,193 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/retry"
+)
+
+var testMessages = []ChatMessage{
+	{Role: "system", Content: "你是编辑"},
+	{Role: "user", Content: "总结一下"},
+}
+
+// newTestServer 创建只响应指定路径的测试服务器，并记录收到的请求体
+func newTestServer(t *testing.T, path string, response any, requestBody *map[string]any, header *http.Header) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		*header = r.Header.Clone()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(requestBody))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	var body map[string]any
+	var header http.Header
+	server := newTestServer(t, "/chat/completions", map[string]any{
+		"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": "openai 回复"}}},
+	}, &body, &header)
+
+	reply, err := NewOpenAIProvider(server.URL, "sk-test", "gpt-4o", 100).Chat(context.Background(), testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "openai 回复", reply)
+	assert.Equal(t, "Bearer sk-test", header.Get("Authorization"))
+	assert.Equal(t, "gpt-4o", body["model"])
+	assert.Len(t, body["messages"], 2)
+}
+
+func TestOpenAIProviderJSONSchema(t *testing.T) {
+	var body map[string]any
+	var header http.Header
+	server := newTestServer(t, "/chat/completions", map[string]any{
+		"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": `{"stories": []}`}}},
+	}, &body, &header)
+
+	reply, err := NewOpenAIProvider(server.URL, "sk-test", "gpt-4o", 100).ChatJSON(context.Background(), testMessages, numberedSummarySchema)
+	require.NoError(t, err)
+	assert.Equal(t, `{"stories": []}`, reply)
+
+	format := body["response_format"].(map[string]any)
+	assert.Equal(t, "json_schema", format["type"])
+	schema := format["json_schema"].(map[string]any)
+	assert.Equal(t, "numbered_summaries", schema["name"])
+	assert.Equal(t, true, schema["strict"])
+	assert.Equal(t, "object", schema["schema"].(map[string]any)["type"])
+}
+
+func TestAnthropicProviderJSONSchema(t *testing.T) {
+	var body map[string]any
+	var header http.Header
+	server := newTestServer(t, "/messages", map[string]any{
+		"content": []any{map[string]any{"type": "tool_use", "name": "numbered_summaries", "input": map[string]any{"stories": []any{}}}},
+	}, &body, &header)
+
+	reply, err := NewAnthropicProvider(server.URL, "ant-key", "claude", 0).ChatJSON(context.Background(), testMessages, numberedSummarySchema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"stories": []}`, reply)
+
+	// 强制调用以 schema 为参数的工具
+	tools := body["tools"].([]any)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "numbered_summaries", tools[0].(map[string]any)["name"])
+	assert.NotNil(t, tools[0].(map[string]any)["input_schema"])
+	assert.Equal(t, map[string]any{"type": "tool", "name": "numbered_summaries"}, body["tool_choice"])
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	var body map[string]any
+	var header http.Header
+	server := newTestServer(t, "/messages", map[string]any{
+		"content": []any{map[string]any{"type": "text", "text": "anthropic 回复"}},
+	}, &body, &header)
+
+	reply, err := NewAnthropicProvider(server.URL, "ant-key", "claude", 0).Chat(context.Background(), testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic 回复", reply)
+	assert.Equal(t, "ant-key", header.Get("x-api-key"))
+	assert.Equal(t, anthropicVersion, header.Get("anthropic-version"))
+	assert.Equal(t, "你是编辑", body["system"])
+	assert.Equal(t, float64(defaultAnthropicMaxTokens), body["max_tokens"])
+	assert.Len(t, body["messages"], 1)
+}
+
+func TestGeminiProvider(t *testing.T) {
+	var body map[string]any
+	var header http.Header
+	server := newTestServer(t, "/models/gemini-pro:generateContent", map[string]any{
+		"candidates": []any{map[string]any{"content": map[string]any{"parts": []any{map[string]any{"text": "gemini 回复"}}}}},
+	}, &body, &header)
+
+	reply, err := NewGeminiProvider(server.URL, "g-key", "gemini-pro", 100).Chat(context.Background(), testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini 回复", reply)
+	assert.Equal(t, "g-key", header.Get("x-goog-api-key"))
+	assert.NotNil(t, body["systemInstruction"])
+	assert.Len(t, body["contents"], 1)
+}
+
+func TestOllamaProvider(t *testing.T) {
+	var body map[string]any
+	var header http.Header
+	server := newTestServer(t, "/api/chat", map[string]any{
+		"message": map[string]any{"role": "assistant", "content": "ollama 回复"},
+	}, &body, &header)
+
+	reply, err := NewOllamaProvider(server.URL, "llama3", 100).Chat(context.Background(), testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "ollama 回复", reply)
+	assert.Equal(t, false, body["stream"])
+	assert.Equal(t, "llama3", body["model"])
+}
+
+func TestProviderErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := NewOpenAIProvider(server.URL, "", "gpt-4o", 0).Chat(context.Background(), testMessages)
+	assert.ErrorContains(t, err, "502")
+}
+
+func TestClientRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(server.URL, "", "gpt-4o", 0)
+	client.SetRetryConfig(retry.Config{MaxAttempts: 1})
+	client.SetRequestTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	_, err := client.chat(context.Background(), "system", "user")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// 调用方取消时不再发出请求
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.chat(ctx, "system", "user")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewProvider(t *testing.T) {
+	for providerType, expected := range map[string]Provider{
+		"":          &OpenAIProvider{},
+		"openai":    &OpenAIProvider{},
+		"anthropic": &AnthropicProvider{},
+		"gemini":    &GeminiProvider{},
+		"ollama":    &OllamaProvider{},
+	} {
+		provider, err := NewProvider(providerType, "", "", "model", 0)
+		require.NoError(t, err)
+		assert.IsType(t, expected, provider)
+	}
+
+	provider, err := NewProvider("ollama", "", "", "llama3", 0)
+	require.NoError(t, err)
+	assert.Equal(t, defaultOllamaBaseURL, provider.(*OllamaProvider).baseURL)
+
+	_, err = NewProvider("unknown", "", "", "model", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,266 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"hacker-news-daily/hackernews"
+)
+
+// numberedSummaryJSON 模型返回的带编号总结 JSON 结构
+type numberedSummaryJSON struct {
+	Stories []storySummaryJSON `json:"stories"`
+}
+
+type storySummaryJSON struct {
+	Number  int      `json:"number"`
+	StoryID int      `json:"story_id"`
+	Title   string   `json:"title"`
+	Summary string   `json:"summary"`
+	Tags    []string `json:"tags"`
+}
+
+// numberedSummarySchema 带编号总结的 JSON Schema，所有字段均为必填以满足 OpenAI 的 strict 模式
+var numberedSummarySchema = JSONSchema{
+	Name: "numbered_summaries",
+	Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"stories": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"number":   map[string]any{"type": "integer"},
+						"story_id": map[string]any{"type": "integer"},
+						"title":    map[string]any{"type": "string"},
+						"summary":  map[string]any{"type": "string"},
+						"tags":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					},
+					"required":             []string{"number", "story_id", "title", "summary", "tags"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"stories"},
+		"additionalProperties": false,
+	},
+}
+
+// chatJSON 要求模型按 schema 返回 JSON，后端不支持结构化输出时退回普通对话
+func (c *Client) chatJSON(ctx context.Context, messages []ChatMessage, schema JSONSchema) (string, error) {
+	if provider, ok := c.provider.(JSONProvider); ok {
+		return c.send(ctx, func(ctx context.Context, messages []ChatMessage) (string, error) {
+			return provider.ChatJSON(ctx, messages, schema)
+		}, messages)
+	}
+	return c.send(ctx, c.provider.Chat, messages)
+}
+
+// summarizeBatch 生成一批故事的带编号总结，userPrompt 根据故事编号生成请求内容。
+// 整批请求缺少故事时逐个重新请求缺少的故事，仍然缺少则返回错误
+func (c *Client) summarizeBatch(ctx context.Context, systemPrompt string, userPrompt func(numbers []int) string, stories []hackernews.Story, expected []int) ([]hackernews.StoryWithNumber, error) {
+	summaries, err := c.summarizeNumbers(ctx, systemPrompt, userPrompt(expected), stories, expected)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := missingNumbers(summaries, expected)
+	if len(missing) > 0 && len(expected) > 1 {
+		log.Printf("Summary is missing stories %v, requesting them one by one", missing)
+		for _, number := range missing {
+			single, err := c.summarizeNumbers(ctx, systemPrompt, userPrompt([]int{number}), stories, []int{number})
+			if err != nil {
+				return nil, err
+			}
+			summaries = append(summaries, single...)
+		}
+		missing = missingNumbers(summaries, expected)
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no valid summary for stories %v", missing)
+	}
+	return summaries, nil
+}
+
+// summarizeNumbers 请求指定编号故事的总结：
+// 优先解析 JSON，校验失败时让模型修复一次，仍然没有有效的 JSON 则退回按 [N] 解析文本。
+// 返回所有有效的总结，可能缺少部分故事
+func (c *Client) summarizeNumbers(ctx context.Context, systemPrompt, userPrompt string, stories []hackernews.Story, expected []int) ([]hackernews.StoryWithNumber, error) {
+	messages := []ChatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	}
+
+	reply, err := c.chatJSON(ctx, messages, numberedSummarySchema)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := parseJSONSummaries(reply, stories, expected)
+	if err == nil {
+		return summaries, nil
+	}
+
+	log.Printf("Invalid JSON summary, retrying with repair prompt: %v", err)
+	repairMessages := append(messages,
+		ChatMessage{Role: "assistant", Content: reply},
+		ChatMessage{Role: "user", Content: fmt.Sprintf("你的输出不符合要求：%v。请重新输出完整的 JSON 对象，包含所有故事，不要输出任何其他文字。", err)},
+	)
+	repaired, repairErr := c.chatJSON(ctx, repairMessages, numberedSummarySchema)
+	if repairErr != nil {
+		log.Printf("Failed to repair JSON summary: %v", repairErr)
+	} else {
+		repairedSummaries, err := parseJSONSummaries(repaired, stories, expected)
+		if err == nil {
+			return repairedSummaries, nil
+		}
+		log.Printf("Repaired JSON summary is still invalid: %v", err)
+		if len(repairedSummaries) >= len(summaries) {
+			summaries = repairedSummaries
+			reply = repaired
+		}
+	}
+
+	if len(summaries) > 0 {
+		return summaries, nil
+	}
+
+	log.Println("Falling back to numbered text parser")
+	return filterNumbers(c.parseNumberedSummaries(reply, stories), expected), nil
+}
+
+// missingNumbers 返回 expected 中没有对应总结的编号
+func missingNumbers(summaries []hackernews.StoryWithNumber, expected []int) []int {
+	seen := make(map[int]bool, len(summaries))
+	for _, summary := range summaries {
+		seen[summary.Number] = true
+	}
+
+	var missing []int
+	for _, number := range expected {
+		if !seen[number] {
+			missing = append(missing, number)
+		}
+	}
+	return missing
+}
+
+// filterNumbers 只保留 expected 中的编号，同一编号只保留第一条
+func filterNumbers(summaries []hackernews.StoryWithNumber, expected []int) []hackernews.StoryWithNumber {
+	wanted := make(map[int]bool, len(expected))
+	for _, number := range expected {
+		wanted[number] = true
+	}
+
+	var filtered []hackernews.StoryWithNumber
+	for _, summary := range summaries {
+		if wanted[summary.Number] && strings.TrimSpace(summary.Summary) != "" {
+			wanted[summary.Number] = false
+			filtered = append(filtered, summary)
+		}
+	}
+	return filtered
+}
+
+// parseJSONSummaries 解析并校验模型返回的 JSON 总结。
+// 返回所有通过校验的总结；缺少故事或存在无效条目时同时返回错误
+func parseJSONSummaries(reply string, stories []hackernews.Story, expected []int) ([]hackernews.StoryWithNumber, error) {
+	data := extractJSON(reply)
+	if data == "" {
+		return nil, fmt.Errorf("no JSON found in response")
+	}
+
+	var items []storySummaryJSON
+	if strings.HasPrefix(data, "[") {
+		if err := json.Unmarshal([]byte(data), &items); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	} else {
+		var parsed numberedSummaryJSON
+		if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		items = parsed.Stories
+	}
+
+	expectedSet := make(map[int]bool, len(expected))
+	for _, number := range expected {
+		expectedSet[number] = true
+	}
+
+	var problems []string
+	seen := make(map[int]bool)
+	var summaries []hackernews.StoryWithNumber
+	for _, item := range items {
+		switch {
+		case !expectedSet[item.Number] || item.Number > len(stories):
+			problems = append(problems, fmt.Sprintf("unexpected story number %d", item.Number))
+			continue
+		case seen[item.Number]:
+			problems = append(problems, fmt.Sprintf("duplicate story number %d", item.Number))
+			continue
+		case strings.TrimSpace(item.Summary) == "":
+			problems = append(problems, fmt.Sprintf("empty summary for story %d", item.Number))
+			continue
+		}
+
+		// 以编号为准，story_id 不一致时使用输入中的故事 ID
+		story := stories[item.Number-1]
+		if item.StoryID != 0 && item.StoryID != story.ID {
+			log.Printf("Story %d returned mismatched ID %d, expected %d", item.Number, item.StoryID, story.ID)
+		}
+
+		title := strings.TrimSpace(item.Title)
+		if title == "" {
+			title = story.Title
+		}
+
+		seen[item.Number] = true
+		summaries = append(summaries, hackernews.StoryWithNumber{
+			Number:  item.Number,
+			StoryID: story.ID,
+			Title:   story.Title,
+			Summary: fmt.Sprintf("**%s** %s", title, strings.TrimSpace(item.Summary)),
+			Tags:    item.Tags,
+		})
+	}
+
+	var missing []string
+	for _, number := range expected {
+		if !seen[number] {
+			missing = append(missing, fmt.Sprintf("%d", number))
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing stories %s", strings.Join(missing, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return summaries, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return summaries, nil
+}
+
+// extractJSON 去除 markdown 代码块等多余内容，返回最外层的 JSON 文本
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return ""
+	}
+
+	closing := "}"
+	if text[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(text, closing)
+	if end < start {
+		return ""
+	}
+	return text[start : end+1]
+}
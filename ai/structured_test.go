@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/hackernews"
+)
+
+var structuredTestStories = []hackernews.Story{
+	{ID: 101, Title: "Go 1.22 Released"},
+	{ID: 102, Title: "SQLite Internals"},
+}
+
+// scriptedProvider 依次返回预设的回复
+type scriptedProvider struct {
+	replies  []string
+	messages [][]ChatMessage
+}
+
+// numbersPrompt 以故事编号作为请求内容，便于断言重新请求了哪些故事
+func numbersPrompt(numbers []int) string {
+	return fmt.Sprint(numbers)
+}
+
+func (p *scriptedProvider) Chat(_ context.Context, messages []ChatMessage) (string, error) {
+	p.messages = append(p.messages, messages)
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return reply, nil
+}
+
+func TestParseJSONSummaries(t *testing.T) {
+	reply := "```json\n" + `{"stories": [
+		{"number": 2, "story_id": 102, "title": "SQLite 内部原理", "summary": "深入介绍 SQLite 存储结构", "tags": ["数据库"]},
+		{"number": 1, "story_id": 999, "title": "Go 1.22 发布", "summary": "循环变量语义变更"}
+	]}` + "\n```"
+
+	summaries, err := parseJSONSummaries(reply, structuredTestStories, []int{1, 2})
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+
+	assert.Equal(t, 2, summaries[0].Number)
+	assert.Equal(t, 102, summaries[0].StoryID)
+	assert.Equal(t, "**SQLite 内部原理** 深入介绍 SQLite 存储结构", summaries[0].Summary)
+	assert.Equal(t, []string{"数据库"}, summaries[0].Tags)
+
+	// story_id 不一致时以编号为准
+	assert.Equal(t, 101, summaries[1].StoryID)
+}
+
+func TestParseJSONSummariesValidation(t *testing.T) {
+	_, err := parseJSONSummaries("not json", structuredTestStories, []int{1, 2})
+	assert.Error(t, err)
+
+	_, err = parseJSONSummaries(`{"stories": [{"number": 1, "summary": "x"`, structuredTestStories, []int{1, 2})
+	assert.Error(t, err)
+
+	// 缺少故事时返回已通过校验的部分和错误
+	summaries, err := parseJSONSummaries(`{"stories": [{"number": 1, "summary": "x"}, {"number": 5, "summary": "y"}]}`, structuredTestStories, []int{1, 2})
+	assert.ErrorContains(t, err, "missing stories 2")
+	assert.ErrorContains(t, err, "unexpected story number 5")
+	assert.Len(t, summaries, 1)
+
+	// 支持顶层数组
+	summaries, err = parseJSONSummaries(`[{"number": 1, "summary": "x"}, {"number": 2, "summary": "y"}]`, structuredTestStories, []int{1, 2})
+	assert.NoError(t, err)
+	assert.Len(t, summaries, 2)
+}
+
+// TestSummarizeBatchRepair 测试 JSON 无效时发起修复请求
+func TestSummarizeBatchRepair(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`{"stories": [{"number": 1, "summary": "x"}]}`,
+		`{"stories": [{"number": 1, "summary": "x"}, {"number": 2, "summary": "y"}]}`,
+	}}
+	client := NewClientWithProvider(provider)
+
+	summaries, err := client.summarizeBatch(context.Background(), "system", numbersPrompt, structuredTestStories, []int{1, 2})
+	require.NoError(t, err)
+	assert.Len(t, summaries, 2)
+
+	require.Len(t, provider.messages, 2)
+	repair := provider.messages[1]
+	assert.Equal(t, "assistant", repair[2].Role)
+	assert.Contains(t, repair[3].Content, "missing stories 2")
+}
+
+// TestSummarizeBatchTextFallback 测试修复后仍不是 JSON 时退回文本解析
+func TestSummarizeBatchTextFallback(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		"抱歉，我无法输出 JSON",
+		"**[1]** **Go 1.22 发布** 循环变量语义变更\n\n[2] **SQLite 内部原理** 存储结构",
+	}}
+	client := NewClientWithProvider(provider)
+
+	summaries, err := client.summarizeBatch(context.Background(), "system", numbersPrompt, structuredTestStories, []int{1, 2})
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "**Go 1.22 发布** 循环变量语义变更", summaries[0].Summary)
+	assert.Equal(t, 102, summaries[1].StoryID)
+}
+
+// TestSummarizeBatchRequestsMissingStories 测试修复后仍缺少的故事单独重新请求
+func TestSummarizeBatchRequestsMissingStories(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`{"stories": [{"number": 1, "summary": "x"}]}`,
+		`{"stories": [{"number": 1, "summary": "x"}]}`,
+		`{"stories": [{"number": 2, "summary": "y"}]}`,
+	}}
+	client := NewClientWithProvider(provider)
+
+	summaries, err := client.summarizeBatch(context.Background(), "system", numbersPrompt, structuredTestStories, []int{1, 2})
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, 2, summaries[1].Number)
+
+	require.Len(t, provider.messages, 3)
+	assert.Equal(t, "[1 2]", provider.messages[0][1].Content)
+	assert.Equal(t, "[2]", provider.messages[2][1].Content)
+}
+
+// TestSummarizeBatchMissingStoriesError 测试单独请求后仍缺少故事时返回错误而不是丢弃
+func TestSummarizeBatchMissingStoriesError(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`{"stories": [{"number": 1, "summary": "x"}]}`,
+		`{"stories": [{"number": 1, "summary": "x"}]}`,
+		`{"stories": []}`,
+		"抱歉，我无法总结这个故事",
+	}}
+	client := NewClientWithProvider(provider)
+
+	_, err := client.summarizeBatch(context.Background(), "system", numbersPrompt, structuredTestStories, []int{1, 2})
+	assert.ErrorContains(t, err, "no valid summary for stories [2]")
+}
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"hacker-news-daily/hackernews"
+)
+
+// translationSchema 翻译结果的 JSON Schema
+var translationSchema = JSONSchema{
+	Name: "translated_summaries",
+	Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"stories": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"number":  map[string]any{"type": "integer"},
+						"summary": map[string]any{"type": "string"},
+					},
+					"required":             []string{"number", "summary"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"stories"},
+		"additionalProperties": false,
+	},
+}
+
+// TranslateSummaries 将故事总结翻译为指定语言，未翻译成功的故事保留原文
+func (c *Client) TranslateSummaries(ctx context.Context, summaries []hackernews.StoryWithNumber, language string) ([]hackernews.StoryWithNumber, error) {
+	if len(summaries) == 0 {
+		return summaries, nil
+	}
+
+	systemPrompt := `你是专业的技术翻译，负责把 Hacker News 故事总结翻译为指定语言。
+
+输出要求：
+- 只输出一个 JSON 对象，格式为：{"stories": [{"number": 1, "summary": "译文"}]}
+- number 必须与输入一致，每个输入的总结都必须出现
+- 保留 **标题** 等格式标记，技术术语翻译准确
+- 不添加任何解释或额外内容`
+
+	input := numberedSummaryJSON{}
+	for _, summary := range summaries {
+		input.Stories = append(input.Stories, storySummaryJSON{Number: summary.Number, Summary: summary.Summary})
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal summaries: %w", err)
+	}
+
+	userPrompt := fmt.Sprintf("请将以下总结翻译为语言代码 %s 对应的语言：\n\n%s", language, data)
+	reply, err := c.chatJSON(ctx, []ChatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	}, translationSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	var output numberedSummaryJSON
+	if err := json.Unmarshal([]byte(extractJSON(reply)), &output); err != nil {
+		return nil, fmt.Errorf("invalid translation JSON: %w", err)
+	}
+
+	translations := make(map[int]string, len(output.Stories))
+	for _, story := range output.Stories {
+		if story.Summary != "" {
+			translations[story.Number] = story.Summary
+		}
+	}
+	if len(translations) == 0 {
+		return nil, fmt.Errorf("no translation returned")
+	}
+
+	translated := make([]hackernews.StoryWithNumber, len(summaries))
+	for i, summary := range summaries {
+		translated[i] = summary
+		if text, ok := translations[summary.Number]; ok {
+			translated[i].Summary = text
+		}
+	}
+	return translated, nil
+}
@@ -0,0 +1,233 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/net/html/charset"
+
+	"hacker-news-daily/retry"
+)
+
+const (
+	defaultMaxLength = 6000            // 默认正文最大字符数
+	maxBodySize      = 5 * 1024 * 1024 // 最多读取 5MB 响应体
+)
+
+// errBlockedAddress 链接指向回环、内网或链路本地地址
+var errBlockedAddress = errors.New("refusing to fetch non-public address")
+
+// Article 从链接中提取的文章信息
+type Article struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Byline      string `json:"byline"`
+	PublishedAt string `json:"published_at"`
+	ContentType string `json:"content_type"`
+	Text        string `json:"text"`
+	Note        string `json:"note"` // 无法提取正文时的说明
+}
+
+type Fetcher struct {
+	httpClient  *resty.Client
+	maxLength   int
+	retryConfig retry.Config
+	retriers    map[string]*retry.Retrier // 按站点分别熔断，个别站点故障不影响其他站点
+	mu          sync.Mutex
+	allowLocal  bool // 允许连接非公网地址，只用于测试
+}
+
+func NewFetcher(timeout int, maxLength int) *Fetcher {
+	if maxLength <= 0 {
+		maxLength = defaultMaxLength
+	}
+
+	f := &Fetcher{
+		maxLength: maxLength,
+		retriers:  make(map[string]*retry.Retrier),
+	}
+
+	// 链接由用户提交，建立连接时检查解析后的地址，重定向后的请求同样经过检查；
+	// 不使用环境变量中的代理，否则无法检查实际访问的地址
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: f.checkAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	f.httpClient = resty.New().
+		SetTransport(transport).
+		SetTimeout(time.Duration(timeout)*time.Second).
+		SetRedirectPolicy(resty.FlexibleRedirectPolicy(5)).
+		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.36").
+		SetHeader("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+	return f
+}
+
+// checkAddress 拒绝连接回环、内网、链路本地等非公网地址，防止提交的链接访问内部服务
+func (f *Fetcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	if f.allowLocal {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errBlockedAddress, address)
+	}
+	ip := addrPort.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", errBlockedAddress, ip)
+	}
+	return nil
+}
+
+// SetRetryConfig 设置下载失败时的重试和熔断策略，每个站点单独熔断
+func (f *Fetcher) SetRetryConfig(config retry.Config) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.retryConfig = config
+	f.retriers = make(map[string]*retry.Retrier)
+}
+
+// retrier 返回链接所在站点的重试器
+func (f *Fetcher) retrier(rawURL string) *retry.Retrier {
+	host := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil {
+		host = parsed.Host
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.retriers[host]
+	if !ok {
+		r = retry.New("article "+host, f.retryConfig)
+		f.retriers[host] = r
+	}
+	return r
+}
+
+// SetTransport 设置底层 HTTP Transport，用于录制和回放请求
+func (f *Fetcher) SetTransport(transport http.RoundTripper) {
+	f.httpClient.SetTransport(transport)
+}
+
+// Fetch 下载链接并提取正文
+func (f *Fetcher) Fetch(ctx context.Context, url string) (*Article, error) {
+	var resp *resty.Response
+	err := f.retrier(url).Do(ctx, func() error {
+		var err error
+		resp, err = f.httpClient.R().
+			SetContext(ctx).
+			SetDoNotParseResponse(true).
+			Get(url)
+		if errors.Is(err, errBlockedAddress) {
+			return fmt.Errorf("failed to fetch article: %w", err)
+		}
+		if err != nil {
+			return retry.Network(fmt.Errorf("failed to fetch article: %w", err), true)
+		}
+		if resp.StatusCode() != 200 {
+			resp.RawBody().Close()
+			return retry.Status(fmt.Errorf("article returned status code: %d", resp.StatusCode()), resp.StatusCode(), resp.Header(), true)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	body := resp.RawBody()
+	defer body.Close()
+
+	contentType := resp.Header().Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/html"
+	}
+
+	// 按 Content-Type、BOM 或 <meta charset> 将正文转换为 UTF-8
+	decode := func() (io.Reader, error) {
+		reader, err := charset.NewReader(io.LimitReader(body, maxBodySize), contentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode article body: %w", err)
+		}
+		return reader, nil
+	}
+
+	article := &Article{URL: url, ContentType: mediaType}
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		reader, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		extracted, err := Extract(reader)
+		if err != nil {
+			return nil, err
+		}
+		extracted.URL = url
+		extracted.ContentType = mediaType
+		article = extracted
+	case strings.HasPrefix(mediaType, "text/"):
+		reader, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read article body: %w", err)
+		}
+		article.Text = normalizeText(string(data))
+	case mediaType == "application/pdf":
+		article.Note = "链接为 PDF 文档，未提取正文"
+	default:
+		article.Note = fmt.Sprintf("链接内容类型为 %s，未提取正文", mediaType)
+	}
+
+	article.Text = truncate(article.Text, f.maxLength)
+	return article, nil
+}
+
+// Format 将文章格式化为传给 AI 的文本
+func (a *Article) Format() string {
+	var content strings.Builder
+	if a.Title != "" {
+		content.WriteString(fmt.Sprintf("文章标题: %s\n", a.Title))
+	}
+	if a.Byline != "" {
+		content.WriteString(fmt.Sprintf("文章作者: %s\n", a.Byline))
+	}
+	if a.PublishedAt != "" {
+		content.WriteString(fmt.Sprintf("发布时间: %s\n", a.PublishedAt))
+	}
+	if a.Note != "" {
+		content.WriteString(a.Note)
+		content.WriteString("\n")
+	}
+	if a.Text != "" {
+		content.WriteString(a.Text)
+		content.WriteString("\n")
+	}
+	return content.String()
+}
+
+// truncate 按字符数截断文本
+func truncate(text string, maxLength int) string {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:maxLength])) + "……（正文已截断）"
+}
@@ -0,0 +1,209 @@
+package article
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/retry"
+)
+
+const sampleHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Site Name | Why We Rewrote Our Build System in Go</title>
+  <meta property="og:title" content="Why We Rewrote Our Build System in Go">
+  <meta name="author" content="Jane Doe">
+  <meta property="article:published_time" content="2024-01-15T08:00:00Z">
+  <script>var tracking = "should not appear";</script>
+</head>
+<body>
+  <header class="site-header"><a href="/">Home</a> <a href="/blog">Blog</a></header>
+  <nav><ul><li><a href="/a">Menu item</a></li></ul></nav>
+  <div class="sidebar"><p>Subscribe to our newsletter, it is great, really, trust us.</p></div>
+  <article class="post-content">
+    <h1>Why We Rewrote Our Build System in Go</h1>
+    <p>Our old build system was written in a mix of shell scripts and Python, and it had grown slow, fragile, and hard to reason about over the years.</p>
+    <p>After evaluating several options, we chose Go because of its fast compile times, static binaries, and excellent concurrency primitives.</p>
+    <p>The rewrite took three months, and build times dropped from twelve minutes to under two minutes on our CI machines.</p>
+  </article>
+  <div class="comments"><p>Great post, thanks for sharing this with everyone here!</p></div>
+  <footer><p>Copyright 2024 Example Corp. All rights reserved worldwide.</p></footer>
+</body>
+</html>`
+
+// TestExtract 测试正文提取
+func TestExtract(t *testing.T) {
+	article, err := Extract(strings.NewReader(sampleHTML))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Why We Rewrote Our Build System in Go", article.Title)
+	assert.Equal(t, "Jane Doe", article.Byline)
+	assert.Equal(t, "2024-01-15T08:00:00Z", article.PublishedAt)
+
+	assert.Contains(t, article.Text, "Our old build system was written in a mix of shell scripts")
+	assert.Contains(t, article.Text, "build times dropped from twelve minutes")
+
+	// 导航、侧边栏、评论、页脚和脚本应被移除
+	for _, boilerplate := range []string{"Menu item", "newsletter", "Great post", "Copyright", "tracking"} {
+		assert.NotContains(t, article.Text, boilerplate)
+	}
+}
+
+// TestExtractFallback 测试没有明显正文容器时使用 body 文本
+func TestExtractFallback(t *testing.T) {
+	article, err := Extract(strings.NewReader(`<html><body><span>short</span></body></html>`))
+	require.NoError(t, err)
+	assert.Equal(t, "short", article.Text)
+}
+
+// TestFetch 测试不同内容类型的处理
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(sampleHTML))
+		case "/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("plain   text\n\n\nbody"))
+		case "/paper.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.4"))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 0x50, 0x4e, 0x47})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(5, 0)
+	fetcher.allowLocal = true
+
+	t.Run("html", func(t *testing.T) {
+		article, err := fetcher.Fetch(context.Background(), server.URL+"/article")
+		require.NoError(t, err)
+		assert.Equal(t, "text/html", article.ContentType)
+		assert.Equal(t, server.URL+"/article", article.URL)
+		assert.Contains(t, article.Format(), "文章标题: Why We Rewrote Our Build System in Go")
+		assert.Contains(t, article.Format(), "文章作者: Jane Doe")
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		article, err := fetcher.Fetch(context.Background(), server.URL+"/plain")
+		require.NoError(t, err)
+		assert.Equal(t, "plain text\n\nbody", article.Text)
+	})
+
+	t.Run("pdf", func(t *testing.T) {
+		article, err := fetcher.Fetch(context.Background(), server.URL+"/paper.pdf")
+		require.NoError(t, err)
+		assert.Empty(t, article.Text)
+		assert.Contains(t, article.Note, "PDF")
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		article, err := fetcher.Fetch(context.Background(), server.URL+"/image.png")
+		require.NoError(t, err)
+		assert.Contains(t, article.Note, "image/png")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := fetcher.Fetch(context.Background(), server.URL+"/missing")
+		assert.Error(t, err)
+	})
+}
+
+// TestFetchRetries 测试服务端临时错误时重试，请求有误时不重试
+func TestFetchRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if r.URL.Path == "/flaky" && n == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("recovered"))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(5, 0)
+	fetcher.allowLocal = true
+	fetcher.SetRetryConfig(retry.Config{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	article, err := fetcher.Fetch(context.Background(), server.URL+"/flaky")
+	require.NoError(t, err)
+	assert.Equal(t, "recovered", article.Text)
+	assert.Equal(t, int32(2), requests.Load())
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/missing")
+	assert.ErrorContains(t, err, "404")
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+// TestFetchDecodesCharset 测试按 Content-Type 或 <meta charset> 将正文转换为 UTF-8
+func TestFetchDecodesCharset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gbk":
+			w.Header().Set("Content-Type", "text/plain; charset=gbk")
+			w.Write([]byte{0xd6, 0xd0, 0xce, 0xc4}) // 中文
+		case "/sjis":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(append([]byte(`<html><head><meta charset="shift_jis"></head><body><p>`), 0x93, 0xfa, 0x96, 0x7b, 0x8c, 0xea)) // 日本語
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/plain; charset=iso-8859-1")
+			w.Write([]byte{'c', 'a', 'f', 0xe9}) // café
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(5, 0)
+	fetcher.allowLocal = true
+	for path, expected := range map[string]string{"/gbk": "中文", "/sjis": "日本語", "/latin1": "café"} {
+		article, err := fetcher.Fetch(context.Background(), server.URL+path)
+		require.NoError(t, err, path)
+		assert.Equal(t, expected, article.Text, path)
+	}
+}
+
+// TestFetchBlocksLocalAddresses 测试拒绝访问回环、内网和链路本地地址
+func TestFetchBlocksLocalAddresses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(5, 0)
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, errBlockedAddress)
+	assert.Zero(t, requests.Load())
+
+	for _, address := range []string{"127.0.0.1:80", "10.1.2.3:80", "192.168.1.1:443", "169.254.169.254:80", "0.0.0.0:80", "[::1]:80", "[fe80::1]:80", "[fc00::1]:80", "[::ffff:127.0.0.1]:80"} {
+		assert.ErrorIs(t, fetcher.checkAddress("tcp", address, nil), errBlockedAddress, address)
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1::]:443"} {
+		assert.NoError(t, fetcher.checkAddress("tcp", address, nil), address)
+	}
+}
+
+// TestTruncate 测试按字符截断
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "短文本", truncate("短文本", 10))
+	assert.Equal(t, "中文字……（正文已截断）", truncate("中文字符截断", 3))
+}
@@ -0,0 +1,382 @@
+package article
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// 可能是非正文区域的 class/id
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|popup|promo|nav|ad-break|advert`)
+	// 可能是正文区域的 class/id
+	maybeCandidate = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|post|entry|text|story`)
+
+	positiveWeight = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeWeight = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+
+	bylineClass = regexp.MustCompile(`(?i)byline|author|writtenby|p-author`)
+)
+
+// 直接丢弃的标签
+var removedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Form: true, atom.Button: true, atom.Svg: true, atom.Nav: true,
+	atom.Aside: true, atom.Footer: true, atom.Header: true, atom.Select: true,
+}
+
+// 输出正文时视为独立段落的标签
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Li: true, atom.Pre: true, atom.Blockquote: true, atom.Br: true, atom.Tr: true,
+	atom.Figcaption: true, atom.Dd: true, atom.Dt: true,
+}
+
+// Extract 从 HTML 中提取标题、作者、发布时间和正文
+func Extract(r io.Reader) (*Article, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	article := &Article{
+		Title:       extractTitle(doc),
+		Byline:      extractByline(doc),
+		PublishedAt: extractPublishedAt(doc),
+	}
+
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+
+	removeBoilerplate(body)
+
+	if top := findTopCandidate(body); top != nil {
+		article.Text = normalizeText(collectText(top))
+	}
+	if article.Text == "" {
+		article.Text = normalizeText(collectText(body))
+	}
+
+	return article, nil
+}
+
+// extractTitle 按 og:title、twitter:title、<title>、<h1> 的顺序提取标题
+func extractTitle(doc *html.Node) string {
+	if title := metaContent(doc, "og:title", "twitter:title"); title != "" {
+		return title
+	}
+	if n := findFirst(doc, atom.Title); n != nil {
+		if title := strings.TrimSpace(textContent(n)); title != "" {
+			return title
+		}
+	}
+	if n := findFirst(doc, atom.H1); n != nil {
+		return strings.TrimSpace(textContent(n))
+	}
+	return ""
+}
+
+// extractByline 提取作者信息
+func extractByline(doc *html.Node) string {
+	if author := metaContent(doc, "author", "article:author", "twitter:creator"); author != "" {
+		return author
+	}
+
+	var byline string
+	walk(doc, func(n *html.Node) bool {
+		if byline != "" {
+			return false
+		}
+		if n.Type != html.ElementNode {
+			return true
+		}
+		if attr(n, "rel") == "author" || attr(n, "itemprop") == "author" || bylineClass.MatchString(attr(n, "class")+" "+attr(n, "id")) {
+			text := strings.Join(strings.Fields(textContent(n)), " ")
+			if text != "" && utf8.RuneCountInString(text) < 100 {
+				byline = text
+				return false
+			}
+		}
+		return true
+	})
+	return byline
+}
+
+// extractPublishedAt 提取发布时间
+func extractPublishedAt(doc *html.Node) string {
+	if published := metaContent(doc, "article:published_time", "datePublished", "date", "pubdate", "publish-date", "dc.date"); published != "" {
+		return published
+	}
+	if n := findFirst(doc, atom.Time); n != nil {
+		if datetime := attr(n, "datetime"); datetime != "" {
+			return datetime
+		}
+		return strings.TrimSpace(textContent(n))
+	}
+	return ""
+}
+
+// metaContent 按顺序查找 name/property/itemprop 匹配的 meta 标签内容
+func metaContent(doc *html.Node, keys ...string) string {
+	values := make(map[string]string)
+	walk(doc, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Meta {
+			content := strings.TrimSpace(attr(n, "content"))
+			for _, key := range []string{attr(n, "property"), attr(n, "name"), attr(n, "itemprop")} {
+				key = strings.ToLower(key)
+				if key != "" && content != "" {
+					if _, exists := values[key]; !exists {
+						values[key] = content
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	for _, key := range keys {
+		if value := values[strings.ToLower(key)]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// removeBoilerplate 移除脚本、导航、侧边栏等非正文节点
+func removeBoilerplate(root *html.Node) {
+	var toRemove []*html.Node
+	walk(root, func(n *html.Node) bool {
+		if n.Type == html.CommentNode {
+			toRemove = append(toRemove, n)
+			return false
+		}
+		if n.Type != html.ElementNode {
+			return true
+		}
+		if removedTags[n.DataAtom] || hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+			toRemove = append(toRemove, n)
+			return false
+		}
+		matchString := attr(n, "class") + " " + attr(n, "id")
+		if n.DataAtom != atom.Body && n.DataAtom != atom.A &&
+			unlikelyCandidates.MatchString(matchString) && !maybeCandidate.MatchString(matchString) {
+			toRemove = append(toRemove, n)
+			return false
+		}
+		return true
+	})
+
+	for _, n := range toRemove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// findTopCandidate 按 readability 算法给节点打分，返回得分最高的正文容器
+func findTopCandidate(root *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, exists := scores[n]; !exists {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	walk(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		default:
+			return true
+		}
+
+		text := strings.TrimSpace(textContent(n))
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return true
+		}
+
+		// 基础分 + 逗号数 + 每100字加一分（最多3分）
+		score := 1.0
+		score += float64(strings.Count(text, ",") + strings.Count(text, "，"))
+		score += min(float64(length)/100, 3)
+
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+		return true
+	})
+
+	var top *html.Node
+	var topScore float64
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		if top == nil || score > topScore {
+			top = n
+			topScore = score
+		}
+	}
+	return top
+}
+
+// initialScore 根据标签和 class/id 计算节点初始分
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Article:
+		score = 10
+	case atom.Div, atom.Section, atom.Main:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			score -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity 计算节点中链接文本所占比例
+func linkDensity(n *html.Node) float64 {
+	textLength := utf8.RuneCountInString(textContent(n))
+	if textLength == 0 {
+		return 0
+	}
+	var linkLength int
+	walk(n, func(child *html.Node) bool {
+		if child.Type == html.ElementNode && child.DataAtom == atom.A {
+			linkLength += utf8.RuneCountInString(textContent(child))
+			return false
+		}
+		return true
+	})
+	return float64(linkLength) / float64(textLength)
+}
+
+// collectText 提取节点文本，块级元素之间换行
+func collectText(n *html.Node) string {
+	var text strings.Builder
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			text.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if removedTags[n.DataAtom] {
+				return
+			}
+		}
+
+		isBlock := n.Type == html.ElementNode && blockTags[n.DataAtom]
+		if isBlock {
+			text.WriteString("\n\n")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+		if isBlock {
+			text.WriteString("\n\n")
+		}
+	}
+	visit(n)
+	return text.String()
+}
+
+// normalizeText 合并多余空白，段落之间保留一个空行
+func normalizeText(text string) string {
+	var paragraphs []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// textContent 返回节点下所有文本
+func textContent(n *html.Node) string {
+	var text strings.Builder
+	walk(n, func(child *html.Node) bool {
+		if child.Type == html.TextNode {
+			text.WriteString(child.Data)
+		}
+		return true
+	})
+	return text.String()
+}
+
+func findFirst(root *html.Node, tag atom.Atom) *html.Node {
+	var found *html.Node
+	walk(root, func(n *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if n.Type == html.ElementNode && n.DataAtom == tag {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// walk 深度优先遍历节点，fn 返回 false 时不再访问其子节点
+func walk(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, fn)
+	}
+}
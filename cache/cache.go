@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTTL    = 6 * time.Hour
+	purgeInterval = 1000 // 每写入多少次清理一次内存中的过期条目
+	fileLockCount = 64   // 磁盘文件锁的数量，键按哈希分配到其中一个锁
+)
+
+// errLoadPanicked 加载函数 panic 时等待同一个键的调用收到的错误
+var errLoadPanicked = errors.New("cache load panicked")
+
+// Cache 带过期时间的键值缓存，可选同时写入磁盘，同一个键的并发加载只执行一次
+type Cache struct {
+	mu        sync.Mutex // 保护内存中的条目和正在进行的加载，读写磁盘时不持有
+	fileLocks [fileLockCount]sync.Mutex
+	ttl       time.Duration
+	dir       string // 磁盘缓存目录，为空时只使用内存
+	entries   map[string]entry
+	calls     map[string]*call
+	writes    int
+	now       func() time.Time
+}
+
+// entry 缓存条目，同时也是磁盘文件的格式
+type entry struct {
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// call 正在进行的加载，等待者共享其结果
+type call struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// New 创建缓存，ttl 为 0 时使用默认有效期；dir 非空时条目同时保存到该目录，重启后仍然有效
+func New(ttl time.Duration, dir string) (*Cache, error) {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+
+	return &Cache{
+		ttl:     ttl,
+		dir:     dir,
+		entries: make(map[string]entry),
+		calls:   make(map[string]*call),
+		now:     time.Now,
+	}, nil
+}
+
+// Get 获取未过期的缓存值
+func (c *Cache) Get(key string) ([]byte, bool) {
+	return c.get(key)
+}
+
+// Set 写入缓存值
+func (c *Cache) Set(key string, value []byte) {
+	c.set(key, value)
+}
+
+// Do 获取缓存值，不存在时调用 load 加载并缓存；同一个键的并发调用共享一次加载，加载失败的结果不缓存。
+// load panic 时等待者收到错误，panic 继续传给发起加载的调用方
+func (c *Cache) Do(key string, load func() ([]byte, error)) ([]byte, error) {
+	if value, ok := c.get(key); ok {
+		return value, nil
+	}
+
+	c.mu.Lock()
+	// 读取磁盘期间其他调用可能已经加载完成
+	if value, ok := c.memory(key); ok {
+		c.mu.Unlock()
+		return value, nil
+	}
+	if inflight, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-inflight.done
+		return inflight.value, inflight.err
+	}
+
+	current := &call{done: make(chan struct{}), err: errLoadPanicked}
+	c.calls[key] = current
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(current.done)
+	}()
+
+	current.value, current.err = load()
+	if current.err == nil {
+		c.set(key, current.value)
+	}
+	return current.value, current.err
+}
+
+// Load 以 JSON 编码缓存任意类型的值，语义与 Do 相同
+func Load[T any](c *Cache, key string, load func() (T, error)) (T, error) {
+	var value T
+	if c == nil {
+		return load()
+	}
+
+	data, err := c.Do(key, func() ([]byte, error) {
+		loaded, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(loaded)
+	})
+	if err != nil {
+		return value, err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("failed to decode cached value for %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// get 依次查找内存和磁盘，读取磁盘时不持有全局锁
+func (c *Cache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	value, ok := c.memory(key)
+	c.mu.Unlock()
+	if ok || c.dir == "" {
+		return value, ok
+	}
+
+	e, ok := c.readFile(key)
+	if !ok {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 读取磁盘期间写入的新值优先
+	if value, ok := c.memory(key); ok {
+		return value, true
+	}
+	c.entries[key] = e
+	return e.Value, true
+}
+
+// memory 查找内存中未过期的条目，调用方需持有锁
+func (c *Cache) memory(key string) ([]byte, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.ExpiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.Value, true
+}
+
+// set 写入内存，再在全局锁之外写入磁盘
+func (c *Cache) set(key string, value []byte) {
+	e := entry{Key: key, Value: value, ExpiresAt: c.now().Add(c.ttl)}
+
+	c.mu.Lock()
+	c.entries[key] = e
+	c.writes++
+	if c.writes%purgeInterval == 0 {
+		c.purge()
+	}
+	c.mu.Unlock()
+
+	if c.dir != "" {
+		c.writeFile(e)
+	}
+}
+
+// readFile 读取键对应的磁盘文件，过期的文件直接删除
+func (c *Cache) readFile(key string) (entry, bool) {
+	lock := c.fileLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to read cache file %s: %v", path, err)
+		}
+		return entry{}, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		log.Printf("Ignoring invalid cache file %s", path)
+		return entry{}, false
+	}
+	if !c.now().Before(e.ExpiresAt) {
+		os.Remove(path)
+		return entry{}, false
+	}
+	return e, true
+}
+
+// writeFile 将条目写入磁盘文件
+func (c *Cache) writeFile(e entry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Failed to encode cache entry %s: %v", e.Key, err)
+		return
+	}
+
+	lock := c.fileLock(e.Key)
+	lock.Lock()
+	defer lock.Unlock()
+	if err := os.WriteFile(c.path(e.Key), data, 0o644); err != nil {
+		log.Printf("Failed to write cache entry %s: %v", e.Key, err)
+	}
+}
+
+// fileLock 返回键对应的磁盘文件锁，同一个键的读写互斥，不同的键通常互不影响
+func (c *Cache) fileLock(key string) *sync.Mutex {
+	sum := sha256.Sum256([]byte(key))
+	return &c.fileLocks[int(sum[0])%fileLockCount]
+}
+
+// purge 清理内存中的过期条目，磁盘文件在下次读取时清理
+func (c *Cache) purge() {
+	now := c.now()
+	for key, e := range c.entries {
+		if !now.Before(e.ExpiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// path 缓存键对应的磁盘文件，使用哈希避免键中的特殊字符
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
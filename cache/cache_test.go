@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCache 创建使用可控时钟的缓存
+func newTestCache(t *testing.T, ttl time.Duration, dir string) (*Cache, *time.Time) {
+	c, err := New(ttl, dir)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCacheExpires(t *testing.T) {
+	c, now := newTestCache(t, time.Hour, "")
+
+	_, ok := c.Get("key")
+	assert.False(t, ok)
+
+	c.Set("key", []byte("value"))
+	value, ok := c.Get("key")
+	require.True(t, ok)
+	assert.Equal(t, "value", string(value))
+
+	*now = now.Add(time.Hour)
+	_, ok = c.Get("key")
+	assert.False(t, ok)
+}
+
+func TestCacheDiskBacking(t *testing.T) {
+	dir := t.TempDir()
+	c, _ := newTestCache(t, time.Hour, dir)
+	c.Set("hn:item:1", []byte("not json \x00"))
+
+	// 新的缓存实例从磁盘读取
+	reopened, now := newTestCache(t, time.Hour, dir)
+	value, ok := reopened.Get("hn:item:1")
+	require.True(t, ok)
+	assert.Equal(t, "not json \x00", string(value))
+
+	*now = now.Add(2 * time.Hour)
+	reopened.entries = make(map[string]entry)
+	_, ok = reopened.Get("hn:item:1")
+	assert.False(t, ok)
+	assert.NoFileExists(t, reopened.path("hn:item:1"))
+}
+
+func TestCacheDoCoalesces(t *testing.T) {
+	c, _ := newTestCache(t, time.Hour, "")
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err := c.Do("key", func() ([]byte, error) {
+				loads.Add(1)
+				<-release
+				return []byte("value"), nil
+			})
+			assert.NoError(t, err)
+			results[i] = string(value)
+		}(i)
+	}
+
+	// 等待第一个加载开始后再放行，其余调用应等待同一次加载
+	require.Eventually(t, func() bool { return loads.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+	for _, result := range results {
+		assert.Equal(t, "value", result)
+	}
+}
+
+func TestCacheDoDoesNotCacheErrors(t *testing.T) {
+	c, _ := newTestCache(t, time.Hour, "")
+	loadErr := errors.New("boom")
+
+	_, err := c.Do("key", func() ([]byte, error) { return nil, loadErr })
+	assert.ErrorIs(t, err, loadErr)
+
+	value, err := c.Do("key", func() ([]byte, error) { return []byte("ok"), nil })
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(value))
+}
+
+func TestCacheDoReleasesWaitersOnPanic(t *testing.T) {
+	c, _ := newTestCache(t, time.Hour, "")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		c.Do("key", func() ([]byte, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	waited := make(chan error, 1)
+	go func() {
+		_, err := c.Do("key", func() ([]byte, error) { return []byte("value"), nil })
+		waited <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-waited:
+		assert.ErrorIs(t, err, errLoadPanicked)
+	case <-time.After(time.Second):
+		t.Fatal("waiter blocked after load panicked")
+	}
+
+	// 之后的调用重新加载
+	value, err := c.Do("key", func() ([]byte, error) { return []byte("value"), nil })
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+}
+
+func TestLoad(t *testing.T) {
+	type item struct {
+		ID   int    `json:"id"`
+		Text string `json:"text"`
+	}
+	c, _ := newTestCache(t, time.Hour, "")
+
+	calls := 0
+	load := func() (item, error) {
+		calls++
+		return item{ID: 1, Text: "评论"}, nil
+	}
+	for range 2 {
+		got, err := Load(c, "hn:item:1", load)
+		require.NoError(t, err)
+		assert.Equal(t, item{ID: 1, Text: "评论"}, got)
+	}
+	assert.Equal(t, 1, calls)
+
+	// 未设置缓存时直接加载
+	_, err := Load(nil, "hn:item:1", load)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
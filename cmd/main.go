@@ -1,30 +1,57 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // 系统没有时区数据时仍能解析 scheduler.timezone
 
 	"hacker-news-daily/ai"
+	"hacker-news-daily/article"
+	"hacker-news-daily/cache"
 	config "hacker-news-daily/configs"
+	"hacker-news-daily/feed"
+	"hacker-news-daily/fixture"
 	"hacker-news-daily/hackernews"
+	"hacker-news-daily/publisher"
+	"hacker-news-daily/retry"
 	"hacker-news-daily/scheduler"
+	"hacker-news-daily/site"
+	"hacker-news-daily/storage"
 	"hacker-news-daily/telegram"
 )
 
+// defaultShutdownTimeout 收到退出信号后等待正在执行的任务完成的默认时间
+const defaultShutdownTimeout = 60 * time.Second
+
 var (
 	configPath = flag.String("config", "configs/config.yaml", "配置文件路径")
 	runOnce    = flag.Bool("once", false, "立即执行一次任务后退出")
 	sendNow    = flag.Bool("send", false, "启动时立即发送一次消息，然后继续运行支持交互")
-	dateFlag   = flag.String("date", "", "指定日期 (YYYY-MM-DD)，默认为今天")
+	dateFlag   = flag.String("date", "", "指定日期 (YYYY-MM-DD)，生成当天 0 点到 24 点发布的故事的总结，默认为截止到当前时间的 24 小时")
+	fromFlag   = flag.String("from", "", "与 -to 一起使用，生成从该日期 (YYYY-MM-DD) 0 点开始发布的故事的总结")
+	toFlag     = flag.String("to", "", "与 -from 一起使用，生成截止到该日期 (YYYY-MM-DD) 24 点发布的故事的总结，默认为今天")
+	genSite    = flag.Bool("generate-site", false, "将已保存的每日总结生成静态网站后退出")
+	siteDir    = flag.String("site-dir", "", "静态网站输出目录，默认使用配置中的 site.output_dir")
+	digestFlag = flag.String("digest", "", "与 -once 或 -send 一起使用，指定生成 digests 中配置的摘要，默认为每日总结")
 )
 
 func main() {
 	flag.Parse()
+	if *toFlag != "" && *fromFlag == "" {
+		log.Fatal("-to must be used with -from")
+	}
+	if *fromFlag != "" && *dateFlag != "" {
+		log.Fatal("-date cannot be used with -from/-to")
+	}
 
 	// 加载配置
 	cfg, err := config.Load(*configPath)
@@ -32,47 +59,251 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// 打开持久化存储
+	store, err := storage.Open(cfg.Storage.Type, cfg.Storage.Path)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	// 详细总结的存储和缓存按模型区分，切换模型后不复用旧结果
+	summaryModel := cfg.AI.Provider + "/" + cfg.AI.Model
+
+	// 生成静态网站模式不需要连接 Telegram
+	if *genSite {
+		outputDir := *siteDir
+		if outputDir == "" {
+			outputDir = cfg.Site.OutputDir
+		}
+		if outputDir == "" {
+			log.Fatal("Site output directory is not configured")
+		}
+		generator := site.NewGenerator(store, outputDir, cfg.Site.BaseURL, cfg.Site.PageSize)
+		generator.SetSummaryModel(summaryModel)
+		if err := generator.Generate(); err != nil {
+			log.Fatalf("Failed to generate site: %v", err)
+		}
+		return
+	}
+
+	// 定时任务和故事日期使用的时区
+	location := time.Local
+	if cfg.Scheduler.Timezone != "" {
+		if location, err = time.LoadLocation(cfg.Scheduler.Timezone); err != nil {
+			log.Fatalf("Invalid scheduler.timezone: %v", err)
+		}
+	}
+
 	// 初始化客户端
+	retryConfig := retry.Config{
+		MaxAttempts:      cfg.Retry.MaxAttempts,
+		InitialBackoff:   time.Duration(cfg.Retry.InitialBackoff) * time.Millisecond,
+		MaxBackoff:       time.Duration(cfg.Retry.MaxBackoff) * time.Millisecond,
+		MaxRetryAfter:    time.Duration(cfg.Retry.MaxRetryAfter) * time.Second,
+		BreakerThreshold: cfg.Retry.BreakerThreshold,
+		BreakerCooldown:  time.Duration(cfg.Retry.BreakerCooldown) * time.Second,
+	}
+	fixtureMode, err := fixture.ParseMode(cfg.Fixture.Mode)
+	if err != nil {
+		log.Fatalf("Invalid fixture config: %v", err)
+	}
+	var fixtureTransport *fixture.Transport
+	if fixtureMode != fixture.Off {
+		fixtureTransport = fixture.NewTransport(fixtureMode, cfg.Fixture.Dir, nil)
+		log.Printf("Fixture mode %s, fixtures in %s", fixtureMode, cfg.Fixture.Dir)
+	}
+
+	if cfg.HackerNews.MaxStories < 0 {
+		log.Fatalf("Invalid hacker_news.max_stories: %d, must not be negative", cfg.HackerNews.MaxStories)
+	}
 	hnClient := hackernews.NewClient(cfg.HackerNews.Timeout, cfg.HackerNews.MaxTopLevelComments, cfg.HackerNews.MaxChildComments)
-	aiClient := ai.NewClient(cfg.AI.BaseURL, cfg.AI.APIKey, cfg.AI.Model, cfg.AI.MaxTokens)
+	hnClient.SetBaseURLs(cfg.HackerNews.AlgoliaBaseURL, cfg.HackerNews.FirebaseBaseURL)
+	hnClient.SetRetryConfig(retryConfig)
+	hnClient.SetLocation(location)
+	hnClient.SetCommentDepth(cfg.HackerNews.MaxCommentDepth)
+	hnClient.SetRateLimit(cfg.HackerNews.MaxConcurrentRequests, cfg.HackerNews.RequestsPerSecond)
+	ranking := hackernews.Ranking{
+		Formula:       cfg.HackerNews.Ranking.Formula,
+		CommentWeight: cfg.HackerNews.Ranking.CommentWeight,
+		Gravity:       cfg.HackerNews.Ranking.Gravity,
+	}
+	if err := ranking.Validate(); err != nil {
+		log.Fatalf("Invalid hacker_news.ranking: %v", err)
+	}
+	hnClient.SetRanking(ranking)
+	if cfg.HackerNews.FetchArticle {
+		fetcher := article.NewFetcher(cfg.HackerNews.Timeout, cfg.HackerNews.ArticleMaxLength)
+		fetcher.SetRetryConfig(retryConfig)
+		if fixtureTransport != nil {
+			fetcher.SetTransport(fixtureTransport)
+		}
+		hnClient.SetArticleFetcher(fetcher)
+	}
+	aiProvider, err := ai.NewProvider(cfg.AI.Provider, cfg.AI.BaseURL, cfg.AI.APIKey, cfg.AI.Model, cfg.AI.MaxTokens)
+	if err != nil {
+		log.Fatalf("Failed to create AI provider: %v", err)
+	}
+	aiClient := ai.NewClientWithProvider(aiProvider)
+	aiClient.SetTokenBudget(cfg.AI.ContextLimit, cfg.AI.MaxTokens)
+	aiClient.SetRetryConfig(retryConfig)
+	aiClient.SetRequestTimeout(time.Duration(cfg.Timeouts.AIRequest) * time.Second)
+	if fixtureTransport != nil {
+		hnClient.SetTransport(fixtureTransport)
+		aiClient.SetTransport(fixtureTransport)
+	}
+	if cfg.Cache.Enabled {
+		itemCache, err := cache.New(time.Duration(cfg.Cache.TTL)*time.Minute, cfg.Cache.Dir)
+		if err != nil {
+			log.Fatalf("Failed to create cache: %v", err)
+		}
+		hnClient.SetCache(itemCache)
+		aiClient.SetCache(itemCache, summaryModel)
+	}
 	tgBot, err := telegram.NewBot(cfg.Telegram.BotToken, cfg.Telegram.ChatID, cfg.Telegram.ProxyURL, cfg.HackerNews.MaxStories)
 	if err != nil {
 		log.Fatalf("Failed to create telegram bot: %v", err)
 	}
+	tgBot.SetRetryConfig(retryConfig)
+	dedupPolicy := telegram.DedupPolicy{Mode: cfg.Telegram.Dedup.Mode, Days: cfg.Telegram.Dedup.Days}
+	if err := dedupPolicy.Validate(); err != nil {
+		log.Fatalf("Invalid telegram.dedup: %v", err)
+	}
+	tgBot.SetDedupPolicy(dedupPolicy)
+	subscriptionPolicy := telegram.SubscriptionPolicy{Mode: cfg.Telegram.Subscription.Mode, AllowedChats: cfg.Telegram.Subscription.AllowedChats}
+	if err := subscriptionPolicy.Validate(); err != nil {
+		log.Fatalf("Invalid telegram.subscription: %v", err)
+	}
+	tgBot.SetSubscriptionPolicy(subscriptionPolicy)
+	tgBot.SetStageTimeouts(telegram.StageTimeouts{
+		FetchStories: time.Duration(cfg.Timeouts.FetchStories) * time.Second,
+		FetchContent: time.Duration(cfg.Timeouts.FetchContent) * time.Second,
+		Summarize:    time.Duration(cfg.Timeouts.Summarize) * time.Second,
+		Publish:      time.Duration(cfg.Timeouts.Publish) * time.Second,
+	})
 
 	// 设置Telegram机器人的客户端
 	tgBot.SetClients(aiClient, hnClient)
+	tgBot.SetSummaryModel(summaryModel)
+	dailySource, err := hnClient.Source(cfg.HackerNews.Source)
+	if err != nil {
+		log.Fatalf("Invalid hacker_news.source: %v", err)
+	}
+	tgBot.SetStorySource(dailySource)
+	tgBot.SetStore(store)
+
+	// 设置其他输出渠道
+	var publishers []publisher.Publisher
+	if cfg.Email.Enabled {
+		emailPublisher, err := publisher.NewEmailPublisher(publisher.EmailConfig{
+			Host:     cfg.Email.Host,
+			Port:     cfg.Email.Port,
+			Username: cfg.Email.Username,
+			Password: cfg.Email.Password,
+			From:     cfg.Email.From,
+			To:       cfg.Email.To,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create email publisher: %v", err)
+		}
+		emailPublisher.SetRetryConfig(retryConfig)
+		publishers = append(publishers, emailPublisher)
+	}
+	if cfg.Slack.Enabled {
+		slackPublisher, err := publisher.NewSlackPublisher(cfg.Slack.WebhookURL)
+		if err != nil {
+			log.Fatalf("Failed to create slack publisher: %v", err)
+		}
+		slackPublisher.SetRetryConfig(retryConfig)
+		publishers = append(publishers, slackPublisher)
+	}
+	if cfg.Discord.Enabled {
+		discordPublisher, err := publisher.NewDiscordPublisher(cfg.Discord.WebhookURL)
+		if err != nil {
+			log.Fatalf("Failed to create discord publisher: %v", err)
+		}
+		discordPublisher.SetRetryConfig(retryConfig)
+		publishers = append(publishers, discordPublisher)
+	}
+	feedOptions := feed.Options{BaseURL: cfg.Feed.BaseURL, Location: location}
+	if cfg.Feed.OutputDir != "" {
+		publishers = append(publishers, feed.NewFilePublisher(store, cfg.Feed.OutputDir, cfg.Feed.Days, feedOptions))
+	}
+	tgBot.SetPublishers(publishers...)
+
+	// 启动订阅 HTTP 服务
+	if cfg.Feed.ListenAddr != "" {
+		server := &http.Server{
+			Addr:    cfg.Feed.ListenAddr,
+			Handler: feed.NewHandler(store, cfg.Feed.Days, feedOptions),
+		}
+		go func() {
+			log.Printf("Feed server listening on %s", cfg.Feed.ListenAddr)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Feed server stopped: %v", err)
+			}
+		}()
+		defer server.Close()
+	}
+
+	// 收到退出信号后不再接收新任务，等待正在执行的任务完成，超过等待时间后取消 ctx 中止任务
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopping := make(chan struct{})
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+
+		timeout := time.Duration(cfg.Timeouts.Shutdown) * time.Second
+		if timeout <= 0 {
+			timeout = defaultShutdownTimeout
+		}
+		log.Printf("Shutting down, waiting up to %v for running jobs...", timeout)
+		close(stopping)
+		time.AfterFunc(timeout, cancel)
+	}()
 
 	// 启动Telegram消息处理器
-	tgBot.StartMessageHandler()
+	tgBot.StartMessageHandler(ctx)
 	defer tgBot.StopMessageHandler()
 
-	// 创建主任务
-	job := func() error {
-		date := *dateFlag
-		// date为空时，GetTopStoriesByDate会自动获取过去24小时的内容
-
-		if date == "" {
-			date = time.Now().Format("2006-01-02")
-			log.Printf("Processing numbered Hacker News daily summary for the last 24 hours")
-		} else {
-			log.Printf("Processing numbered Hacker News daily summary for date: %s", date)
+	// 每日总结之外按配置定时生成的摘要，-once 和 -send 可以通过 -digest 指定
+	dailyDigest := telegram.Digest{MaxStories: cfg.HackerNews.MaxStories}
+	digests, err := buildDigests(hnClient, cfg.Digests)
+	if err != nil {
+		log.Fatalf("Invalid digest config: %v", err)
+	}
+	selectedDigest := dailyDigest
+	if *digestFlag != "" {
+		scheduled, ok := findDigest(digests, *digestFlag)
+		if !ok {
+			log.Fatalf("Unknown digest: %s", *digestFlag)
 		}
+		selectedDigest = scheduled.digest
+	}
 
-		return processDailySummary(tgBot, date, cfg.HackerNews.MaxStories)
+	// 创建 -once 和 -send 执行的任务，按 -date 或 -from/-to 确定故事的发布时间段
+	job := func(ctx context.Context, digest telegram.Digest) error {
+		switch {
+		case *fromFlag != "":
+			to := *toFlag
+			if to == "" {
+				to = hnClient.Today()
+			}
+			log.Printf("Processing numbered Hacker News %s from %s to %s", digestName(digest), *fromFlag, to)
+			return processDigestRange(ctx, tgBot, digest, *fromFlag, to)
+		case *dateFlag != "":
+			log.Printf("Processing numbered Hacker News %s for date: %s", digestName(digest), *dateFlag)
+		default:
+			// date为空时，ProcessDigest会获取截止到当前时间的内容
+			log.Printf("Processing numbered Hacker News %s up to now", digestName(digest))
+		}
+		return processDigest(ctx, tgBot, digest, *dateFlag)
 	}
 
 	// 如果指定了立即发送，执行一次带编号的消息发送
 	if *sendNow {
-		date := *dateFlag
-		if date == "" {
-			date = time.Now().Format("2006-01-02")
-			log.Printf("Sending numbered Hacker News daily summary for the last 24 hours")
-		} else {
-			log.Printf("Sending numbered Hacker News daily summary for date: %s", date)
-		}
-
-		if err := processDailySummary(tgBot, date, cfg.HackerNews.MaxStories); err != nil {
+		if err := job(ctx, selectedDigest); err != nil {
 			log.Fatalf("Send execution failed: %v", err)
 		}
 		log.Println("Initial numbered summary sent successfully, bot continues running for interaction...")
@@ -80,7 +311,7 @@ func main() {
 
 	// 如果指定了立即运行，执行一次任务然后退出
 	if *runOnce {
-		if err := job(); err != nil {
+		if err := job(ctx, selectedDigest); err != nil {
 			log.Fatalf("Job execution failed: %v", err)
 		}
 		log.Println("Once execution completed, exiting...")
@@ -88,34 +319,124 @@ func main() {
 	}
 
 	// 设置定时任务
-	sched := scheduler.NewScheduler()
-	if err := sched.AddJob(cfg.Scheduler.Cron, job); err != nil {
+	sched := scheduler.NewScheduler(ctx, location)
+	if err := sched.AddJob(cfg.Scheduler.Cron, func(ctx context.Context) error {
+		return processDigest(ctx, tgBot, dailyDigest, "")
+	}); err != nil {
 		log.Fatalf("Failed to add scheduled job: %v", err)
 	}
+	for _, scheduled := range digests {
+		if err := sched.AddJob(scheduled.cron, func(ctx context.Context) error {
+			return processDigest(ctx, tgBot, scheduled.digest, "")
+		}); err != nil {
+			log.Fatalf("Failed to add scheduled job for digest %s: %v", scheduled.digest.Name, err)
+		}
+		log.Printf("Digest %s scheduled with cron: %s", scheduled.digest.Name, scheduled.cron)
+	}
+
+	// 每分钟检查是否有订阅者设置了当前时间推送
+	if err := sched.AddJob("0 * * * * *", func(ctx context.Context) error {
+		return tgBot.DeliverScheduledDigests(ctx, time.Now().In(location))
+	}); err != nil {
+		log.Fatalf("Failed to add subscriber delivery job: %v", err)
+	}
 
 	sched.Start()
 	defer sched.Stop()
 
-	log.Printf("Hacker News Daily Bot started with cron: %s", cfg.Scheduler.Cron)
+	log.Printf("Hacker News Daily Bot started with cron: %s (%s)", cfg.Scheduler.Cron, location)
 
-	// 等待退出信号
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// 等待退出信号，返回时依次停止调度器和消息处理器，等待正在执行的任务完成
+	<-stopping
+}
+
+// processDigest 处理并发送截止到 date 的带编号摘要，date 为空时截止到当前时间
+func processDigest(ctx context.Context, tgBot *telegram.Bot, digest telegram.Digest, date string) error {
+	return reportDigestError(ctx, tgBot, digest, tgBot.ProcessDigest(ctx, digest, date))
+}
 
-	log.Println("Shutting down...")
+// processDigestRange 处理并发送 from 到 to 这几天的带编号摘要
+func processDigestRange(ctx context.Context, tgBot *telegram.Bot, digest telegram.Digest, from, to string) error {
+	return reportDigestError(ctx, tgBot, digest, tgBot.ProcessDigestRange(ctx, digest, from, to))
 }
 
-// processDailySummary 处理并发送带编号的每日总结
-func processDailySummary(tgBot *telegram.Bot, date string, maxStories int) error {
-	// 使用 bot 的 ProcessDailySummary 方法
-	if err := tgBot.ProcessDailySummary(date, maxStories); err != nil {
-		// 如果发送失败，尝试发送错误信息
-		if sendErr := tgBot.SendError(fmt.Sprintf("发送带编号总结失败: %v", err)); sendErr != nil {
-			log.Printf("Failed to send error message: %v", sendErr)
+// reportDigestError 摘要处理失败时尝试向 Telegram 发送错误信息
+func reportDigestError(ctx context.Context, tgBot *telegram.Bot, digest telegram.Digest, err error) error {
+	if err == nil {
+		return nil
+	}
+	if sendErr := tgBot.SendError(ctx, fmt.Sprintf("发送带编号总结失败: %v", err)); sendErr != nil {
+		log.Printf("Failed to send error message: %v", sendErr)
+	}
+	return fmt.Errorf("failed to process %s: %w", digestName(digest), err)
+}
+
+// scheduledDigest 配置的摘要及其定时任务
+type scheduledDigest struct {
+	cron   string
+	digest telegram.Digest
+}
+
+// buildDigests 根据配置创建摘要，名称为空或重复、缺少定时任务、周期或故事来源无效时返回错误
+func buildDigests(hnClient *hackernews.Client, configs []config.DigestConfig) ([]scheduledDigest, error) {
+	digests := make([]scheduledDigest, 0, len(configs))
+	for _, c := range configs {
+		// 名称是存储键的一部分
+		if c.Name == "" || strings.ContainsAny(c.Name, "/: ") {
+			return nil, fmt.Errorf("digest name %q must be non-empty and must not contain '/', ':' or spaces", c.Name)
+		}
+		if _, ok := findDigest(digests, c.Name); ok {
+			return nil, fmt.Errorf("duplicate digest name: %s", c.Name)
+		}
+		if c.Cron == "" {
+			return nil, fmt.Errorf("digest %s: cron is required", c.Name)
+		}
+		if err := telegram.ValidatePeriod(c.Period); err != nil {
+			return nil, fmt.Errorf("digest %s: %w", c.Name, err)
+		}
+		if c.MaxStories < 0 {
+			return nil, fmt.Errorf("digest %s: max_stories must not be negative", c.Name)
+		}
+
+		digest := telegram.Digest{
+			Name:       c.Name,
+			Title:      c.Title,
+			Days:       c.Days,
+			MaxStories: c.MaxStories,
+			Period:     c.Period,
+		}
+		// 周报和月报汇总已保存的每日总结，不从故事来源获取
+		if c.Period != "" {
+			if c.Source != "" || c.Days != 0 {
+				return nil, fmt.Errorf("digest %s: source and days cannot be used with period %s", c.Name, c.Period)
+			}
+		} else {
+			source, err := hnClient.Source(c.Source)
+			if err != nil {
+				return nil, fmt.Errorf("digest %s: %w", c.Name, err)
+			}
+			digest.Source = source
+		}
+
+		digests = append(digests, scheduledDigest{cron: c.Cron, digest: digest})
+	}
+	return digests, nil
+}
+
+// findDigest 按名称查找配置的摘要
+func findDigest(digests []scheduledDigest, name string) (scheduledDigest, bool) {
+	for _, scheduled := range digests {
+		if scheduled.digest.Name == name {
+			return scheduled, true
 		}
-		return fmt.Errorf("failed to process daily summary: %w", err)
 	}
+	return scheduledDigest{}, false
+}
 
-	return nil
+// digestName 日志中的摘要名称
+func digestName(digest telegram.Digest) string {
+	if digest.Name == "" {
+		return "daily summary"
+	}
+	return "digest " + digest.Name
 }
@@ -17,6 +17,17 @@ type Config struct {
 	Telegram   TelegramConfig   `mapstructure:"telegram"`
 	HackerNews HackerNewsConfig `mapstructure:"hacker_news"`
 	Scheduler  SchedulerConfig  `mapstructure:"scheduler"`
+	Storage    StorageConfig    `mapstructure:"storage"`
+	Email      EmailConfig      `mapstructure:"email"`
+	Slack      WebhookConfig    `mapstructure:"slack"`
+	Discord    WebhookConfig    `mapstructure:"discord"`
+	Site       SiteConfig       `mapstructure:"site"`
+	Feed       FeedConfig       `mapstructure:"feed"`
+	Cache      CacheConfig      `mapstructure:"cache"`
+	Retry      RetryConfig      `mapstructure:"retry"`
+	Timeouts   TimeoutConfig    `mapstructure:"timeouts"`
+	Fixture    FixtureConfig    `mapstructure:"fixture"`
+	Digests    []DigestConfig   `mapstructure:"digests"`
 }
 
 // 全局配置实例和互斥锁
@@ -26,27 +37,133 @@ var (
 )
 
 type AIConfig struct {
-	BaseURL   string `mapstructure:"base_url"`
-	APIKey    string `mapstructure:"api_key"`
-	Model     string `mapstructure:"model"`
-	MaxTokens int    `mapstructure:"max_tokens"`
+	Provider     string `mapstructure:"provider"` // openai、anthropic、gemini 或 ollama
+	BaseURL      string `mapstructure:"base_url"` // 为空时使用各后端的默认地址
+	APIKey       string `mapstructure:"api_key"`
+	Model        string `mapstructure:"model"`
+	MaxTokens    int    `mapstructure:"max_tokens"`
+	ContextLimit int    `mapstructure:"context_limit"` // 模型上下文窗口 token 数，0 表示不分批
 }
 
 type TelegramConfig struct {
-	BotToken string `mapstructure:"bot_token"`
-	ChatID   string `mapstructure:"chat_id"`
-	ProxyURL string `mapstructure:"proxy_url"`
+	BotToken     string             `mapstructure:"bot_token"`
+	ChatID       string             `mapstructure:"chat_id"`
+	ProxyURL     string             `mapstructure:"proxy_url"`
+	Dedup        DedupConfig        `mapstructure:"dedup"`
+	Subscription SubscriptionConfig `mapstructure:"subscription"`
+}
+
+// SubscriptionConfig 哪些聊天可以订阅，管理员即 chat_id 对应的聊天
+type SubscriptionConfig struct {
+	Mode         string  `mapstructure:"mode"`          // approval、allowlist 或 open，为空时为 approval
+	AllowedChats []int64 `mapstructure:"allowed_chats"` // 无需批准即可订阅的聊天 ID
+}
+
+// DedupConfig 同一聊天中重复出现的故事的处理方式
+type DedupConfig struct {
+	Mode string `mapstructure:"mode"` // off、exclude 或 mark，为空时为 off
+	Days int    `mapstructure:"days"` // 只与最近多少天内发送过的故事比较，0 使用默认值 7
 }
 
 type HackerNewsConfig struct {
-	Timeout             int `mapstructure:"timeout"`
-	MaxStories          int `mapstructure:"max_stories"`
-	MaxTopLevelComments int `mapstructure:"max_top_level_comments"`
-	MaxChildComments    int `mapstructure:"max_child_comments"`
+	Timeout               int           `mapstructure:"timeout"`
+	MaxStories            int           `mapstructure:"max_stories"`
+	MaxTopLevelComments   int           `mapstructure:"max_top_level_comments"`
+	MaxChildComments      int           `mapstructure:"max_child_comments"`
+	MaxCommentDepth       int           `mapstructure:"max_comment_depth"`       // 获取的评论层数，0 使用默认值 2
+	FetchArticle          bool          `mapstructure:"fetch_article"`           // 是否抓取链接原文
+	ArticleMaxLength      int           `mapstructure:"article_max_length"`      // 原文最大字符数
+	MaxConcurrentRequests int           `mapstructure:"max_concurrent_requests"` // API 最大并发请求数，0 使用默认值
+	RequestsPerSecond     float64       `mapstructure:"requests_per_second"`     // API 每秒请求数上限，0 使用默认值
+	Source                string        `mapstructure:"source"`                  // 每日总结的故事来源，为空时使用 front_page
+	AlgoliaBaseURL        string        `mapstructure:"algolia_base_url"`        // 搜索 API 地址，为空时使用官方地址
+	FirebaseBaseURL       string        `mapstructure:"firebase_base_url"`       // 条目 API 地址，为空时使用官方地址
+	Ranking               RankingConfig `mapstructure:"ranking"`
+}
+
+// RankingConfig 从时间段内的所有故事中挑选热门故事的排序方式
+type RankingConfig struct {
+	Formula       string  `mapstructure:"formula"`        // points、comments、velocity 或 gravity，为空时为 points
+	CommentWeight float64 `mapstructure:"comment_weight"` // 每条评论折算的分数
+	Gravity       float64 `mapstructure:"gravity"`        // gravity 公式的重力系数，0 使用默认值 1.8
 }
 
 type SchedulerConfig struct {
-	Cron string `mapstructure:"cron"`
+	Cron     string `mapstructure:"cron"`
+	Timezone string `mapstructure:"timezone"` // IANA 时区，定时任务和故事日期都按该时区计算，为空时使用系统时区
+}
+
+// DigestConfig 每日总结之外按计划生成的摘要
+type DigestConfig struct {
+	Name       string `mapstructure:"name"`        // 唯一名称，用于保存总结和 -digest 参数
+	Title      string `mapstructure:"title"`       // 消息标题，为空时使用每日总结的标题，周报和月报使用各自的默认标题
+	Source     string `mapstructure:"source"`      // 故事来源：front_page、top、best、new、ask_hn、show_hn、launch_hn 或 who_is_hiring
+	Cron       string `mapstructure:"cron"`        // 生成时间，格式同 scheduler.cron
+	Days       int    `mapstructure:"days"`        // 包括最近多少天的故事，0 为 1 天
+	MaxStories int    `mapstructure:"max_stories"` // 故事数量，0 使用 hacker_news.max_stories
+	Period     string `mapstructure:"period"`      // weekly 或 monthly 时汇总已保存的每日总结，不使用 source 和 days
+}
+
+type StorageConfig struct {
+	Type string `mapstructure:"type"` // memory 或 bolt
+	Path string `mapstructure:"path"` // bolt 数据库文件路径
+}
+
+type EmailConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	Host     string   `mapstructure:"host"`
+	Port     int      `mapstructure:"port"` // 465 使用隐式 TLS，587/25 在服务器支持时使用 STARTTLS
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+type WebhookConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	WebhookURL string `mapstructure:"webhook_url"`
+}
+
+type SiteConfig struct {
+	OutputDir string `mapstructure:"output_dir"` // 静态网站输出目录
+	BaseURL   string `mapstructure:"base_url"`   // 网站部署地址，用于订阅中的绝对链接
+	PageSize  int    `mapstructure:"page_size"`  // 首页每页显示的天数
+}
+
+type CacheConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	TTL     int    `mapstructure:"ttl"` // 缓存有效期（分钟），0 使用默认值
+	Dir     string `mapstructure:"dir"` // 磁盘缓存目录，为空时只缓存在内存中
+}
+
+type RetryConfig struct {
+	MaxAttempts      int `mapstructure:"max_attempts"`      // 包括首次请求在内的最大尝试次数，0 使用默认值 3
+	InitialBackoff   int `mapstructure:"initial_backoff"`   // 第一次重试前的等待时间（毫秒），之后每次翻倍
+	MaxBackoff       int `mapstructure:"max_backoff"`       // 单次等待时间上限（毫秒）
+	MaxRetryAfter    int `mapstructure:"max_retry_after"`   // 服务端要求等待超过该秒数时放弃重试
+	BreakerThreshold int `mapstructure:"breaker_threshold"` // 同一服务连续失败多少次后熔断
+	BreakerCooldown  int `mapstructure:"breaker_cooldown"`  // 熔断后多少秒允许试探请求
+}
+
+type TimeoutConfig struct {
+	FetchStories int `mapstructure:"fetch_stories"` // 获取热门故事列表的超时时间（秒），0 使用默认值
+	FetchContent int `mapstructure:"fetch_content"` // 获取所有故事正文和评论的超时时间（秒）
+	Summarize    int `mapstructure:"summarize"`     // AI 生成总结的超时时间（秒）
+	Publish      int `mapstructure:"publish"`       // 发送到 Telegram（包括为订阅者翻译总结）和每个其他渠道的超时时间（秒）
+	AIRequest    int `mapstructure:"ai_request"`    // 单次 AI 请求的超时时间（秒）
+	Shutdown     int `mapstructure:"shutdown"`      // 收到退出信号后等待正在执行的任务完成的时间（秒）
+}
+
+type FixtureConfig struct {
+	Mode string `mapstructure:"mode"` // record 保存所有外部请求的响应，replay 只使用已保存的响应，为空时关闭
+	Dir  string `mapstructure:"dir"`  // 响应文件目录
+}
+
+type FeedConfig struct {
+	OutputDir  string `mapstructure:"output_dir"`  // 每日总结发布后写入 rss.xml 和 atom.xml 的目录，为空时不写文件
+	ListenAddr string `mapstructure:"listen_addr"` // 订阅 HTTP 服务监听地址，如 ":8080"，为空时不启动
+	BaseURL    string `mapstructure:"base_url"`    // 订阅对外访问地址，用于自身链接
+	Days       int    `mapstructure:"days"`        // 订阅包含最近的天数
 }
 
 // findProjectRoot 查找项目根目录
@@ -150,6 +267,9 @@ func Load(configPath string) (*Config, error) {
 	v.BindEnv("ai.api_key", "AI_API_KEY")
 	v.BindEnv("telegram.bot_token", "TELEGRAM_BOT_TOKEN")
 	v.BindEnv("telegram.chat_id", "TELEGRAM_CHAT_ID")
+	v.BindEnv("email.password", "EMAIL_PASSWORD")
+	v.BindEnv("slack.webhook_url", "SLACK_WEBHOOK_URL")
+	v.BindEnv("discord.webhook_url", "DISCORD_WEBHOOK_URL")
 
 	// 读取配置文件
 	if err := v.ReadInConfig(); err != nil {
@@ -0,0 +1,83 @@
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"hacker-news-daily/hackernews"
+)
+
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Atom 生成 Atom 订阅，每个故事一个条目，原文为 alternate 链接，HN 讨论为 related 链接
+func Atom(summaries []*hackernews.DailySummaryWithNumbers, options Options) ([]byte, error) {
+	items := entries(summaries, options.location())
+
+	document := atomDocument{
+		Title:   options.title(),
+		ID:      "urn:hacker-news-daily",
+		Updated: latest(items).Format(time.RFC3339),
+	}
+	if self := options.selfURL("atom.xml"); self != "" {
+		document.ID = self
+		document.Links = append(document.Links,
+			atomLink{Href: self, Rel: "self"},
+			atomLink{Href: options.BaseURL, Rel: "alternate"},
+		)
+	}
+
+	for _, item := range items {
+		entry := atomEntry{
+			Title:   item.title(),
+			ID:      item.id(),
+			Updated: item.updated.Format(time.RFC3339),
+			Links: []atomLink{
+				{Href: item.item.URL, Rel: "alternate"},
+				{Href: item.item.HackerNewsURL, Rel: "related", Title: "HN 讨论"},
+			},
+			Content: atomContent{Type: "html", Body: item.html()},
+		}
+		for _, tag := range item.item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		document.Entries = append(document.Entries, entry)
+	}
+
+	data, err := marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal atom feed: %w", err)
+	}
+	return data, nil
+}
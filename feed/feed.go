@@ -0,0 +1,157 @@
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/publisher"
+	"hacker-news-daily/storage"
+)
+
+const (
+	defaultTitle = "Hacker News 每日热点"
+	// hackerNewsURL 未配置对外访问地址时 RSS 频道使用的链接，RSS 2.0 要求频道必须有 link
+	hackerNewsURL = "https://news.ycombinator.com/"
+)
+
+// Options 订阅的元信息
+type Options struct {
+	Title    string         // 订阅标题，为空时使用默认标题
+	BaseURL  string         // 订阅对外访问地址，rss.xml 和 atom.xml 位于其下，为空时不输出自身链接
+	Location *time.Location // 总结日期所在的时区，与获取故事时的自然日一致，为空时使用 UTC
+}
+
+// entry 一个故事对应的订阅条目
+type entry struct {
+	key     string // 所属总结的存储键，区分不同日期和摘要中的同一故事
+	date    string
+	updated time.Time
+	item    publisher.Item
+}
+
+// entries 将每日总结展开为故事条目，保持传入顺序
+func entries(summaries []*hackernews.DailySummaryWithNumbers, location *time.Location) []entry {
+	var result []entry
+	for _, summary := range summaries {
+		updated := parseDate(summary.Date, location)
+		for _, item := range publisher.Items(summary) {
+			result = append(result, entry{key: summary.Key(), date: summary.Date, updated: updated, item: item})
+		}
+	}
+	return result
+}
+
+// title 条目标题，带上日期和编号
+func (e entry) title() string {
+	return fmt.Sprintf("[%s #%d] %s", e.date, e.item.Number, e.item.Title)
+}
+
+// id 条目唯一标识，由总结的存储键和故事 ID 组成，
+// 同一故事连续多天出现时每天都是独立的条目
+func (e entry) id() string {
+	return fmt.Sprintf("urn:hn-daily:%s:%d", e.key, e.item.StoryID)
+}
+
+// html 条目正文：总结、原文链接和 HN 讨论链接
+func (e entry) html() string {
+	var content strings.Builder
+	if e.item.Title != e.item.OriginalTitle && e.item.OriginalTitle != "" {
+		fmt.Fprintf(&content, "<p><em>%s</em></p>", template.HTMLEscapeString(e.item.OriginalTitle))
+	}
+	fmt.Fprintf(&content, "<p>%s</p>", template.HTMLEscapeString(e.item.Summary))
+	fmt.Fprintf(&content, `<p><a href="%s">原文</a> · <a href="%s">HN 讨论</a>`,
+		template.HTMLEscapeString(e.item.URL), template.HTMLEscapeString(e.item.HackerNewsURL))
+	if e.item.Score > 0 {
+		fmt.Fprintf(&content, " · %d points", e.item.Score)
+	}
+	content.WriteString("</p>")
+	return content.String()
+}
+
+// LoadRecent 从存储中读取最近 days 天的每日总结，最新的在前
+func LoadRecent(store storage.Store, days int) ([]*hackernews.DailySummaryWithNumbers, error) {
+	dates, err := store.ListDates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dates: %w", err)
+	}
+
+	var summaries []*hackernews.DailySummaryWithNumbers
+	for i := len(dates) - 1; i >= 0 && len(summaries) < days; i-- {
+		summary, err := store.GetDailySummary(dates[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daily summary for %s: %w", dates[i], err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// marshal 生成带 XML 声明的文档
+func marshal(v any) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// latest 返回最新条目的时间，没有条目时返回当前时间
+func latest(items []entry) time.Time {
+	var t time.Time
+	for _, item := range items {
+		if item.updated.After(t) {
+			t = item.updated
+		}
+	}
+	if t.IsZero() {
+		return time.Now().UTC()
+	}
+	return t
+}
+
+// parseDate 将 YYYY-MM-DD 解析为所在时区的零点，格式错误时返回零值
+func parseDate(date string, location *time.Location) time.Time {
+	t, err := time.ParseInLocation("2006-01-02", date, location)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// selfURL 返回订阅文件的绝对地址
+func (o Options) selfURL(name string) string {
+	if o.BaseURL == "" {
+		return ""
+	}
+	if !strings.HasSuffix(o.BaseURL, "/") {
+		return o.BaseURL + "/" + name
+	}
+	return o.BaseURL + name
+}
+
+// link 订阅频道的链接，未配置对外访问地址时使用 Hacker News 首页
+func (o Options) link() string {
+	if o.BaseURL == "" {
+		return hackerNewsURL
+	}
+	return o.BaseURL
+}
+
+// location 总结日期所在的时区
+func (o Options) location() *time.Location {
+	if o.Location == nil {
+		return time.UTC
+	}
+	return o.Location
+}
+
+func (o Options) title() string {
+	if o.Title == "" {
+		return defaultTitle
+	}
+	return o.Title
+}
@@ -0,0 +1,202 @@
+package feed
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/storage"
+)
+
+func newSummary(date string, id int) *hackernews.DailySummaryWithNumbers {
+	return &hackernews.DailySummaryWithNumbers{
+		Date: date,
+		Stories: []hackernews.Story{
+			{ID: id, Title: "Go 1.22 Released", URL: "https://go.dev/blog/go1.22", Score: 500, HackerNewsURL: "https://news.ycombinator.com/item?id=1"},
+			{ID: id + 1, Title: "Ask HN: What are you working on?", HackerNewsURL: "https://news.ycombinator.com/item?id=2"},
+		},
+		StorySummaries: []hackernews.StoryWithNumber{
+			{Number: 1, StoryID: id, Title: "Go 1.22 Released", Summary: "**Go 1.22 发布** 修改了 <for> 循环变量语义", Tags: []string{"Go", "编程语言"}},
+			{Number: 2, StoryID: id + 1, Title: "Ask HN: What are you working on?", Summary: "大家分享了各自的副业项目"},
+		},
+	}
+}
+
+func TestRSS(t *testing.T) {
+	data, err := RSS([]*hackernews.DailySummaryWithNumbers{newSummary("2024-01-15", 1)}, Options{BaseURL: "https://hn.example.com/feed"})
+	require.NoError(t, err)
+
+	var document rssDocument
+	require.NoError(t, xml.Unmarshal(data, &document))
+	assert.Equal(t, "Hacker News 每日热点", document.Channel.Title)
+	assert.Equal(t, "Mon, 15 Jan 2024 00:00:00 +0000", document.Channel.LastBuildDate)
+	require.Len(t, document.Channel.Items, 2)
+
+	item := document.Channel.Items[0]
+	assert.Equal(t, "[2024-01-15 #1] Go 1.22 发布", item.Title)
+	assert.Equal(t, "https://go.dev/blog/go1.22", item.Link)
+	assert.Equal(t, "https://news.ycombinator.com/item?id=1", item.Comments)
+	assert.Equal(t, "urn:hn-daily:2024-01-15:1", item.GUID.Value)
+	assert.False(t, item.GUID.IsPermaLink)
+	assert.Equal(t, []string{"Go", "编程语言"}, item.Categories)
+	assert.Contains(t, item.Description, "修改了 &lt;for&gt; 循环变量语义")
+	assert.Contains(t, item.Description, `<a href="https://news.ycombinator.com/item?id=1">HN 讨论</a>`)
+
+	// 没有外链的故事使用 HN 链接
+	assert.Equal(t, "https://news.ycombinator.com/item?id=2", document.Channel.Items[1].Link)
+
+	// 自身链接指向 rss.xml
+	assert.Contains(t, string(data), `<atom:link href="https://hn.example.com/feed/rss.xml" rel="self" type="application/rss+xml">`)
+}
+
+func TestRSSWithoutBaseURL(t *testing.T) {
+	data, err := RSS([]*hackernews.DailySummaryWithNumbers{newSummary("2024-01-15", 1)}, Options{})
+	require.NoError(t, err)
+
+	// RSS 2.0 要求频道有 link，未配置对外访问地址时指向 Hacker News 首页
+	var document rssDocument
+	require.NoError(t, xml.Unmarshal(data, &document))
+	assert.Equal(t, "https://news.ycombinator.com/", document.Channel.Link)
+	assert.Nil(t, document.Channel.AtomLink)
+}
+
+func TestFeedLocation(t *testing.T) {
+	options := Options{Location: time.FixedZone("CST", 8*60*60)}
+	summaries := []*hackernews.DailySummaryWithNumbers{newSummary("2024-01-15", 1)}
+
+	// 日期按配置的时区解析为当地零点
+	data, err := Atom(summaries, options)
+	require.NoError(t, err)
+	var atom atomDocument
+	require.NoError(t, xml.Unmarshal(data, &atom))
+	assert.Equal(t, "2024-01-15T00:00:00+08:00", atom.Updated)
+	assert.Equal(t, "2024-01-15T00:00:00+08:00", atom.Entries[0].Updated)
+
+	data, err = RSS(summaries, options)
+	require.NoError(t, err)
+	var rss rssDocument
+	require.NoError(t, xml.Unmarshal(data, &rss))
+	assert.Equal(t, "Mon, 15 Jan 2024 00:00:00 +0800", rss.Channel.LastBuildDate)
+}
+
+func TestAtom(t *testing.T) {
+	summaries := []*hackernews.DailySummaryWithNumbers{newSummary("2024-01-15", 1), newSummary("2024-01-14", 3)}
+	data, err := Atom(summaries, Options{Title: "HN 日报", BaseURL: "https://hn.example.com/"})
+	require.NoError(t, err)
+
+	var document atomDocument
+	require.NoError(t, xml.Unmarshal(data, &document))
+	assert.Equal(t, "HN 日报", document.Title)
+	assert.Equal(t, "https://hn.example.com/atom.xml", document.ID)
+	assert.Equal(t, "2024-01-15T00:00:00Z", document.Updated)
+	require.Len(t, document.Entries, 4)
+
+	entry := document.Entries[0]
+	assert.Equal(t, "2024-01-15T00:00:00Z", entry.Updated)
+	assert.Equal(t, []atomLink{
+		{Href: "https://go.dev/blog/go1.22", Rel: "alternate"},
+		{Href: "https://news.ycombinator.com/item?id=1", Rel: "related", Title: "HN 讨论"},
+	}, entry.Links)
+	assert.Equal(t, []atomCategory{{Term: "Go"}, {Term: "编程语言"}}, entry.Categories)
+	assert.Equal(t, "html", entry.Content.Type)
+	assert.Contains(t, entry.Content.Body, "<em>Go 1.22 Released</em>")
+	assert.Contains(t, entry.Content.Body, "500 points")
+
+	assert.Equal(t, "[2024-01-14 #2] Ask HN: What are you working on?", document.Entries[3].Title)
+}
+
+func TestEntryIDsAreUniquePerDay(t *testing.T) {
+	// 同一故事连续两天出现在总结中
+	summaries := []*hackernews.DailySummaryWithNumbers{newSummary("2024-01-15", 1), newSummary("2024-01-14", 1)}
+	data, err := Atom(summaries, Options{})
+	require.NoError(t, err)
+
+	var document atomDocument
+	require.NoError(t, xml.Unmarshal(data, &document))
+	require.Len(t, document.Entries, 4)
+	assert.Equal(t, "urn:hn-daily:2024-01-15:1", document.Entries[0].ID)
+	assert.Equal(t, "urn:hn-daily:2024-01-14:1", document.Entries[2].ID)
+
+	ids := make(map[string]bool)
+	for _, entry := range document.Entries {
+		ids[entry.ID] = true
+	}
+	assert.Len(t, ids, 4)
+}
+
+func TestAtomWithoutBaseURL(t *testing.T) {
+	data, err := Atom(nil, Options{})
+	require.NoError(t, err)
+
+	var document atomDocument
+	require.NoError(t, xml.Unmarshal(data, &document))
+	assert.Equal(t, "urn:hacker-news-daily", document.ID)
+	assert.Empty(t, document.Links)
+	assert.Empty(t, document.Entries)
+}
+
+func newTestStore(t *testing.T) storage.Store {
+	store := storage.NewMemoryStore()
+	for i, date := range []string{"2024-01-13", "2024-01-14", "2024-01-15"} {
+		require.NoError(t, store.SaveDailySummary(newSummary(date, i*10+1)))
+	}
+	return store
+}
+
+func TestLoadRecent(t *testing.T) {
+	summaries, err := LoadRecent(newTestStore(t), 2)
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "2024-01-15", summaries[0].Date)
+	assert.Equal(t, "2024-01-14", summaries[1].Date)
+}
+
+func TestFilePublisher(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "feed")
+	publisher := NewFilePublisher(newTestStore(t), dir, 1, Options{})
+	assert.Equal(t, "feed", publisher.Name())
+	require.NoError(t, publisher.Publish(context.Background(), nil))
+
+	data, err := os.ReadFile(filepath.Join(dir, "rss.xml"))
+	require.NoError(t, err)
+	var document rssDocument
+	require.NoError(t, xml.Unmarshal(data, &document))
+	require.Len(t, document.Channel.Items, 2)
+	assert.Contains(t, document.Channel.Items[0].Title, "2024-01-15")
+
+	assert.FileExists(t, filepath.Join(dir, "atom.xml"))
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(NewHandler(newTestStore(t), 0, Options{}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/atom.xml")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	var document atomDocument
+	require.NoError(t, xml.NewDecoder(resp.Body).Decode(&document))
+	assert.Len(t, document.Entries, 6)
+
+	resp, err = http.Get(server.URL + "/rss.xml")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "application/rss+xml; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	resp, err = http.Post(server.URL+"/rss.xml", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,96 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/storage"
+)
+
+const defaultDays = 7
+
+// WriteFiles 将订阅写入目录下的 rss.xml 和 atom.xml
+func WriteFiles(dir string, summaries []*hackernews.DailySummaryWithNumbers, options Options) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create feed directory: %w", err)
+	}
+
+	for name, build := range map[string]func([]*hackernews.DailySummaryWithNumbers, Options) ([]byte, error){
+		"rss.xml":  RSS,
+		"atom.xml": Atom,
+	} {
+		data, err := build(summaries, options)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// FilePublisher 每日总结发布后，用存储中最近几天的总结重新生成订阅文件
+type FilePublisher struct {
+	store   storage.Store
+	dir     string
+	days    int
+	options Options
+}
+
+func NewFilePublisher(store storage.Store, dir string, days int, options Options) *FilePublisher {
+	if days <= 0 {
+		days = defaultDays
+	}
+	return &FilePublisher{store: store, dir: dir, days: days, options: options}
+}
+
+// Name 渠道名称
+func (p *FilePublisher) Name() string {
+	return "feed"
+}
+
+// Publish 重新生成订阅文件，summary 已由机器人保存到存储中
+func (p *FilePublisher) Publish(_ context.Context, _ *hackernews.DailySummaryWithNumbers) error {
+	summaries, err := LoadRecent(p.store, p.days)
+	if err != nil {
+		return err
+	}
+	return WriteFiles(p.dir, summaries, p.options)
+}
+
+// NewHandler 返回提供 /rss.xml 和 /atom.xml 的 HTTP 处理器，每次请求从存储中读取最近几天的总结
+func NewHandler(store storage.Store, days int, options Options) http.Handler {
+	if days <= 0 {
+		days = defaultDays
+	}
+
+	serve := func(contentType string, build func([]*hackernews.DailySummaryWithNumbers, Options) ([]byte, error)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			summaries, err := LoadRecent(store, days)
+			if err != nil {
+				log.Printf("Failed to load summaries for feed: %v", err)
+				http.Error(w, "failed to load summaries", http.StatusInternalServerError)
+				return
+			}
+			data, err := build(summaries, options)
+			if err != nil {
+				log.Printf("Failed to build feed: %v", err)
+				http.Error(w, "failed to build feed", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Write(data)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rss.xml", serve("application/rss+xml; charset=utf-8", RSS))
+	mux.HandleFunc("GET /atom.xml", serve("application/atom+xml; charset=utf-8", Atom))
+	return mux
+}
@@ -0,0 +1,83 @@
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"hacker-news-daily/hackernews"
+)
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string       `xml:"title"`
+	Link          string       `xml:"link"`
+	Description   string       `xml:"description"`
+	AtomLink      *rssAtomLink `xml:"atom:link,omitempty"`
+	LastBuildDate string       `xml:"lastBuildDate"`
+	Items         []rssItem    `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Comments    string   `xml:"comments,omitempty"` // HN 讨论链接
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS 生成 RSS 2.0 订阅，每个故事一个条目
+func RSS(summaries []*hackernews.DailySummaryWithNumbers, options Options) ([]byte, error) {
+	items := entries(summaries, options.location())
+
+	document := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         options.title(),
+			Link:          options.link(),
+			Description:   "Hacker News 热门故事的每日中文总结",
+			LastBuildDate: latest(items).Format(time.RFC1123Z),
+		},
+	}
+	if self := options.selfURL("rss.xml"); self != "" {
+		document.Channel.AtomLink = &rssAtomLink{Href: self, Rel: "self", Type: "application/rss+xml"}
+	}
+
+	for _, item := range items {
+		document.Channel.Items = append(document.Channel.Items, rssItem{
+			Title:       item.title(),
+			Link:        item.item.URL,
+			Comments:    item.item.HackerNewsURL,
+			GUID:        rssGUID{Value: item.id()},
+			PubDate:     item.updated.Format(time.RFC1123Z),
+			Description: item.html(),
+			Categories:  item.item.Tags,
+		})
+	}
+
+	data, err := marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rss feed: %w", err)
+	}
+	return data, nil
+}
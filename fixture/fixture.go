@@ -0,0 +1,197 @@
+// Package fixture 录制和回放 HTTP 请求：录制模式下把真实响应保存为 JSON 文件，
+// 回放模式下只从文件返回响应，不访问网络，用于离线测试和复现问题
+package fixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode 录制回放模式
+type Mode string
+
+const (
+	Off    Mode = ""       // 直接发送请求
+	Record Mode = "record" // 发送请求并保存响应
+	Replay Mode = "replay" // 只使用已保存的响应
+)
+
+// ParseMode 解析配置中的模式名称
+func ParseMode(name string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case Off, Record, Replay:
+		return mode, nil
+	default:
+		return Off, fmt.Errorf("unknown fixture mode: %s", name)
+	}
+}
+
+// entry 一次录制的请求和响应
+type entry struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+	Base64      bool        `json:"base64,omitempty"` // Body 不是合法 UTF-8 时以 base64 保存
+}
+
+// Transport 按模式录制或回放请求的 http.RoundTripper
+type Transport struct {
+	mode Mode
+	dir  string
+	next http.RoundTripper
+}
+
+// NewTransport 创建在 dir 中录制或回放请求的 Transport，next 为空时使用 http.DefaultTransport
+func NewTransport(mode Mode, dir string, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{mode: mode, dir: dir, next: next}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == Off {
+		return t.next.RoundTrip(req)
+	}
+
+	var requestBody []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		requestBody = data
+		req.Body = io.NopCloser(bytes.NewReader(data))
+	}
+	path := filepath.Join(t.dir, fileName(req, requestBody))
+
+	if t.mode == Replay {
+		return t.replay(req, path)
+	}
+	return t.record(req, path, requestBody)
+}
+
+// replay 从文件读取响应
+func (t *Transport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no fixture recorded for %s %s", req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+
+	body := []byte(e.Body)
+	if e.Base64 {
+		if body, err = base64.StdEncoding.DecodeString(e.Body); err != nil {
+			return nil, fmt.Errorf("invalid fixture body %s: %w", path, err)
+		}
+	}
+
+	return newResponse(req, e.Status, e.Header, body), nil
+}
+
+// record 发送请求并把响应保存到文件
+func (t *Transport) record(req *http.Request, path string, requestBody []byte) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	e := entry{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(requestBody),
+		Status:      resp.StatusCode,
+		Header:      resp.Header,
+		Body:        string(body),
+	}
+	if !utf8.Valid(body) {
+		e.Body = base64.StdEncoding.EncodeToString(body)
+		e.Base64 = true
+	}
+	if err := writeEntry(path, e); err != nil {
+		return nil, err
+	}
+
+	return newResponse(req, resp.StatusCode, resp.Header, body), nil
+}
+
+// writeEntry 先写临时文件再重命名，并发录制同一请求时不会留下不完整的文件
+func writeEntry(path string, e entry) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fixture-*")
+	if err != nil {
+		return fmt.Errorf("failed to create fixture: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// fileName 由请求方法、地址和请求体生成文件名，前缀为主机名便于查找
+func fileName(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", req.Method, req.URL)
+	hash.Write(body)
+
+	host := strings.NewReplacer(":", "_", "/", "_").Replace(req.URL.Host)
+	return fmt.Sprintf("%s-%s.json", host, hex.EncodeToString(hash.Sum(nil))[:16])
+}
+
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,94 @@
+package fixture
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Echo", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(r.URL.Path + ":" + string(body)))
+	}))
+	dir := t.TempDir()
+
+	recorder := &http.Client{Transport: NewTransport(Record, dir, nil)}
+	resp, err := recorder.Post(server.URL+"/echo", "text/plain", strings.NewReader("hello"))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "/echo:hello", string(body))
+	assert.EqualValues(t, 1, calls.Load())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// 回放时不访问服务器
+	server.Close()
+	player := &http.Client{Transport: NewTransport(Replay, dir, nil)}
+	resp, err = player.Post(server.URL+"/echo", "text/plain", strings.NewReader("hello"))
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "yes", resp.Header.Get("X-Echo"))
+	assert.Equal(t, "/echo:hello", string(body))
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	dir := t.TempDir()
+	player := &http.Client{Transport: NewTransport(Replay, dir, nil)}
+
+	_, err := player.Post("http://127.0.0.1:1/echo", "text/plain", strings.NewReader("other"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no fixture recorded")
+}
+
+func TestRequestBodyDistinguishesFixtures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	recorder := &http.Client{Transport: NewTransport(Record, dir, nil)}
+	for _, body := range []string{"a", "b", "\xff\xfe"} {
+		resp, err := recorder.Post(server.URL, "text/plain", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	player := &http.Client{Transport: NewTransport(Replay, dir, nil)}
+	for _, body := range []string{"a", "b", "\xff\xfe"} {
+		resp, err := player.Post(server.URL, "text/plain", strings.NewReader(body))
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, body, string(data))
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for name, want := range map[string]Mode{"": Off, "record": Record, " Replay ": Replay} {
+		mode, err := ParseMode(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, mode)
+	}
+
+	_, err := ParseMode("rewind")
+	assert.Error(t, err)
+}
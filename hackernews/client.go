@@ -1,25 +1,57 @@
 package hackernews
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
+
+	"hacker-news-daily/article"
+	"hacker-news-daily/cache"
+	"hacker-news-daily/retry"
+)
+
+// Hacker News API 的默认地址
+const (
+	DefaultAlgoliaBaseURL  = "https://hn.algolia.com/api/v1"
+	DefaultFirebaseBaseURL = "https://hacker-news.firebaseio.com/v0"
+)
+
+// defaultCommentDepth 默认获取的评论层数：顶级评论及其直接回复
+const defaultCommentDepth = 2
+
+// 写入故事内容时每层保留的评论数量
+const (
+	maxContentComments = 10
+	maxContentReplies  = 3
 )
 
 type CommentConfig struct {
 	MaxTopLevelComments int
 	MaxChildComments    int
+	MaxDepth            int // 获取的评论层数，1 表示只获取顶级评论
 }
 
 type Client struct {
-	httpClient    *resty.Client
-	timeout       time.Duration
-	commentConfig CommentConfig
+	httpClient     *resty.Client
+	timeout        time.Duration
+	commentConfig  CommentConfig
+	articleFetcher *article.Fetcher // 为空时不抓取链接原文
+	cache          *cache.Cache     // 为空时每次都请求 Firebase
+	limiter        *limiter         // 所有 API 请求共享的并发和速率限制
+	algolia        *retry.Retrier   // 搜索 API 的重试和熔断
+	firebase       *retry.Retrier   // 条目 API 的重试和熔断
+	algoliaURL     string           // 搜索 API 地址
+	firebaseURL    string           // 条目 API 地址
+	ranking        Ranking          // 搜索 API 结果的排序方式
+	location       *time.Location   // 按日期获取故事时自然日所在的时区
 }
 
 func NewClient(timeout int, maxTopLevelComments int, maxChildComments int) *Client {
@@ -33,84 +65,108 @@ func NewClient(timeout int, maxTopLevelComments int, maxChildComments int) *Clie
 		commentConfig: CommentConfig{
 			MaxTopLevelComments: maxTopLevelComments,
 			MaxChildComments:    maxChildComments,
+			MaxDepth:            defaultCommentDepth,
 		},
+		limiter:     newLimiter(0, 0),
+		algolia:     retry.New("algolia", retry.Config{}),
+		firebase:    retry.New("firebase", retry.Config{}),
+		algoliaURL:  DefaultAlgoliaBaseURL,
+		firebaseURL: DefaultFirebaseBaseURL,
+		location:    time.UTC,
 	}
 }
 
-// GetTopStoriesByDate 获取指定日期的热门故事
-func (c *Client) GetTopStoriesByDate(date string, maxStories int) ([]Story, error) {
-	// 如果传入空字符串，则获取过去24小时的内容
-	if date == "" {
-		date = time.Now().Format("2006-01-02")
+// SetBaseURLs 设置搜索 API 和条目 API 的地址，为空时使用默认地址
+func (c *Client) SetBaseURLs(algoliaURL, firebaseURL string) {
+	if algoliaURL == "" {
+		algoliaURL = DefaultAlgoliaBaseURL
+	}
+	if firebaseURL == "" {
+		firebaseURL = DefaultFirebaseBaseURL
 	}
+	c.algoliaURL = strings.TrimSuffix(algoliaURL, "/")
+	c.firebaseURL = strings.TrimSuffix(firebaseURL, "/")
+}
+
+// SetTransport 设置 API 请求使用的 HTTP Transport，用于录制和回放请求
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.httpClient.SetTransport(transport)
+}
 
-	endTime := getTimeForDate(date)
-	startTime := endTime.Add(-24 * time.Hour)
-	if startTime.IsZero() || endTime.IsZero() {
-		return nil, fmt.Errorf("invalid date format: %s", date)
+// SetCommentDepth 设置获取的评论层数，不大于 0 时使用默认值
+func (c *Client) SetCommentDepth(depth int) {
+	if depth <= 0 {
+		depth = defaultCommentDepth
 	}
-	return c.getTopStoriesByTime(startTime, endTime, maxStories)
+	c.commentConfig.MaxDepth = depth
 }
 
-func (c *Client) getTopStoriesByTime(startTime, endTime time.Time, maxStories int) ([]Story, error) {
-	// 使用 HN 的搜索 API 获取指定时间段的热门故事
-	url := "https://hn.algolia.com/api/v1/search_by_date"
+// SetRateLimit 设置 API 请求的最大并发数和每秒请求数，不大于 0 时使用默认值
+func (c *Client) SetRateLimit(maxConcurrent int, requestsPerSecond float64) {
+	c.limiter = newLimiter(maxConcurrent, requestsPerSecond)
+}
 
-	var response TopStoriesResponse
+// SetRetryConfig 设置 API 请求失败时的重试和熔断策略，搜索 API 和条目 API 分别熔断
+func (c *Client) SetRetryConfig(config retry.Config) {
+	c.algolia = retry.New("algolia", config)
+	c.firebase = retry.New("firebase", config)
+}
 
-	resp, err := c.httpClient.R().
-		SetResult(&response).
-		SetQueryParams(map[string]string{
-			"tags":           "front_page",
-			"numericFilters": fmt.Sprintf("created_at_i>%d,created_at_i<%d", startTime.Unix(), endTime.Unix()),
-			"hitsPerPage":    fmt.Sprintf("%d", maxStories),
-		}).
-		Get(url)
+// SetRanking 设置从搜索结果中挑选热门故事的排序方式
+func (c *Client) SetRanking(ranking Ranking) {
+	c.ranking = ranking
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch top stories: %w", err)
+// SetLocation 设置按日期获取故事时自然日所在的时区，为空时使用 UTC
+func (c *Client) SetLocation(location *time.Location) {
+	if location == nil {
+		location = time.UTC
 	}
+	c.location = location
+}
 
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("API returned status code: %d", resp.StatusCode())
-	}
+// SetArticleFetcher 设置链接原文抓取器
+func (c *Client) SetArticleFetcher(fetcher *article.Fetcher) {
+	c.articleFetcher = fetcher
+}
 
-	stories := make([]Story, 0, len(response.Hits))
-	for _, hit := range response.Hits {
-		story := Story{
-			ID:            parseInt(hit.ObjectID),
-			Title:         hit.Title,
-			URL:           hit.URL,
-			Score:         hit.Points,
-			By:            hit.Author,
-			Time:          hit.CreatedAtI,
-			Text:          hit.StoryText,
-			HackerNewsURL: fmt.Sprintf("https://news.ycombinator.com/item?id=%s", hit.ObjectID),
+// SetCache 设置 Firebase 条目缓存，故事和评论在有效期内不会重复请求
+func (c *Client) SetCache(itemCache *cache.Cache) {
+	c.cache = itemCache
+}
+
+// GetTopStoriesByDate 获取指定日期的首页热门故事
+func (c *Client) GetTopStoriesByDate(ctx context.Context, date string, maxStories int) ([]Story, error) {
+	source, _ := c.Source(SourceFrontPage)
+	return c.GetStoriesByDate(ctx, source, date, 1, maxStories)
+}
+
+// GetStoriesByDate 从指定来源获取截止到 date 当天结束的 days 个自然日内的故事，
+// date 为空时获取截止到当前时间的 days 个 24 小时内的故事，days 不大于 0 时为 1 天
+func (c *Client) GetStoriesByDate(ctx context.Context, source StorySource, date string, days, maxStories int) ([]Story, error) {
+	window := c.RecentWindow(time.Now(), days)
+	if date != "" {
+		var err error
+		if window, err = c.DayWindow(date, days); err != nil {
+			return nil, err
 		}
-		stories = append(stories, story)
 	}
+	return c.GetStoriesInWindow(ctx, source, window, maxStories)
+}
 
-	return stories, nil
+// GetStoriesInWindow 从指定来源获取发布时间在时间段内的故事
+func (c *Client) GetStoriesInWindow(ctx context.Context, source StorySource, window Window, maxStories int) ([]Story, error) {
+	return source.Stories(ctx, window.Start, window.End, maxStories)
 }
 
 // GetStoryWithComments 获取故事详情和评论
-func (c *Client) GetStoryWithComments(storyID int) (*Story, []Comment, error) {
+func (c *Client) GetStoryWithComments(ctx context.Context, storyID int) (*Story, []Comment, error) {
 	// 获取故事详情
-	storyURL := fmt.Sprintf("https://hacker-news.firebaseio.com/v0/item/%d.json", storyID)
-
-	var story Story
-	resp, err := c.httpClient.R().
-		SetResult(&story).
-		Get(storyURL)
-
+	story, err := getItem[Story](ctx, c, storyID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to fetch story: %w", err)
 	}
 
-	if resp.StatusCode() != 200 {
-		return nil, nil, fmt.Errorf("story API returned status code: %d", resp.StatusCode())
-	}
-
 	// 获取评论
 	comments := make([]Comment, 0)
 	if len(story.Kids) > 0 {
@@ -121,34 +177,24 @@ func (c *Client) GetStoryWithComments(storyID int) (*Story, []Comment, error) {
 		}
 
 		// 使用并发获取顶级评论
-		comments = c.getCommentsParallel(story.Kids, 2)
+		comments = c.getCommentsParallel(ctx, story.Kids, c.commentConfig.MaxDepth)
 	}
 
 	return &story, comments, nil
 }
 
 // getComment 递归获取评论和子评论
-func (c *Client) getComment(commentID int, maxDepth int) (*Comment, error) {
+func (c *Client) getComment(ctx context.Context, commentID int, maxDepth int) (*Comment, error) {
 	if maxDepth <= 0 {
 		return nil, nil
 	}
 
-	commentURL := fmt.Sprintf("https://hacker-news.firebaseio.com/v0/item/%d.json", commentID)
-
-	var comment Comment
-	resp, err := c.httpClient.R().
-		SetResult(&comment).
-		Get(commentURL)
-
+	comment, err := getItem[Comment](ctx, c, commentID)
 	if err != nil {
 		log.Printf("Failed to fetch comment %d: %v", commentID, err)
 		return nil, err
 	}
 
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("comment API returned status code: %d", resp.StatusCode())
-	}
-
 	// 如果评论被删除或为空，跳过
 	if comment.Text == "" || comment.Type != "comment" {
 		return nil, nil
@@ -162,50 +208,130 @@ func (c *Client) getComment(commentID int, maxDepth int) (*Comment, error) {
 		}
 
 		// 使用并发获取子评论
-		comment.Children = c.getCommentsParallel(comment.Kids, maxDepth-1)
+		comment.Children = c.getCommentsParallel(ctx, comment.Kids, maxDepth-1)
 	}
 
 	return &comment, nil
 }
 
-// getCommentsParallel 并发获取多个评论
-func (c *Client) getCommentsParallel(commentIDs []int, maxDepth int) []Comment {
+// getItem 从 Firebase 获取故事或评论并解码为 T
+func getItem[T any](ctx context.Context, c *Client, itemID int) (T, error) {
+	var item T
+	data, err := c.getItemData(ctx, itemID)
+	if err != nil {
+		return item, err
+	}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return item, fmt.Errorf("failed to decode item %d: %w", itemID, err)
+	}
+	return item, nil
+}
+
+// getItemData 获取条目的原始 JSON，设置了缓存时优先使用缓存，并发请求同一条目时只请求一次。
+// 缓存原始数据而不是解码后的值，不同类型的调用方读取同一条目时都能得到完整字段
+func (c *Client) getItemData(ctx context.Context, itemID int) ([]byte, error) {
+	load := func() ([]byte, error) {
+		var data []byte
+		err := c.firebase.Do(ctx, func() error {
+			if err := c.limiter.acquire(ctx); err != nil {
+				return err
+			}
+			defer c.limiter.release()
+
+			resp, err := c.httpClient.R().
+				SetContext(ctx).
+				Get(fmt.Sprintf("%s/item/%d.json", c.firebaseURL, itemID))
+
+			if err != nil {
+				return retry.Network(err, true)
+			}
+
+			if resp.StatusCode() != 200 {
+				return retry.Status(fmt.Errorf("item API returned status code: %d", resp.StatusCode()), resp.StatusCode(), resp.Header(), true)
+			}
+
+			data = resp.Body()
+			return nil
+		})
+		return data, err
+	}
+
+	if c.cache == nil {
+		return load()
+	}
+	return c.cache.Do(itemCacheKey(itemID), load)
+}
+
+// itemCacheKey 条目原始 JSON 的缓存键
+func itemCacheKey(itemID int) string {
+	return fmt.Sprintf("hn:item-json:%d", itemID)
+}
+
+// getCommentsParallel 并发获取多个评论，结果保持 commentIDs 中 HN 的排名顺序
+func (c *Client) getCommentsParallel(ctx context.Context, commentIDs []int, maxDepth int) []Comment {
 	if len(commentIDs) == 0 {
 		return nil
 	}
 
-	// 使用 channel 收集结果
-	commentChan := make(chan Comment, len(commentIDs))
+	// 按位置保存结果，避免按完成顺序打乱排名
+	results := make([]*Comment, len(commentIDs))
 	var wg sync.WaitGroup
 
 	// 启动 goroutine 并发获取评论
-	for _, commentID := range commentIDs {
+	for i, commentID := range commentIDs {
 		wg.Add(1)
-		go func(id int) {
+		go func() {
 			defer wg.Done()
-			if comment, err := c.getComment(id, maxDepth); err == nil && comment != nil {
-				commentChan <- *comment
+			if comment, err := c.getComment(ctx, commentID, maxDepth); err == nil {
+				results[i] = comment
 			}
-		}(commentID)
+		}()
 	}
+	wg.Wait()
 
-	// 等待所有 goroutine 完成
-	go func() {
-		wg.Wait()
-		close(commentChan)
-	}()
-
-	// 收集结果
+	// 跳过获取失败和已删除的评论
 	var comments []Comment
-	for comment := range commentChan {
-		comments = append(comments, comment)
+	for _, comment := range results {
+		if comment != nil {
+			comments = append(comments, *comment)
+		}
 	}
 
 	return comments
 }
 
-// GetStoryContent 获取故事完整内容（包括正文和评论）
-func (c *Client) GetStoryContent(story Story) (string, error) {
+// GetStoryContents 在并发限制内同时获取多个故事的完整内容，结果与输入顺序一致，
+// 获取失败或因 ctx 取消未获取的故事对应的错误非空
+func (c *Client) GetStoryContents(ctx context.Context, stories []Story) ([]string, []error) {
+	contents := make([]string, len(stories))
+	errs := make([]error, len(stories))
+
+	// 故事级别单独限制并发，评论请求仍受共享限制器约束
+	workers := make(chan struct{}, c.limiter.concurrency())
+	var wg sync.WaitGroup
+	for i, story := range stories {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case workers <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-workers }()
+
+			log.Printf("Processing story %d/%d: %s", i+1, len(stories), story.Title)
+			contents[i], errs[i] = c.GetStoryContent(ctx, story)
+		}()
+	}
+	wg.Wait()
+
+	return contents, errs
+}
+
+// GetStoryContent 获取故事完整内容（包括正文和评论），ctx 取消时返回错误而不是不完整的内容
+func (c *Client) GetStoryContent(ctx context.Context, story Story) (string, error) {
 	var content strings.Builder
 
 	// 添加标题和基本信息
@@ -221,30 +347,28 @@ func (c *Client) GetStoryContent(story Story) (string, error) {
 		content.WriteString("\n\n")
 	}
 
+	// 抓取链接原文
+	if c.articleFetcher != nil && story.URL != "" {
+		if art, err := c.articleFetcher.Fetch(ctx, story.URL); err != nil {
+			log.Printf("Failed to fetch article for story %d: %v", story.ID, err)
+		} else if text := art.Format(); text != "" {
+			content.WriteString("原文内容:\n")
+			content.WriteString(text)
+			content.WriteString("\n")
+		}
+	}
+
 	// 获取评论
-	_, comments, err := c.GetStoryWithComments(story.ID)
+	_, comments, err := c.GetStoryWithComments(ctx, story.ID)
 	if err != nil {
 		log.Printf("Failed to get comments for story %d: %v", story.ID, err)
 	} else if len(comments) > 0 {
 		content.WriteString("热门评论:\n")
-		for i, comment := range comments {
-			if i >= 10 { // 限制评论数量
-				break
-			}
-			content.WriteString(fmt.Sprintf("\n评论 %d (作者: %s):\n", i+1, comment.By))
-			content.WriteString(cleanHTMLText(comment.Text))
-
-			// 添加子评论
-			if len(comment.Children) > 0 {
-				for j, child := range comment.Children {
-					if j >= 3 { // 限制子评论数量
-						break
-					}
-					content.WriteString(fmt.Sprintf("\n  └─ 回复 (作者: %s): %s", child.By, cleanHTMLText(child.Text)))
-				}
-			}
-			content.WriteString("\n")
-		}
+		writeComments(&content, comments)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
 	}
 
 	return content.String(), nil
@@ -252,12 +376,28 @@ func (c *Client) GetStoryContent(story Story) (string, error) {
 
 // 辅助函数
 
-func getTimeForDate(date string) time.Time {
-	t, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		return time.Now()
+// writeComments 按排名顺序写入评论及其各层回复
+func writeComments(content *strings.Builder, comments []Comment) {
+	for i, comment := range comments {
+		if i >= maxContentComments { // 限制评论数量
+			break
+		}
+		content.WriteString(fmt.Sprintf("\n评论 %d (作者: %s):\n", i+1, comment.By))
+		content.WriteString(cleanHTMLText(comment.Text))
+		writeReplies(content, comment.Children, 1)
+		content.WriteString("\n")
+	}
+}
+
+// writeReplies 递归写入回复，层级越深缩进越多
+func writeReplies(content *strings.Builder, replies []Comment, level int) {
+	for i, reply := range replies {
+		if i >= maxContentReplies { // 限制子评论数量
+			break
+		}
+		content.WriteString(fmt.Sprintf("\n%s└─ 回复 (作者: %s): %s", strings.Repeat("  ", level), reply.By, cleanHTMLText(reply.Text)))
+		writeReplies(content, reply.Children, level+1)
 	}
-	return t
 }
 
 func parseInt(s string) int {
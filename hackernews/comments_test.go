@@ -0,0 +1,102 @@
+package hackernews
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/cache"
+)
+
+// newCachedClient 创建评论已全部放入缓存的客户端，不会发出网络请求
+func newCachedClient(t *testing.T, comments ...Comment) *Client {
+	itemCache, err := cache.New(time.Hour, "")
+	require.NoError(t, err)
+	for _, comment := range comments {
+		data, err := json.Marshal(comment)
+		require.NoError(t, err)
+		itemCache.Set(itemCacheKey(comment.ID), data)
+	}
+
+	client := NewClient(30, 20, 20)
+	client.SetCache(itemCache)
+	return client
+}
+
+func comment(id int, kids ...int) Comment {
+	return Comment{ID: id, By: fmt.Sprintf("user%d", id), Text: fmt.Sprintf("comment %d", id), Type: "comment", Kids: kids}
+}
+
+func commentIDs(comments []Comment) []int {
+	ids := make([]int, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestGetCommentsParallelKeepsRankOrder(t *testing.T) {
+	var all []Comment
+	var topIDs []int
+	for i := 1; i <= 20; i++ {
+		top := i * 100
+		topIDs = append(topIDs, top)
+		all = append(all, comment(top, top+1, top+2, top+3), comment(top+1, top+11, top+12), comment(top+2), comment(top+3), comment(top+11), comment(top+12))
+	}
+	// 已删除的评论被跳过，不影响其他评论的顺序
+	all = append(all, Comment{ID: 2100, Type: "comment"})
+	topIDs = append(topIDs[:5], append([]int{2100}, topIDs[5:]...)...)
+
+	client := newCachedClient(t, all...)
+	for range 5 {
+		comments := client.getCommentsParallel(context.Background(), topIDs, 3)
+		require.Len(t, comments, 20)
+		for i, c := range comments {
+			top := (i + 1) * 100
+			assert.Equal(t, top, c.ID)
+			require.Len(t, c.Children, 3)
+			assert.Equal(t, []int{top + 1, top + 2, top + 3}, commentIDs(c.Children))
+			assert.Equal(t, []int{top + 11, top + 12}, commentIDs(c.Children[0].Children))
+		}
+	}
+}
+
+func TestGetCommentsParallelDepth(t *testing.T) {
+	client := newCachedClient(t, comment(1, 2), comment(2, 3), comment(3, 4), comment(4))
+
+	comments := client.getCommentsParallel(context.Background(), []int{1}, 1)
+	require.Len(t, comments, 1)
+	assert.Empty(t, comments[0].Children)
+
+	comments = client.getCommentsParallel(context.Background(), []int{1}, 4)
+	require.Len(t, comments, 1)
+	assert.Equal(t, 4, comments[0].Children[0].Children[0].Children[0].ID)
+}
+
+func TestSetCommentDepth(t *testing.T) {
+	client := NewClient(30, 5, 5)
+	assert.Equal(t, defaultCommentDepth, client.commentConfig.MaxDepth)
+	client.SetCommentDepth(4)
+	assert.Equal(t, 4, client.commentConfig.MaxDepth)
+	client.SetCommentDepth(0)
+	assert.Equal(t, defaultCommentDepth, client.commentConfig.MaxDepth)
+}
+
+func TestWriteComments(t *testing.T) {
+	reply := comment(2, 3)
+	reply.Children = []Comment{comment(3)}
+	top := comment(1, 2)
+	top.Children = []Comment{reply}
+
+	var content strings.Builder
+	writeComments(&content, []Comment{top})
+	assert.Equal(t, "\n评论 1 (作者: user1):\ncomment 1"+
+		"\n  └─ 回复 (作者: user2): comment 2"+
+		"\n    └─ 回复 (作者: user3): comment 3\n", content.String())
+}
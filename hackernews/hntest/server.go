@@ -0,0 +1,257 @@
+// Package hntest 提供模拟 Hacker News Algolia 搜索 API 和 Firebase 条目 API 的测试服务器
+package hntest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Item Firebase 条目 API 返回的故事或评论
+type Item struct {
+	ID          int    `json:"id"`
+	Type        string `json:"type"`
+	By          string `json:"by,omitempty"`
+	Time        int64  `json:"time,omitempty"`
+	Title       string `json:"title,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Score       int    `json:"score,omitempty"`
+	Kids        []int  `json:"kids,omitempty"`
+	Parent      int    `json:"parent,omitempty"`
+	Descendants int    `json:"descendants,omitempty"`
+}
+
+// hit Algolia 搜索结果中的单个故事
+type hit struct {
+	ObjectID    string `json:"objectID"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Points      int    `json:"points"`
+	Author      string `json:"author"`
+	CreatedAtI  int64  `json:"created_at_i"`
+	StoryText   string `json:"story_text"`
+	NumComments int    `json:"num_comments"`
+}
+
+// Server 模拟 Hacker News API 的测试服务器，测试结束时自动关闭
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	items     map[int]Item
+	frontPage []int            // 出现在首页的故事 ID
+	lists     map[string][]int // topstories 等排名列表
+	requests  []string         // 收到的请求路径，包括查询参数
+}
+
+// NewServer 创建并启动测试服务器
+func NewServer(t testing.TB) *Server {
+	s := &Server{items: make(map[int]Item), lists: make(map[string][]int)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/search_by_date", s.handleSearch)
+	mux.HandleFunc("GET /v0/item/{file}", s.handleItem)
+	mux.HandleFunc("GET /v0/{file}", s.handleList)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.RequestURI())
+		s.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// AlgoliaURL 搜索 API 地址
+func (s *Server) AlgoliaURL() string {
+	return s.URL + "/api/v1"
+}
+
+// FirebaseURL 条目 API 地址
+func (s *Server) FirebaseURL() string {
+	return s.URL + "/v0"
+}
+
+// AddStory 添加出现在首页的故事，Type 为空时设为 story
+func (s *Server) AddStory(item Item) {
+	if item.Type == "" {
+		item.Type = "story"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[item.ID] = item
+	s.frontPage = append(s.frontPage, item.ID)
+}
+
+// AddItem 添加评论等只能通过条目 API 获取的条目，Type 为空时设为 comment
+func (s *Server) AddItem(items ...Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, item := range items {
+		if item.Type == "" {
+			item.Type = "comment"
+		}
+		s.items[item.ID] = item
+	}
+}
+
+// SetList 设置 topstories、beststories 或 newstories 排名列表中的故事 ID
+func (s *Server) SetList(name string, ids ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lists[name] = ids
+}
+
+// Requests 返回已收到的请求路径
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// handleSearch 分页返回满足所有标签、包含搜索关键词且在 created_at_i 时间范围内的故事，按发布时间倒序
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	after, before, err := parseTimeRange(query.Get("numericFilters"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := strconv.Atoi(query.Get("hitsPerPage"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	keyword := strings.ToLower(query.Get("query"))
+
+	s.mu.Lock()
+	var hits []hit
+	for _, item := range s.items {
+		if item.Type != "story" || item.Time <= after || item.Time >= before {
+			continue
+		}
+		ok, err := s.matchTags(item, query.Get("tags"))
+		if err != nil {
+			s.mu.Unlock()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !ok || !strings.Contains(strings.ToLower(item.Title), keyword) {
+			continue
+		}
+		hits = append(hits, hit{
+			ObjectID:    strconv.Itoa(item.ID),
+			Title:       item.Title,
+			URL:         item.URL,
+			Points:      item.Score,
+			Author:      item.By,
+			CreatedAtI:  item.Time,
+			StoryText:   item.Text,
+			NumComments: item.Descendants,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].CreatedAtI != hits[j].CreatedAtI {
+			return hits[i].CreatedAtI > hits[j].CreatedAtI
+		}
+		return hits[i].ObjectID > hits[j].ObjectID
+	})
+	page, _ := strconv.Atoi(query.Get("page"))
+	pages := (len(hits) + limit - 1) / limit
+	hits = hits[min(page*limit, len(hits)):min((page+1)*limit, len(hits))]
+	writeJSON(w, map[string]any{"hits": hits, "page": page, "nbPages": pages})
+}
+
+// matchTags 检查故事是否满足逗号分隔的所有标签，调用方需持有锁
+func (s *Server) matchTags(item Item, tags string) (bool, error) {
+	for _, tag := range strings.Split(tags, ",") {
+		var ok bool
+		switch {
+		case tag == "" || tag == "story":
+			ok = true
+		case tag == "front_page":
+			ok = slices.Contains(s.frontPage, item.ID)
+		case tag == "ask_hn":
+			ok = strings.HasPrefix(item.Title, "Ask HN:")
+		case tag == "show_hn":
+			ok = strings.HasPrefix(item.Title, "Show HN:")
+		case strings.HasPrefix(tag, "author_"):
+			ok = item.By == strings.TrimPrefix(tag, "author_")
+		default:
+			return false, fmt.Errorf("unsupported tag: %s", tag)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// handleList 返回排名列表，列表不存在时返回 null
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	ids, ok := s.lists[strings.TrimSuffix(r.PathValue("file"), ".json")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, nil)
+		return
+	}
+	writeJSON(w, ids)
+}
+
+// handleItem 返回单个条目，条目不存在时与 Firebase 一样返回 null
+func (s *Server) handleItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("file"), ".json"))
+	if err != nil {
+		http.Error(w, "invalid item id", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	item, ok := s.items[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, nil)
+		return
+	}
+	writeJSON(w, item)
+}
+
+// parseTimeRange 解析 "created_at_i>=开始,created_at_i<结束" 格式的过滤条件，返回不包含两端的范围
+func parseTimeRange(filters string) (after, before int64, err error) {
+	before = 1<<63 - 1
+	for _, filter := range strings.Split(filters, ",") {
+		switch {
+		case strings.HasPrefix(filter, "created_at_i>="):
+			after, err = strconv.ParseInt(strings.TrimPrefix(filter, "created_at_i>="), 10, 64)
+			after--
+		case strings.HasPrefix(filter, "created_at_i>"):
+			after, err = strconv.ParseInt(strings.TrimPrefix(filter, "created_at_i>"), 10, 64)
+		case strings.HasPrefix(filter, "created_at_i<"):
+			before, err = strconv.ParseInt(strings.TrimPrefix(filter, "created_at_i<"), 10, 64)
+		case filter == "":
+		default:
+			err = fmt.Errorf("unsupported filter: %s", filter)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return after, before, nil
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
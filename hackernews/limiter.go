@@ -0,0 +1,57 @@
+package hackernews
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultMaxConcurrent     = 8  // 默认同时进行的 API 请求数
+	defaultRequestsPerSecond = 20 // 默认每秒 API 请求数
+)
+
+// limiter 限制对 Hacker News API 的并发请求数和请求速率，由客户端的所有请求共享
+type limiter struct {
+	slots chan struct{}
+	rate  *rate.Limiter
+}
+
+// newLimiter 创建限制器，参数不大于 0 时使用默认值
+func newLimiter(maxConcurrent int, requestsPerSecond float64) *limiter {
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrent
+	}
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = defaultRequestsPerSecond
+	}
+	return &limiter{
+		slots: make(chan struct{}, maxConcurrent),
+		rate:  rate.NewLimiter(rate.Limit(requestsPerSecond), maxConcurrent),
+	}
+}
+
+// acquire 等待并发名额和速率令牌，ctx 取消时放弃等待并返回错误
+func (l *limiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err := l.rate.Wait(ctx); err != nil {
+		<-l.slots
+		return err
+	}
+	return nil
+}
+
+// release 归还并发名额
+func (l *limiter) release() {
+	<-l.slots
+}
+
+// concurrency 返回允许的最大并发数
+func (l *limiter) concurrency() int {
+	return cap(l.slots)
+}
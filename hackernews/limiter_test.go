@@ -0,0 +1,73 @@
+package hackernews
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterBoundsConcurrency(t *testing.T) {
+	l := newLimiter(3, 1000)
+	assert.Equal(t, 3, l.concurrency())
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.acquire(context.Background())
+			defer l.release()
+
+			current := running.Add(1)
+			for {
+				old := peak.Load()
+				if current <= old || peak.CompareAndSwap(old, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	assert.Greater(t, peak.Load(), int32(1))
+}
+
+func TestLimiterRate(t *testing.T) {
+	// 突发额度等于并发数，之后按每秒 50 个请求发放
+	l := newLimiter(2, 50)
+
+	start := time.Now()
+	for range 7 {
+		l.acquire(context.Background())
+		l.release()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestLimiterAcquireCancelled(t *testing.T) {
+	l := newLimiter(1, 1000)
+	require.NoError(t, l.acquire(context.Background()))
+
+	// 名额已满时 ctx 取消立即返回
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.acquire(ctx), context.DeadlineExceeded)
+
+	l.release()
+	assert.NoError(t, l.acquire(context.Background()))
+}
+
+func TestNewLimiterDefaults(t *testing.T) {
+	l := newLimiter(0, 0)
+	assert.Equal(t, defaultMaxConcurrent, l.concurrency())
+	assert.Equal(t, float64(defaultRequestsPerSecond), float64(l.rate.Limit()))
+}
@@ -8,7 +8,8 @@ type Story struct {
 	By            string `json:"by"`
 	Time          int64  `json:"time"`
 	Text          string `json:"text"`
-	Kids          []int  `json:"kids"` // 评论ID列表
+	Kids          []int  `json:"kids"`        // 评论ID列表
+	NumComments   int    `json:"descendants"` // 评论总数，与 Firebase 条目的字段名一致
 	HackerNewsURL string `json:"hacker_news_url"`
 }
 
@@ -30,16 +31,56 @@ type DailyStories struct {
 
 type DailySummaryWithNumbers struct {
 	Date           string            `json:"date"`
+	Digest         string            `json:"digest,omitempty"` // 摘要名称，为空时为默认的每日总结
+	Title          string            `json:"title,omitempty"`  // 摘要标题，为空时使用 DefaultTitle
+	Period         string            `json:"period,omitempty"` // 周报、月报的汇总周期，其他摘要为空
 	Stories        []Story           `json:"stories"`
 	StorySummaries []StoryWithNumber `json:"story_summaries"`
+	Overview       string            `json:"overview,omitempty"` // 周报、月报的趋势概述，每日总结为空
+	Revision       string            `json:"revision,omitempty"` // 生成批次，同一日期重新生成时不同
+}
+
+// DefaultTitle 默认每日总结的标题
+const DefaultTitle = "Hacker News 每日热点"
+
+// DigestKey 存储和查找总结使用的键：默认每日总结为日期，其他摘要为 "名称/日期"
+func DigestKey(digest, date string) string {
+	if digest == "" {
+		return date
+	}
+	return digest + "/" + date
+}
+
+// Key 总结的存储键，同一日期重新生成的总结会覆盖之前的总结
+func (s *DailySummaryWithNumbers) Key() string {
+	return DigestKey(s.Digest, s.Date)
+}
+
+// SnapshotKey 这一次生成的总结的存储键，如 "2025-01-10@m5x2k1"，重新生成后仍指向这一次的总结。
+// 已发送的消息按此查找故事编号，没有生成批次时与 Key 相同
+func (s *DailySummaryWithNumbers) SnapshotKey() string {
+	if s.Revision == "" {
+		return s.Key()
+	}
+	return s.Key() + "@" + s.Revision
+}
+
+// Heading 带日期的标题，如 "Hacker News 每日热点 - 2025-01-10"
+func (s *DailySummaryWithNumbers) Heading() string {
+	title := s.Title
+	if title == "" {
+		title = DefaultTitle
+	}
+	return title + " - " + s.Date
 }
 
 type StoryWithNumber struct {
-	Number  int    `json:"number"`
-	StoryID int    `json:"story_id"`
-	Title   string `json:"title"`
-	Summary string `json:"summary"`
-	Content string `json:"content"`
+	Number  int      `json:"number"`
+	StoryID int      `json:"story_id"`
+	Title   string   `json:"title"`
+	Summary string   `json:"summary"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags,omitempty"`
 }
 
 type TopStoriesResponse struct {
@@ -53,4 +94,6 @@ type TopStoriesResponse struct {
 		StoryText   string `json:"story_text"`
 		NumComments int    `json:"num_comments"`
 	} `json:"hits"`
+	Page    int `json:"page"`    // 当前页，从 0 开始
+	NbPages int `json:"nbPages"` // 总页数
 }
@@ -0,0 +1,83 @@
+package hackernews
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 故事排序公式
+const (
+	RankByPoints   = "points"   // 分数
+	RankByComments = "comments" // 评论数
+	RankByVelocity = "velocity" // 每小时获得的分数，发布不久就快速上涨的故事靠前
+	RankByGravity  = "gravity"  // HN 首页的排序公式 (分数-1) / (小时数+2)^gravity
+)
+
+// 排序默认参数
+const (
+	defaultGravity  = 1.8 // HN 首页使用的重力系数
+	minVelocityAge  = 1.0 // 计算速度时的最小小时数，避免刚发布的故事分数被放大
+	gravityAgeShift = 2.0 // HN 公式中加到小时数上的偏移
+)
+
+// Ranking 从时间段内的所有故事中挑选热门故事的排序方式，零值按分数排序
+type Ranking struct {
+	Formula       string  // points、comments、velocity 或 gravity，为空时为 points
+	CommentWeight float64 // 每条评论折算的分数，加到 points、velocity 和 gravity 使用的分数上
+	Gravity       float64 // gravity 公式的重力系数，不大于 0 时为 1.8
+}
+
+// Validate 检查排序公式是否有效
+func (r Ranking) Validate() error {
+	switch r.Formula {
+	case "", RankByPoints, RankByComments, RankByVelocity, RankByGravity:
+		return nil
+	default:
+		return fmt.Errorf("unknown ranking formula %q, available: %s", r.Formula,
+			strings.Join([]string{RankByPoints, RankByComments, RankByVelocity, RankByGravity}, ", "))
+	}
+}
+
+// score 计算故事在 now 时的排序得分
+func (r Ranking) score(story Story, now time.Time) float64 {
+	points := float64(story.Score) + r.CommentWeight*float64(story.NumComments)
+	hours := max(now.Sub(time.Unix(story.Time, 0)).Hours(), 0)
+
+	switch r.Formula {
+	case RankByComments:
+		return float64(story.NumComments)
+	case RankByVelocity:
+		return points / max(hours, minVelocityAge)
+	case RankByGravity:
+		gravity := r.Gravity
+		if gravity <= 0 {
+			gravity = defaultGravity
+		}
+		return (points - 1) / math.Pow(hours+gravityAgeShift, gravity)
+	default:
+		return points
+	}
+}
+
+// Rank 按得分从高到低排序故事，得分相同时分数高的在前，其次是较早发布的。
+// now 为计算故事发布时长的时间，一般为时间段的结束时间
+func (r Ranking) Rank(stories []Story, now time.Time) {
+	scores := make(map[int]float64, len(stories))
+	for _, story := range stories {
+		scores[story.ID] = r.score(story, now)
+	}
+
+	sort.SliceStable(stories, func(i, j int) bool {
+		a, b := stories[i], stories[j]
+		if scores[a.ID] != scores[b.ID] {
+			return scores[a.ID] > scores[b.ID]
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Time < b.Time
+	})
+}
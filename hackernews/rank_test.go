@@ -0,0 +1,57 @@
+package hackernews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rankIDs(ranking Ranking, stories []Story, now time.Time) []int {
+	ranked := append([]Story(nil), stories...)
+	ranking.Rank(ranked, now)
+	ids := make([]int, 0, len(ranked))
+	for _, story := range ranked {
+		ids = append(ids, story.ID)
+	}
+	return ids
+}
+
+func TestRankingFormulas(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	hoursAgo := func(h float64) int64 { return now.Add(-time.Duration(h * float64(time.Hour))).Unix() }
+
+	stories := []Story{
+		{ID: 1, Score: 300, NumComments: 40, Time: hoursAgo(20)},  // 分数最高但发布较早
+		{ID: 2, Score: 120, NumComments: 400, Time: hoursAgo(10)}, // 讨论最多
+		{ID: 3, Score: 100, NumComments: 10, Time: hoursAgo(1)},   // 刚发布就快速上涨
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, rankIDs(Ranking{}, stories, now))
+	assert.Equal(t, []int{1, 2, 3}, rankIDs(Ranking{Formula: RankByPoints}, stories, now))
+	assert.Equal(t, []int{2, 1, 3}, rankIDs(Ranking{Formula: RankByComments}, stories, now))
+	assert.Equal(t, []int{3, 1, 2}, rankIDs(Ranking{Formula: RankByVelocity}, stories, now))
+	assert.Equal(t, []int{3, 2, 1}, rankIDs(Ranking{Formula: RankByGravity}, stories, now))
+
+	// 评论折算为分数后讨论多的故事靠前
+	assert.Equal(t, []int{2, 1, 3}, rankIDs(Ranking{Formula: RankByPoints, CommentWeight: 1}, stories, now))
+}
+
+func TestRankingTies(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	stories := []Story{
+		{ID: 1, Score: 10, NumComments: 5, Time: now.Add(-time.Hour).Unix()},
+		{ID: 2, Score: 20, NumComments: 5, Time: now.Add(-time.Hour).Unix()},
+		{ID: 3, Score: 20, NumComments: 5, Time: now.Add(-2 * time.Hour).Unix()},
+	}
+
+	// 得分相同时分数高的在前，其次是较早发布的
+	assert.Equal(t, []int{3, 2, 1}, rankIDs(Ranking{Formula: RankByComments}, stories, now))
+}
+
+func TestRankingValidate(t *testing.T) {
+	for _, formula := range []string{"", RankByPoints, RankByComments, RankByVelocity, RankByGravity} {
+		assert.NoError(t, Ranking{Formula: formula}.Validate())
+	}
+	assert.ErrorContains(t, Ranking{Formula: "hotness"}.Validate(), "unknown ranking formula")
+}
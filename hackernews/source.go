@@ -0,0 +1,268 @@
+package hackernews
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hacker-news-daily/retry"
+)
+
+// 故事来源名称
+const (
+	SourceFrontPage   = "front_page"    // 当天上过首页的故事，默认来源
+	SourceTop         = "top"           // Firebase topstories 当前排名
+	SourceBest        = "best"          // Firebase beststories 近期最高分
+	SourceNew         = "new"           // Firebase newstories 最新发布
+	SourceAskHN       = "ask_hn"        // Ask HN 提问帖
+	SourceShowHN      = "show_hn"       // Show HN 作品展示帖
+	SourceLaunchHN    = "launch_hn"     // YC 公司的 Launch HN 发布帖
+	SourceWhoIsHiring = "who_is_hiring" // 每月的 "Who is hiring?" 招聘帖
+)
+
+// SourceNames 所有可用的故事来源名称
+var SourceNames = []string{SourceFrontPage, SourceTop, SourceBest, SourceNew, SourceAskHN, SourceShowHN, SourceLaunchHN, SourceWhoIsHiring}
+
+// StorySource 故事来源，每日总结和其他摘要从中获取故事
+type StorySource interface {
+	// Name 来源名称
+	Name() string
+	// Stories 返回发布时间不早于 start 且早于 end 的故事，最多 maxStories 个
+	Stories(ctx context.Context, start, end time.Time, maxStories int) ([]Story, error)
+}
+
+// Source 返回指定名称的故事来源，名称为空时使用首页故事
+func (c *Client) Source(name string) (StorySource, error) {
+	switch name {
+	case "", SourceFrontPage:
+		return &algoliaSource{client: c, name: SourceFrontPage, tags: "front_page"}, nil
+	case SourceAskHN:
+		return &algoliaSource{client: c, name: name, tags: "ask_hn"}, nil
+	case SourceShowHN:
+		return &algoliaSource{client: c, name: name, tags: "show_hn"}, nil
+	case SourceLaunchHN:
+		// Algolia 没有 Launch HN 标签，按标题搜索后只保留以 "Launch HN:" 开头的故事
+		return &algoliaSource{client: c, name: name, tags: "story", query: "Launch HN", match: func(title string) bool {
+			return strings.HasPrefix(title, "Launch HN:")
+		}}, nil
+	case SourceWhoIsHiring:
+		// whoishiring 账号每月同时发布招聘、求职和自由职业三个帖子，只保留招聘帖
+		return &algoliaSource{client: c, name: name, tags: "story,author_whoishiring", match: func(title string) bool {
+			return strings.Contains(strings.ToLower(title), "who is hiring")
+		}}, nil
+	case SourceTop:
+		return &firebaseSource{client: c, name: name, list: "topstories"}, nil
+	case SourceBest:
+		return &firebaseSource{client: c, name: name, list: "beststories"}, nil
+	case SourceNew:
+		return &firebaseSource{client: c, name: name, list: "newstories"}, nil
+	default:
+		return nil, fmt.Errorf("unknown story source %q, available: %s", name, strings.Join(SourceNames, ", "))
+	}
+}
+
+// Algolia 分页参数
+const (
+	algoliaHitsPerPage = 200  // 每页结果数
+	algoliaMaxHits     = 1000 // Algolia 最多返回前 1000 条结果，更早的结果无法翻页获取
+)
+
+// algoliaSource 通过 Algolia search_by_date 按标签查询时间段内的故事，
+// 取回整个时间段的结果后再排序，而不是只取最新发布的几个
+type algoliaSource struct {
+	client *Client
+	name   string
+	tags   string                  // 逗号分隔的标签，同时满足所有标签
+	query  string                  // 搜索关键词，为空时不限制
+	match  func(title string) bool // 为空时保留所有结果
+}
+
+func (s *algoliaSource) Name() string {
+	return s.name
+}
+
+// Stories 分页获取时间段内的所有结果，按客户端的排序方式挑选前 maxStories 个
+func (s *algoliaSource) Stories(ctx context.Context, start, end time.Time, maxStories int) ([]Story, error) {
+	var stories []Story
+	for page := 0; page*algoliaHitsPerPage < algoliaMaxHits; page++ {
+		response, err := s.search(ctx, start, end, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hit := range response.Hits {
+			if s.match != nil && !s.match(hit.Title) {
+				continue
+			}
+			stories = append(stories, Story{
+				ID:            parseInt(hit.ObjectID),
+				Title:         hit.Title,
+				URL:           hit.URL,
+				Score:         hit.Points,
+				By:            hit.Author,
+				Time:          hit.CreatedAtI,
+				Text:          hit.StoryText,
+				NumComments:   hit.NumComments,
+				HackerNewsURL: fmt.Sprintf("https://news.ycombinator.com/item?id=%s", hit.ObjectID),
+			})
+		}
+		if page+1 >= response.NbPages {
+			break
+		}
+	}
+
+	// 以时间段结束时间计算发布时长，查询今天时使用当前时间
+	now := time.Now()
+	if end.Before(now) {
+		now = end
+	}
+	s.client.ranking.Rank(stories, now)
+	if len(stories) > maxStories {
+		stories = stories[:maxStories]
+	}
+	return stories, nil
+}
+
+// search 获取一页按发布时间倒序的搜索结果
+func (s *algoliaSource) search(ctx context.Context, start, end time.Time, page int) (*TopStoriesResponse, error) {
+	params := map[string]string{
+		"tags":           s.tags,
+		"numericFilters": fmt.Sprintf("created_at_i>=%d,created_at_i<%d", start.Unix(), end.Unix()),
+		"hitsPerPage":    strconv.Itoa(algoliaHitsPerPage),
+		"page":           strconv.Itoa(page),
+	}
+	if s.query != "" {
+		params["query"] = s.query
+	}
+
+	var response TopStoriesResponse
+	err := s.client.algolia.Do(ctx, func() error {
+		if err := s.client.limiter.acquire(ctx); err != nil {
+			return err
+		}
+		defer s.client.limiter.release()
+
+		resp, err := s.client.httpClient.R().
+			SetContext(ctx).
+			SetResult(&response).
+			SetQueryParams(params).
+			Get(s.client.algoliaURL + "/search_by_date")
+
+		if err != nil {
+			return retry.Network(fmt.Errorf("failed to fetch %s stories: %w", s.name, err), true)
+		}
+
+		if resp.StatusCode() != 200 {
+			return retry.Status(fmt.Errorf("API returned status code: %d", resp.StatusCode()), resp.StatusCode(), resp.Header(), true)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// firebaseSource 读取 Firebase 的故事排名列表，按列表本身的排名顺序保留时间段内的故事，
+// 不使用客户端的排序方式。列表只反映当前排名，适合最近的时间段
+type firebaseSource struct {
+	client *Client
+	name   string
+	list   string // topstories、beststories 或 newstories
+}
+
+// firebaseStory 带类型和状态字段的故事条目，用于过滤招聘帖和已删除的故事
+type firebaseStory struct {
+	Story
+	Type    string `json:"type"`
+	Dead    bool   `json:"dead"`
+	Deleted bool   `json:"deleted"`
+}
+
+func (s *firebaseSource) Name() string {
+	return s.name
+}
+
+// Stories 按排名顺序获取时间段内的前 maxStories 个故事，maxStories 不大于 0 时不返回故事
+func (s *firebaseSource) Stories(ctx context.Context, start, end time.Time, maxStories int) ([]Story, error) {
+	if maxStories <= 0 {
+		return nil, nil
+	}
+
+	ids, err := s.client.getStoryList(ctx, s.list)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每次并发获取一批，凑够数量后不再请求后面的故事
+	stories := make([]Story, 0, maxStories)
+	for len(ids) > 0 && len(stories) < maxStories {
+		batch := ids[:min(maxStories, len(ids))]
+		ids = ids[len(batch):]
+
+		for _, item := range s.client.getStoriesParallel(ctx, batch) {
+			if item.ID == 0 || item.Dead || item.Deleted || item.Type != "story" {
+				continue
+			}
+			if item.Time < start.Unix() || item.Time >= end.Unix() || len(stories) == maxStories {
+				continue
+			}
+			story := item.Story
+			story.HackerNewsURL = fmt.Sprintf("https://news.ycombinator.com/item?id=%d", story.ID)
+			stories = append(stories, story)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return stories, nil
+}
+
+// getStoryList 获取 Firebase 故事排名列表中的故事 ID
+func (c *Client) getStoryList(ctx context.Context, list string) ([]int, error) {
+	var ids []int
+	err := c.firebase.Do(ctx, func() error {
+		if err := c.limiter.acquire(ctx); err != nil {
+			return err
+		}
+		defer c.limiter.release()
+
+		resp, err := c.httpClient.R().
+			SetContext(ctx).
+			SetResult(&ids).
+			Get(fmt.Sprintf("%s/%s.json", c.firebaseURL, list))
+
+		if err != nil {
+			return retry.Network(fmt.Errorf("failed to fetch %s: %w", list, err), true)
+		}
+
+		if resp.StatusCode() != 200 {
+			return retry.Status(fmt.Errorf("%s API returned status code: %d", list, resp.StatusCode()), resp.StatusCode(), resp.Header(), true)
+		}
+
+		return nil
+	})
+	return ids, err
+}
+
+// getStoriesParallel 并发获取多个故事，结果与 storyIDs 顺序一致，获取失败的位置为零值
+func (c *Client) getStoriesParallel(ctx context.Context, storyIDs []int) []firebaseStory {
+	results := make([]firebaseStory, len(storyIDs))
+	var wg sync.WaitGroup
+	for i, storyID := range storyIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if story, err := getItem[firebaseStory](ctx, c, storyID); err == nil {
+				results[i] = story
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
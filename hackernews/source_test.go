@@ -0,0 +1,118 @@
+package hackernews
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/cache"
+	"hacker-news-daily/hackernews/hntest"
+)
+
+// sourceDay 来源测试中的故事都发布在这一天
+var sourceDay = time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+func storyAt(id int, hour int, title, by string) hntest.Item {
+	return hntest.Item{ID: id, Type: "story", Title: title, By: by, Score: id, Time: sourceDay.Add(time.Duration(hour) * time.Hour).Unix()}
+}
+
+func storyTitles(stories []Story) []string {
+	titles := make([]string, 0, len(stories))
+	for _, story := range stories {
+		titles = append(titles, story.Title)
+	}
+	return titles
+}
+
+func fetchSource(t *testing.T, client *Client, name string, days, maxStories int) []Story {
+	t.Helper()
+	source, err := client.Source(name)
+	require.NoError(t, err)
+	assert.Equal(t, name, source.Name())
+
+	stories, err := client.GetStoriesByDate(context.Background(), source, sourceDay.Format("2006-01-02"), days, maxStories)
+	require.NoError(t, err)
+	return stories
+}
+
+func TestAlgoliaSources(t *testing.T) {
+	client, server := newTestClient(t)
+	server.AddStory(storyAt(1, 1, "Front page story", "a"))
+	server.AddItem(
+		storyAt(2, 2, "Show HN: My side project", "b"),
+		storyAt(3, 3, "Ask HN: How do you test?", "c"),
+		storyAt(4, 4, "Launch HN: Acme (YC W25) – Rockets", "d"),
+		storyAt(5, 5, "Why Launch HN posts work", "e"),
+		storyAt(6, 6, "Ask HN: Who is hiring? (March 2025)", "whoishiring"),
+		storyAt(7, 6, "Ask HN: Who wants to be hired? (March 2025)", "whoishiring"),
+		hntest.Item{ID: 8, Type: "story", Title: "Show HN: Last week", Time: sourceDay.AddDate(0, 0, -5).Unix()},
+	)
+
+	assert.Equal(t, []string{"Front page story"}, storyTitles(fetchSource(t, client, SourceFrontPage, 1, 10)))
+	assert.Equal(t, []string{"Show HN: My side project"}, storyTitles(fetchSource(t, client, SourceShowHN, 1, 10)))
+	assert.Equal(t, []string{"Ask HN: Who wants to be hired? (March 2025)", "Ask HN: Who is hiring? (March 2025)", "Ask HN: How do you test?"},
+		storyTitles(fetchSource(t, client, SourceAskHN, 1, 10)))
+	assert.Equal(t, []string{"Launch HN: Acme (YC W25) – Rockets"}, storyTitles(fetchSource(t, client, SourceLaunchHN, 1, 10)))
+	assert.Equal(t, []string{"Ask HN: Who is hiring? (March 2025)"}, storyTitles(fetchSource(t, client, SourceWhoIsHiring, 1, 10)))
+
+	// 时间段按天数向前延伸
+	assert.Equal(t, []string{"Show HN: My side project", "Show HN: Last week"}, storyTitles(fetchSource(t, client, SourceShowHN, 7, 10)))
+}
+
+func TestFirebaseSourceKeepsRankOrder(t *testing.T) {
+	client, server := newTestClient(t)
+	server.AddItem(
+		storyAt(1, 1, "First", "a"),
+		storyAt(2, 2, "Second", "b"),
+		hntest.Item{ID: 3, Type: "job", Title: "Hiring", Time: sourceDay.Add(time.Hour).Unix()},
+		storyAt(5, 4, "Third", "d"),
+		storyAt(6, 5, "Fourth", "e"),
+	)
+	server.AddItem(hntest.Item{ID: 4, Type: "story", Title: "Old", Time: sourceDay.AddDate(0, 0, -3).Unix()})
+	server.SetList("topstories", 2, 3, 4, 999, 1, 5, 6)
+
+	// 跳过招聘帖、时间段外和不存在的条目，按排名顺序凑够数量
+	stories := fetchSource(t, client, SourceTop, 1, 3)
+	assert.Equal(t, []string{"Second", "First", "Third"}, storyTitles(stories))
+	assert.Equal(t, "https://news.ycombinator.com/item?id=2", stories[0].HackerNewsURL)
+
+	// 够数后不再请求后面的故事
+	for _, request := range server.Requests() {
+		assert.NotContains(t, request, "/item/6.json")
+	}
+
+	// 数量不大于 0 时不返回故事
+	assert.Empty(t, fetchSource(t, client, SourceTop, 1, -1))
+}
+
+func TestFirebaseSourceWithSharedCache(t *testing.T) {
+	client, server := newTestClient(t)
+	itemCache, err := cache.New(time.Hour, "")
+	require.NoError(t, err)
+	client.SetCache(itemCache)
+	server.AddItem(
+		storyAt(1, 1, "First", "a"),
+		hntest.Item{ID: 2, Type: "job", Title: "Hiring", Time: sourceDay.Add(time.Hour).Unix()},
+	)
+	server.SetList("topstories", 2, 1)
+
+	// 先以 Story 读取招聘帖，缓存中的条目仍保留类型字段
+	story, _, err := client.GetStoryWithComments(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Hiring", story.Title)
+
+	assert.Equal(t, []string{"First"}, storyTitles(fetchSource(t, client, SourceTop, 1, 10)))
+}
+
+func TestUnknownSource(t *testing.T) {
+	client := NewClient(5, 5, 5)
+	_, err := client.Source("jobs")
+	assert.ErrorContains(t, err, "unknown story source")
+
+	source, err := client.Source("")
+	require.NoError(t, err)
+	assert.Equal(t, SourceFrontPage, source.Name())
+}
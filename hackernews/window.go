@@ -0,0 +1,85 @@
+package hackernews
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayout 日期参数和总结日期标签的格式
+const dateLayout = "2006-01-02"
+
+// Window 故事发布时间段，包含 Start，不包含 End
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// String 返回时间段的可读形式，用于日志
+func (w Window) String() string {
+	return fmt.Sprintf("[%s, %s)", w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339))
+}
+
+// RangeLabel 返回 from 到 to 这几天的总结日期标签，如 "2025-01-01~2025-01-07"，同一天时为日期本身
+func RangeLabel(from, to string) string {
+	if from == to {
+		return from
+	}
+	return from + "~" + to
+}
+
+// Location 计算自然日使用的时区
+func (c *Client) Location() *time.Location {
+	return c.location
+}
+
+// Today 返回客户端时区中今天的日期
+func (c *Client) Today() string {
+	return time.Now().In(c.location).Format(dateLayout)
+}
+
+// DayWindow 返回截止到 date 当天结束的 days 个自然日，days 不大于 0 时为 1 天。
+// 例如 days 为 1 时是 date 当天 0 点到第二天 0 点
+func (c *Client) DayWindow(date string, days int) (Window, error) {
+	if days <= 0 {
+		days = 1
+	}
+	day, err := c.parseDate(date)
+	if err != nil {
+		return Window{}, err
+	}
+	return Window{Start: day.AddDate(0, 0, 1-days), End: day.AddDate(0, 0, 1)}, nil
+}
+
+// RangeWindow 返回 from 当天 0 点到 to 第二天 0 点，包含 from 和 to 这两天
+func (c *Client) RangeWindow(from, to string) (Window, error) {
+	start, err := c.parseDate(from)
+	if err != nil {
+		return Window{}, err
+	}
+	end, err := c.parseDate(to)
+	if err != nil {
+		return Window{}, err
+	}
+	if end.Before(start) {
+		return Window{}, fmt.Errorf("end date %s is before start date %s", to, from)
+	}
+	return Window{Start: start, End: end.AddDate(0, 0, 1)}, nil
+}
+
+// RecentWindow 返回截止到 now 的 days 个 24 小时，days 不大于 0 时为 1 天
+func (c *Client) RecentWindow(now time.Time, days int) Window {
+	if days <= 0 {
+		days = 1
+	}
+	now = now.In(c.location)
+	return Window{Start: now.Add(-time.Duration(days) * 24 * time.Hour), End: now}
+}
+
+// parseDate 将 YYYY-MM-DD 解析为客户端时区中当天的 0 点
+func (c *Client) parseDate(date string) (time.Time, error) {
+	day, err := time.ParseInLocation(dateLayout, date, c.location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date format: %s", date)
+	}
+	return day, nil
+}
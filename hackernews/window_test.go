@@ -0,0 +1,87 @@
+package hackernews
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/hackernews/hntest"
+)
+
+func TestDayWindow(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	client := NewClient(5, 5, 5)
+
+	// 默认按 UTC 计算自然日
+	window, err := client.DayWindow("2025-01-10", 1)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), window.Start)
+	assert.Equal(t, time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC), window.End)
+
+	client.SetLocation(shanghai)
+	window, err = client.DayWindow("2025-01-10", 7)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 3, 16, 0, 0, 0, time.UTC), window.Start.UTC())
+	assert.Equal(t, time.Date(2025, 1, 10, 16, 0, 0, 0, time.UTC), window.End.UTC())
+
+	_, err = client.DayWindow("2025/01/10", 1)
+	assert.ErrorContains(t, err, "invalid date format")
+}
+
+func TestDayWindowAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	client := NewClient(5, 5, 5)
+	client.SetLocation(newYork)
+
+	// 夏令时开始的这一天只有 23 小时
+	window, err := client.DayWindow("2025-03-09", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 23*time.Hour, window.End.Sub(window.Start))
+}
+
+func TestRangeWindow(t *testing.T) {
+	client := NewClient(5, 5, 5)
+
+	window, err := client.RangeWindow("2025-01-01", "2025-01-07")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), window.Start)
+	assert.Equal(t, time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC), window.End)
+	assert.Equal(t, "2025-01-01~2025-01-07", RangeLabel("2025-01-01", "2025-01-07"))
+	assert.Equal(t, "2025-01-01", RangeLabel("2025-01-01", "2025-01-01"))
+
+	_, err = client.RangeWindow("2025-01-07", "2025-01-01")
+	assert.ErrorContains(t, err, "before start date")
+}
+
+func TestRecentWindow(t *testing.T) {
+	client := NewClient(5, 5, 5)
+	now := time.Date(2025, 1, 10, 8, 30, 0, 0, time.UTC)
+
+	window := client.RecentWindow(now, 0)
+	assert.Equal(t, now.Add(-24*time.Hour), window.Start)
+	assert.Equal(t, now, window.End)
+}
+
+func TestGetStoriesByDateInLocation(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	client, server := newTestClient(t)
+	client.SetLocation(shanghai)
+
+	// 上海时间 1 月 10 日 0 点即 UTC 1 月 9 日 16 点
+	server.AddStory(hntest.Item{ID: 1, Title: "Shanghai Jan 9", Score: 10, Time: time.Date(2025, 1, 9, 15, 59, 0, 0, time.UTC).Unix()})
+	server.AddStory(hntest.Item{ID: 2, Title: "Shanghai midnight", Score: 20, Time: time.Date(2025, 1, 9, 16, 0, 0, 0, time.UTC).Unix()})
+	server.AddStory(hntest.Item{ID: 3, Title: "Shanghai Jan 10 evening", Score: 30, Time: time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC).Unix()})
+	server.AddStory(hntest.Item{ID: 4, Title: "Shanghai Jan 11", Score: 40, Time: time.Date(2025, 1, 10, 16, 0, 0, 0, time.UTC).Unix()})
+
+	source, err := client.Source(SourceFrontPage)
+	require.NoError(t, err)
+	stories, err := client.GetStoriesByDate(context.Background(), source, "2025-01-10", 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Shanghai Jan 10 evening", "Shanghai midnight"}, storyTitles(stories))
+}
@@ -0,0 +1,132 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/retry"
+)
+
+// Discord embed 限制
+const (
+	discordMaxEmbeds      = 10   // 单条消息最多 10 个 embed
+	discordMaxEmbedTotal  = 6000 // 单条消息所有 embed 的字符总数上限
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+	discordMaxContent     = 2000
+	discordEmbedColor     = 0xff6600 // Hacker News 橙色
+)
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	URL         string              `json:"url,omitempty"`
+	Description string              `json:"description"`
+	Color       int                 `json:"color"`
+	Footer      *discordEmbedFooter `json:"footer,omitempty"`
+}
+
+type discordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+// DiscordPublisher 通过 Webhook 以 embed 格式发送每日总结
+type DiscordPublisher struct {
+	httpClient *resty.Client
+	webhookURL string
+	retrier    *retry.Retrier
+}
+
+func NewDiscordPublisher(webhookURL string) (*DiscordPublisher, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("discord webhook url is required")
+	}
+
+	client := resty.New().
+		SetTimeout(webhookTimeout).
+		SetHeader("Content-Type", "application/json")
+
+	return &DiscordPublisher{
+		httpClient: client,
+		webhookURL: webhookURL,
+		retrier:    retry.New("discord", retry.Config{}),
+	}, nil
+}
+
+// SetRetryConfig 设置发送失败时的重试和熔断策略
+func (p *DiscordPublisher) SetRetryConfig(config retry.Config) {
+	p.retrier = retry.New("discord", config)
+}
+
+// Name 渠道名称
+func (p *DiscordPublisher) Name() string {
+	return "discord"
+}
+
+// Publish 发送每日总结，embed 数量或字符数超出限制时拆分为多条消息
+func (p *DiscordPublisher) Publish(ctx context.Context, summary *hackernews.DailySummaryWithNumbers) error {
+	accepted := func(status int) bool { return status == 200 || status == 204 }
+	for _, message := range buildDiscordMessages(summary) {
+		// 每条消息单独重试，已发送的消息不会重复发送
+		if err := postWebhook(ctx, p.retrier, p.httpClient, "discord", p.webhookURL, message, accepted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildDiscordMessages 将每日总结转换为 Discord 消息，每个故事一个 embed
+func buildDiscordMessages(summary *hackernews.DailySummaryWithNumbers) []discordMessage {
+	content := fmt.Sprintf("🗞️ **%s**", summary.Heading())
+	if summary.Overview != "" {
+		content += "\n\n" + summary.Overview
+	}
+
+	var messages []discordMessage
+	current := discordMessage{
+		Content: truncateRunes(content, discordMaxContent),
+	}
+	var currentSize int
+
+	for _, item := range Items(summary) {
+		// 总结过长时截断正文，保留 HN 讨论链接
+		link := fmt.Sprintf("\n\n[HN 讨论](%s)", item.HackerNewsURL)
+		embed := discordEmbed{
+			Title:       truncateRunes(fmt.Sprintf("[%d] %s", item.Number, item.Title), discordMaxTitle),
+			URL:         item.URL,
+			Description: truncateRunes(item.Summary, discordMaxDescription-utf8.RuneCountInString(link)) + link,
+			Color:       discordEmbedColor,
+		}
+		if item.Score > 0 {
+			embed.Footer = &discordEmbedFooter{Text: fmt.Sprintf("%d points", item.Score)}
+		}
+		size := embedSize(embed)
+
+		if len(current.Embeds) > 0 && (len(current.Embeds) >= discordMaxEmbeds || currentSize+size > discordMaxEmbedTotal) {
+			messages = append(messages, current)
+			current = discordMessage{}
+			currentSize = 0
+		}
+		current.Embeds = append(current.Embeds, embed)
+		currentSize += size
+	}
+
+	return append(messages, current)
+}
+
+// embedSize 计算 embed 中计入 6000 字符限制的字符数
+func embedSize(embed discordEmbed) int {
+	size := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	if embed.Footer != nil {
+		size += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	return size
+}
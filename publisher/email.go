@@ -0,0 +1,258 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/retry"
+)
+
+const emailTimeout = 30 * time.Second
+
+var textTemplate = template.Must(template.New("text").Parse(`{{.Heading}}
+{{if .Overview}}
+{{.Overview}}
+{{end}}{{range .Items}}
+[{{.Number}}] {{.Title}}
+{{.Summary}}
+原文: {{.URL}}
+HN 讨论: {{.HackerNewsURL}}
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Heading}}</title></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', sans-serif; max-width: 720px; margin: 0 auto; line-height: 1.6;">
+<h1 style="font-size: 22px;">🗞️ {{.Heading}}</h1>
+{{if .Overview}}<p style="margin: 8px 0 16px; white-space: pre-line;">{{.Overview}}</p>{{end}}
+{{range .Items}}
+<div style="margin-bottom: 24px;">
+  <h2 style="font-size: 17px; margin-bottom: 4px;">[{{.Number}}] <a href="{{.URL}}">{{.Title}}</a></h2>
+  {{if ne .Title .OriginalTitle}}<div style="color: #888; font-size: 13px;">{{.OriginalTitle}}</div>{{end}}
+  <p style="margin: 8px 0;">{{.Summary}}</p>
+  <div style="font-size: 13px;"><a href="{{.URL}}">原文</a> · <a href="{{.HackerNewsURL}}">HN 讨论</a>{{if .Score}} · {{.Score}} points{{end}}</div>
+</div>
+{{end}}
+</body>
+</html>`))
+
+// EmailConfig SMTP 发信配置
+type EmailConfig struct {
+	Host     string
+	Port     int // 465 使用隐式 TLS，其他端口在服务器支持时使用 STARTTLS
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailPublisher 通过 SMTP 发送 HTML 和纯文本双格式的每日总结邮件
+type EmailPublisher struct {
+	config  EmailConfig
+	retrier *retry.Retrier
+}
+
+func NewEmailPublisher(config EmailConfig) (*EmailPublisher, error) {
+	if config.Host == "" || config.Port == 0 {
+		return nil, fmt.Errorf("smtp host and port are required")
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("email sender and recipients are required")
+	}
+	return &EmailPublisher{config: config, retrier: retry.New("email", retry.Config{})}, nil
+}
+
+// SetRetryConfig 设置发送失败时的重试和熔断策略
+func (p *EmailPublisher) SetRetryConfig(config retry.Config) {
+	p.retrier = retry.New("email", config)
+}
+
+// Name 渠道名称
+func (p *EmailPublisher) Name() string {
+	return "email"
+}
+
+// Publish 渲染并发送每日总结邮件
+func (p *EmailPublisher) Publish(ctx context.Context, summary *hackernews.DailySummaryWithNumbers) error {
+	message, err := p.buildMessage(summary, time.Now())
+	if err != nil {
+		return err
+	}
+	return p.retrier.Do(ctx, func() error { return p.send(ctx, message) })
+}
+
+// buildMessage 生成 multipart/alternative 格式的邮件
+func (p *EmailPublisher) buildMessage(summary *hackernews.DailySummaryWithNumbers, now time.Time) ([]byte, error) {
+	data := struct {
+		Heading  string
+		Overview string
+		Items    []Item
+	}{Heading: summary.Heading(), Overview: summary.Overview, Items: Items(summary)}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render text email: %w", err)
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render html email: %w", err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=UTF-8", text.Bytes()},
+		{"text/html; charset=UTF-8", html.Bytes()},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email part: %w", err)
+		}
+		qp := quotedprintable.NewWriter(partWriter)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, fmt.Errorf("failed to encode email part: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode email part: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish email body: %w", err)
+	}
+
+	var message bytes.Buffer
+	headers := []string{
+		"From: " + p.config.From,
+		"To: " + strings.Join(p.config.To, ", "),
+		"Subject: " + mime.BEncoding.Encode("UTF-8", summary.Heading()),
+		"Date: " + now.Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+	for _, header := range headers {
+		message.WriteString(header + "\r\n")
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// send 连接 SMTP 服务器并发送邮件，ctx 取消时关闭连接。
+// 服务器确认收到邮件之前的连接错误和 4xx 临时错误可以重试；结束数据后没有收到确认时
+// 邮件可能已被接收，不再重试，避免重复发送
+func (p *EmailPublisher) send(ctx context.Context, message []byte) error {
+	addr := net.JoinHostPort(p.config.Host, strconv.Itoa(p.config.Port))
+	tlsConfig := &tls.Config{ServerName: p.config.Host}
+
+	dialer := &net.Dialer{Timeout: emailTimeout}
+	var conn net.Conn
+	var err error
+	if p.config.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return retry.Network(fmt.Errorf("failed to connect smtp server: %w", err), false)
+	}
+	conn.SetDeadline(time.Now().Add(emailTimeout))
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, p.config.Host)
+	if err != nil {
+		conn.Close()
+		return smtpRetryable(fmt.Errorf("failed to create smtp client: %w", err))
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && p.config.Port != 465 {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return smtpRetryable(fmt.Errorf("failed to start tls: %w", err))
+		}
+	}
+
+	if p.config.Username != "" {
+		auth := smtp.PlainAuth("", p.config.Username, p.config.Password, p.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return smtpRetryable(fmt.Errorf("smtp authentication failed: %w", err))
+		}
+	}
+
+	if err := client.Mail(envelopeAddress(p.config.From)); err != nil {
+		return smtpRetryable(fmt.Errorf("smtp MAIL FROM failed: %w", err))
+	}
+	for _, to := range p.config.To {
+		if err := client.Rcpt(envelopeAddress(to)); err != nil {
+			return smtpRetryable(fmt.Errorf("smtp RCPT TO %s failed: %w", to, err))
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return smtpRetryable(fmt.Errorf("smtp DATA failed: %w", err))
+	}
+	// 数据没有完整结束时服务器会丢弃邮件，写入失败可以重试
+	if _, err := writer.Write(message); err != nil {
+		return smtpRetryable(fmt.Errorf("failed to write email: %w", err))
+	}
+	if err := writer.Close(); err != nil {
+		err = fmt.Errorf("failed to finish email: %w", err)
+		// 只有服务器明确回复临时错误时才能确定邮件没有被接收
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) {
+			return smtpRetryable(err)
+		}
+		return err
+	}
+
+	// 邮件已被接收，退出失败不影响结果
+	if err := client.Quit(); err != nil {
+		log.Printf("Failed to quit smtp session: %v", err)
+	}
+	return nil
+}
+
+// smtpRetryable 标记服务器接收邮件之前的错误：4xx 临时错误和连接错误可以重试，
+// 5xx 等永久错误不重试
+func smtpRetryable(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		if protoErr.Code >= 400 && protoErr.Code < 500 {
+			return retry.Retryable(err, 0)
+		}
+		return err
+	}
+	return retry.Network(err, true)
+}
+
+// envelopeAddress 从 "名称 <地址>" 格式中提取 SMTP 信封使用的邮箱地址
+func envelopeAddress(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return parsed.Address
+	}
+	return address
+}
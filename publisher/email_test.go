@@ -0,0 +1,219 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/retry"
+)
+
+// fakeSMTPServer 最小的 SMTP 服务端，记录收到的信封和邮件内容
+type fakeSMTPServer struct {
+	listener      net.Listener
+	from          string
+	recipients    []string
+	data          chan string
+	busy          int  // 前几个连接回复 421 临时错误
+	dropAfterData bool // 收到邮件后不回复直接断开连接
+	connections   atomic.Int32
+}
+
+// newFakeSMTPServer 启动服务端，options 在开始接受连接前调整其行为
+func newFakeSMTPServer(t *testing.T, options ...func(*fakeSMTPServer)) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &fakeSMTPServer{listener: listener, data: make(chan string, 5)}
+	for _, option := range options {
+		option(server)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+// fastRetry 测试中使用的重试配置，几乎不等待
+var fastRetry = retry.Config{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// serve 依次处理每个连接
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.handle(conn, int(s.connections.Add(1)))
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn, n int) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	if n <= s.busy {
+		reply("421 localhost busy, try again later")
+		return
+	}
+	reply("220 localhost ESMTP")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			s.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.recipients = append(s.recipients, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.data <- data.String()
+			if s.dropAfterData {
+				return
+			}
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmailPublisher(t *testing.T) {
+	server := newFakeSMTPServer(t)
+
+	publisher, err := NewEmailPublisher(EmailConfig{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "Hacker News Daily <hnd@example.com>",
+		To:   []string{"alice@example.com", "bob@example.com"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "email", publisher.Name())
+	require.NoError(t, publisher.Publish(context.Background(), testSummary))
+
+	var data string
+	select {
+	case data = <-server.data:
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp server did not receive data")
+	}
+	assert.Equal(t, "hnd@example.com", server.from)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, server.recipients)
+
+	message, err := mail.ReadMessage(strings.NewReader(data))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Hacker News 每日热点 - 2024-01-15", subject)
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(part)
+		require.NoError(t, err)
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[partType] = string(content)
+	}
+
+	require.Contains(t, parts, "text/plain")
+	assert.Contains(t, parts["text/plain"], "[1] Go 1.22 发布")
+	assert.Contains(t, parts["text/plain"], "原文: https://go.dev/blog/go1.22")
+	assert.Contains(t, parts["text/plain"], "HN 讨论: https://news.ycombinator.com/item?id=1")
+
+	require.Contains(t, parts, "text/html")
+	assert.Contains(t, parts["text/html"], `<a href="https://go.dev/blog/go1.22">Go 1.22 发布</a>`)
+	assert.Contains(t, parts["text/html"], `<a href="https://news.ycombinator.com/item?id=2">HN 讨论</a>`)
+	assert.Contains(t, parts["text/html"], "&lt;for&gt;", "总结内容应被 HTML 转义")
+}
+
+func TestNewEmailPublisherValidation(t *testing.T) {
+	_, err := NewEmailPublisher(EmailConfig{Port: 25, From: "a@example.com", To: []string{"b@example.com"}})
+	assert.Error(t, err)
+
+	_, err = NewEmailPublisher(EmailConfig{Host: "localhost", Port: 25, From: "a@example.com"})
+	assert.Error(t, err)
+}
+
+func TestEmailPublisherConnectionError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	publisher, err := NewEmailPublisher(EmailConfig{Host: "127.0.0.1", Port: port, From: "a@example.com", To: []string{"b@example.com"}})
+	require.NoError(t, err)
+	publisher.SetRetryConfig(fastRetry)
+	assert.Error(t, publisher.Publish(context.Background(), testSummary))
+}
+
+func TestEmailPublisherRetriesTemporaryErrors(t *testing.T) {
+	server := newFakeSMTPServer(t, func(s *fakeSMTPServer) { s.busy = 1 })
+
+	publisher, err := NewEmailPublisher(EmailConfig{Host: "127.0.0.1", Port: server.port(), From: "a@example.com", To: []string{"b@example.com"}})
+	require.NoError(t, err)
+	publisher.SetRetryConfig(fastRetry)
+	require.NoError(t, publisher.Publish(context.Background(), testSummary))
+
+	assert.Equal(t, int32(2), server.connections.Load())
+	assert.Len(t, server.data, 1)
+}
+
+func TestEmailPublisherDoesNotResendAfterData(t *testing.T) {
+	server := newFakeSMTPServer(t, func(s *fakeSMTPServer) { s.dropAfterData = true })
+
+	publisher, err := NewEmailPublisher(EmailConfig{Host: "127.0.0.1", Port: server.port(), From: "a@example.com", To: []string{"b@example.com"}})
+	require.NoError(t, err)
+	publisher.SetRetryConfig(fastRetry)
+
+	// 结束数据后没有收到确认，邮件可能已被接收，不再重发
+	assert.ErrorContains(t, publisher.Publish(context.Background(), testSummary), "failed to finish email")
+	assert.Equal(t, int32(1), server.connections.Load())
+	assert.Len(t, server.data, 1)
+}
@@ -0,0 +1,115 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/retry"
+)
+
+// webhookTimeout Slack、Discord 等 webhook 请求的超时时间
+const webhookTimeout = 30 * time.Second
+
+// Publisher 每日总结的输出渠道
+type Publisher interface {
+	// Name 渠道名称，用于日志
+	Name() string
+	// Publish 发布带编号的每日总结
+	Publish(ctx context.Context, summary *hackernews.DailySummaryWithNumbers) error
+}
+
+// postWebhook 向 webhook 发送一条消息，accepted 判断状态码是否表示发送成功。
+// webhook 请求不是幂等的，只在确定消息没有被发布时重试：连接失败、429 和 503；
+// 连接建立后的网络错误和 502、504 等其他 5xx 可能发生在服务端已接收消息之后，不重试，避免消息重复出现
+func postWebhook(ctx context.Context, retrier *retry.Retrier, client *resty.Client, name, url string, message any, accepted func(status int) bool) error {
+	return retrier.Do(ctx, func() error {
+		resp, err := client.R().
+			SetContext(ctx).
+			SetBody(message).
+			Post(url)
+		if err != nil {
+			return retry.Network(fmt.Errorf("failed to send %s message: %w", name, err), false)
+		}
+		if !accepted(resp.StatusCode()) {
+			err := fmt.Errorf("%s webhook returned status code: %d, body: %s", name, resp.StatusCode(), resp.String())
+			return retry.Status(err, resp.StatusCode(), resp.Header(), false)
+		}
+		return nil
+	})
+}
+
+// Item 渲染单个故事所需的信息
+type Item struct {
+	Number        int
+	StoryID       int
+	Title         string // AI 生成的标题，缺失时使用原标题
+	OriginalTitle string
+	Summary       string // 去除标题后的总结正文
+	URL           string // 原文链接，Ask HN 等没有外链的故事为 HN 讨论链接
+	HackerNewsURL string
+	Score         int
+	Tags          []string
+}
+
+// Items 将每日总结转换为便于渲染的故事列表
+func Items(summary *hackernews.DailySummaryWithNumbers) []Item {
+	stories := make(map[int]hackernews.Story, len(summary.Stories))
+	for _, story := range summary.Stories {
+		stories[story.ID] = story
+	}
+
+	items := make([]Item, 0, len(summary.StorySummaries))
+	for _, storySummary := range summary.StorySummaries {
+		title, body := SplitTitle(storySummary.Summary)
+		if title == "" {
+			title = storySummary.Title
+		}
+
+		story := stories[storySummary.StoryID]
+		url := story.URL
+		if url == "" {
+			url = story.HackerNewsURL
+		}
+
+		items = append(items, Item{
+			Number:        storySummary.Number,
+			StoryID:       storySummary.StoryID,
+			Title:         title,
+			OriginalTitle: storySummary.Title,
+			Summary:       body,
+			URL:           url,
+			HackerNewsURL: story.HackerNewsURL,
+			Score:         story.Score,
+			Tags:          storySummary.Tags,
+		})
+	}
+	return items
+}
+
+// SplitTitle 拆分 "**标题** 正文" 格式的总结，没有加粗标题时标题为空
+func SplitTitle(summary string) (string, string) {
+	summary = strings.TrimSpace(summary)
+	if !strings.HasPrefix(summary, "**") {
+		return "", summary
+	}
+	end := strings.Index(summary[2:], "**")
+	if end < 0 {
+		return "", summary
+	}
+	return strings.TrimSpace(summary[2 : end+2]), strings.TrimSpace(summary[end+4:])
+}
+
+// truncateRunes 按字符数截断文本，超出时以省略号结尾
+func truncateRunes(text string, maxLength int) string {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:maxLength-1]) + "…"
+}
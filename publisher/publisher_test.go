@@ -0,0 +1,50 @@
+package publisher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/hackernews"
+)
+
+var testSummary = &hackernews.DailySummaryWithNumbers{
+	Date: "2024-01-15",
+	Stories: []hackernews.Story{
+		{ID: 1, Title: "Go 1.22 Released", URL: "https://go.dev/blog/go1.22", Score: 500, HackerNewsURL: "https://news.ycombinator.com/item?id=1"},
+		{ID: 2, Title: "Ask HN: What are you working on?", Score: 120, HackerNewsURL: "https://news.ycombinator.com/item?id=2"},
+	},
+	StorySummaries: []hackernews.StoryWithNumber{
+		{Number: 1, StoryID: 1, Title: "Go 1.22 Released", Summary: "**Go 1.22 发布** 新版本修改了 <for> 循环变量语义", Tags: []string{"Go"}},
+		{Number: 2, StoryID: 2, Title: "Ask HN: What are you working on?", Summary: "大家分享了各自的副业项目"},
+	},
+}
+
+func TestSplitTitle(t *testing.T) {
+	title, body := SplitTitle("**标题** 正文内容")
+	assert.Equal(t, "标题", title)
+	assert.Equal(t, "正文内容", body)
+
+	title, body = SplitTitle("没有标题的正文")
+	assert.Empty(t, title)
+	assert.Equal(t, "没有标题的正文", body)
+
+	title, body = SplitTitle("**未闭合的标题")
+	assert.Empty(t, title)
+	assert.Equal(t, "**未闭合的标题", body)
+}
+
+func TestItems(t *testing.T) {
+	items := Items(testSummary)
+	require.Len(t, items, 2)
+
+	assert.Equal(t, "Go 1.22 发布", items[0].Title)
+	assert.Equal(t, "Go 1.22 Released", items[0].OriginalTitle)
+	assert.Equal(t, "https://go.dev/blog/go1.22", items[0].URL)
+	assert.Equal(t, 500, items[0].Score)
+
+	// 没有 AI 标题时使用原标题，没有外链时使用 HN 链接
+	assert.Equal(t, "Ask HN: What are you working on?", items[1].Title)
+	assert.Equal(t, "https://news.ycombinator.com/item?id=2", items[1].URL)
+}
@@ -0,0 +1,139 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/retry"
+)
+
+// Slack Block Kit 限制
+const (
+	slackMaxBlocks      = 50   // 单条消息最多 50 个 block
+	slackMaxSectionText = 3000 // section 文本最多 3000 字符
+	slackMaxHeaderText  = 150  // header 文本最多 150 字符
+)
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"` // 通知中显示的后备文本
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// SlackPublisher 通过 Incoming Webhook 以 Block Kit 格式发送每日总结
+type SlackPublisher struct {
+	httpClient *resty.Client
+	webhookURL string
+	retrier    *retry.Retrier
+}
+
+func NewSlackPublisher(webhookURL string) (*SlackPublisher, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("slack webhook url is required")
+	}
+
+	client := resty.New().
+		SetTimeout(webhookTimeout).
+		SetHeader("Content-Type", "application/json")
+
+	return &SlackPublisher{
+		httpClient: client,
+		webhookURL: webhookURL,
+		retrier:    retry.New("slack", retry.Config{}),
+	}, nil
+}
+
+// SetRetryConfig 设置发送失败时的重试和熔断策略
+func (p *SlackPublisher) SetRetryConfig(config retry.Config) {
+	p.retrier = retry.New("slack", config)
+}
+
+// Name 渠道名称
+func (p *SlackPublisher) Name() string {
+	return "slack"
+}
+
+// Publish 发送每日总结，block 数量超出限制时拆分为多条消息
+func (p *SlackPublisher) Publish(ctx context.Context, summary *hackernews.DailySummaryWithNumbers) error {
+	accepted := func(status int) bool { return status == 200 }
+	for _, message := range buildSlackMessages(summary) {
+		// 每条消息单独重试，已发送的消息不会重复发送
+		if err := postWebhook(ctx, p.retrier, p.httpClient, "slack", p.webhookURL, message, accepted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildSlackMessages 将每日总结转换为 Block Kit 消息，每个故事一个 section
+func buildSlackMessages(summary *hackernews.DailySummaryWithNumbers) []slackMessage {
+	title := "🗞️ " + summary.Heading()
+
+	var messages []slackMessage
+	current := slackMessage{
+		Text: title,
+		Blocks: []slackBlock{{
+			Type: "header",
+			Text: &slackText{Type: "plain_text", Text: truncateRunes(title, slackMaxHeaderText)},
+		}},
+	}
+	if summary.Overview != "" {
+		current.Blocks = append(current.Blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: truncateRunes(escapeSlack(summary.Overview), slackMaxSectionText)},
+		})
+	}
+
+	for _, item := range Items(summary) {
+		if len(current.Blocks) >= slackMaxBlocks {
+			messages = append(messages, current)
+			current = slackMessage{Text: title}
+		}
+
+		links := fmt.Sprintf("<%s|原文> · <%s|HN 讨论>", escapeSlackURL(item.URL), escapeSlackURL(item.HackerNewsURL))
+		if item.Score > 0 {
+			links += fmt.Sprintf(" · %d points", item.Score)
+		}
+		heading := fmt.Sprintf("*[%d] <%s|%s>*\n", item.Number, escapeSlackURL(item.URL), escapeSlackLinkText(item.Title))
+
+		// 总结过长时截断正文，保留标题和链接
+		available := slackMaxSectionText - len([]rune(heading)) - len([]rune(links)) - 1
+		body := truncateRunes(escapeSlack(item.Summary), max(available, 1))
+
+		current.Blocks = append(current.Blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: heading + body + "\n" + links},
+		})
+	}
+
+	return append(messages, current)
+}
+
+// escapeSlack 转义 Slack mrkdwn 中的控制字符
+func escapeSlack(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// escapeSlackLinkText 转义 <url|文本> 中的链接文本，Slack 不支持转义 |，替换为相近的 ¦
+func escapeSlackLinkText(text string) string {
+	return strings.ReplaceAll(escapeSlack(text), "|", "¦")
+}
+
+// escapeSlackURL 对链接中会破坏 <url|文本> 结构的字符进行百分号编码
+func escapeSlackURL(url string) string {
+	return strings.NewReplacer("|", "%7C", "<", "%3C", ">", "%3E").Replace(url)
+}
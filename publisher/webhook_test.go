@@ -0,0 +1,230 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/hackernews"
+)
+
+// newWebhookServer 创建记录请求体的 webhook 测试服务器
+func newWebhookServer(t *testing.T, status int, payloads *[]json.RawMessage) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		*payloads = append(*payloads, payload)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// largeSummary 生成包含 count 个故事、每个总结长度为 summaryLength 的每日总结
+func largeSummary(count, summaryLength int) *hackernews.DailySummaryWithNumbers {
+	summary := &hackernews.DailySummaryWithNumbers{Date: "2024-01-15"}
+	for i := 1; i <= count; i++ {
+		summary.Stories = append(summary.Stories, hackernews.Story{
+			ID:            i,
+			URL:           fmt.Sprintf("https://example.com/%d", i),
+			HackerNewsURL: fmt.Sprintf("https://news.ycombinator.com/item?id=%d", i),
+		})
+		summary.StorySummaries = append(summary.StorySummaries, hackernews.StoryWithNumber{
+			Number:  i,
+			StoryID: i,
+			Title:   fmt.Sprintf("Story %d", i),
+			Summary: fmt.Sprintf("**故事 %d** %s", i, strings.Repeat("长", summaryLength)),
+		})
+	}
+	return summary
+}
+
+func TestSlackPublisher(t *testing.T) {
+	var payloads []json.RawMessage
+	server := newWebhookServer(t, http.StatusOK, &payloads)
+
+	publisher, err := NewSlackPublisher(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), testSummary))
+	require.Len(t, payloads, 1)
+
+	var message slackMessage
+	require.NoError(t, json.Unmarshal(payloads[0], &message))
+	require.Len(t, message.Blocks, 3)
+	assert.Equal(t, "header", message.Blocks[0].Type)
+	assert.Contains(t, message.Blocks[1].Text.Text, "*[1] <https://go.dev/blog/go1.22|Go 1.22 发布>*")
+	assert.Contains(t, message.Blocks[1].Text.Text, "&lt;for&gt;")
+	assert.Contains(t, message.Blocks[1].Text.Text, "<https://news.ycombinator.com/item?id=1|HN 讨论>")
+}
+
+func TestBuildSlackMessagesEscapesLinks(t *testing.T) {
+	summary := &hackernews.DailySummaryWithNumbers{
+		Date:    "2024-01-15",
+		Stories: []hackernews.Story{{ID: 1, URL: "https://example.com/a|b>c", HackerNewsURL: "https://news.ycombinator.com/item?id=1"}},
+		StorySummaries: []hackernews.StoryWithNumber{
+			{Number: 1, StoryID: 1, Summary: "**A | B > C** 正文"},
+		},
+	}
+
+	text := buildSlackMessages(summary)[0].Blocks[1].Text.Text
+	assert.Contains(t, text, "*[1] <https://example.com/a%7Cb%3Ec|A ¦ B &gt; C>*")
+	assert.Contains(t, text, "<https://example.com/a%7Cb%3Ec|原文>")
+}
+
+func TestBuildSlackMessagesLimits(t *testing.T) {
+	messages := buildSlackMessages(largeSummary(60, 5000))
+	require.Len(t, messages, 2)
+
+	var sections int
+	for _, message := range messages {
+		assert.LessOrEqual(t, len(message.Blocks), slackMaxBlocks)
+		for _, block := range message.Blocks {
+			assert.LessOrEqual(t, utf8.RuneCountInString(block.Text.Text), slackMaxSectionText)
+			if block.Type == "section" {
+				sections++
+				assert.Contains(t, block.Text.Text, "HN 讨论", "截断后应保留链接")
+			}
+		}
+	}
+	assert.Equal(t, 60, sections)
+}
+
+func TestDiscordPublisher(t *testing.T) {
+	var payloads []json.RawMessage
+	server := newWebhookServer(t, http.StatusNoContent, &payloads)
+
+	publisher, err := NewDiscordPublisher(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), testSummary))
+	require.Len(t, payloads, 1)
+
+	var message discordMessage
+	require.NoError(t, json.Unmarshal(payloads[0], &message))
+	assert.Contains(t, message.Content, "2024-01-15")
+	require.Len(t, message.Embeds, 2)
+	assert.Equal(t, "[1] Go 1.22 发布", message.Embeds[0].Title)
+	assert.Equal(t, "https://go.dev/blog/go1.22", message.Embeds[0].URL)
+	assert.Contains(t, message.Embeds[0].Description, "[HN 讨论](https://news.ycombinator.com/item?id=1)")
+	assert.Equal(t, "500 points", message.Embeds[0].Footer.Text)
+}
+
+func TestBuildDiscordMessagesLimits(t *testing.T) {
+	// 每个 embed 约 2000 字符，每条消息最多放两个
+	messages := buildDiscordMessages(largeSummary(5, 2000))
+	require.Len(t, messages, 3)
+
+	// 超长总结被截断到 description 上限
+	messages = append(messages, buildDiscordMessages(largeSummary(1, 10000))...)
+
+	// 短总结按 10 个 embed 拆分
+	messages = append(messages, buildDiscordMessages(largeSummary(25, 10))...)
+
+	var embeds int
+	for _, message := range messages {
+		assert.LessOrEqual(t, len(message.Embeds), discordMaxEmbeds)
+		var size int
+		for _, embed := range message.Embeds {
+			assert.LessOrEqual(t, utf8.RuneCountInString(embed.Description), discordMaxDescription)
+			size += embedSize(embed)
+		}
+		assert.LessOrEqual(t, size, discordMaxEmbedTotal)
+		embeds += len(message.Embeds)
+	}
+	assert.Equal(t, 31, embeds)
+}
+
+func TestWebhookMessagesIncludeOverview(t *testing.T) {
+	summary := largeSummary(2, 10)
+	summary.Title = "Hacker News 每周回顾"
+	summary.Overview = "本周 Rust 持续升温 [1]。"
+
+	slackMessages := buildSlackMessages(summary)
+	require.Len(t, slackMessages[0].Blocks, 4)
+	assert.Equal(t, "本周 Rust 持续升温 [1]。", slackMessages[0].Blocks[1].Text.Text)
+
+	discordMessages := buildDiscordMessages(summary)
+	assert.Equal(t, "🗞️ **Hacker News 每周回顾 - 2024-01-15**\n\n本周 Rust 持续升温 [1]。", discordMessages[0].Content)
+}
+
+func TestWebhookErrorStatus(t *testing.T) {
+	var payloads []json.RawMessage
+	server := newWebhookServer(t, http.StatusBadRequest, &payloads)
+
+	slack, err := NewSlackPublisher(server.URL)
+	require.NoError(t, err)
+	slack.SetRetryConfig(fastRetry)
+	assert.ErrorContains(t, slack.Publish(context.Background(), testSummary), "400")
+
+	discord, err := NewDiscordPublisher(server.URL)
+	require.NoError(t, err)
+	discord.SetRetryConfig(fastRetry)
+	assert.ErrorContains(t, discord.Publish(context.Background(), testSummary), "400")
+
+	// 请求本身有误时不重试
+	assert.Len(t, payloads, 2)
+
+	_, err = NewSlackPublisher("")
+	assert.Error(t, err)
+	_, err = NewDiscordPublisher("")
+	assert.Error(t, err)
+}
+
+func TestWebhookRetriesRejectedMessages(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 每条消息第一次请求时服务端繁忙
+		if requests.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	discord, err := NewDiscordPublisher(server.URL)
+	require.NoError(t, err)
+	discord.SetRetryConfig(fastRetry)
+	require.NoError(t, discord.Publish(context.Background(), testSummary))
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestWebhookDoesNotRetryGatewayErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusGatewayTimeout} {
+		var payloads []json.RawMessage
+		server := newWebhookServer(t, status, &payloads)
+
+		// 网关错误时消息可能已经发布，不重试
+		slack, err := NewSlackPublisher(server.URL)
+		require.NoError(t, err)
+		slack.SetRetryConfig(fastRetry)
+		assert.ErrorContains(t, slack.Publish(context.Background(), testSummary), fmt.Sprint(status))
+		assert.Len(t, payloads, 1, status)
+	}
+}
+
+func TestWebhookDoesNotResendAfterConnectionLost(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 收到请求后不回复直接断开，消息可能已经发布
+		requests.Add(1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		conn.Close()
+	}))
+	t.Cleanup(server.Close)
+
+	slack, err := NewSlackPublisher(server.URL)
+	require.NoError(t, err)
+	slack.SetRetryConfig(fastRetry)
+	assert.Error(t, slack.Publish(context.Background(), testSummary))
+	assert.Equal(t, int32(1), requests.Load())
+}
@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 服务连续失败后熔断期间返回的错误
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// breaker 熔断器：连续失败达到阈值后打开，冷却时间过后只放行一个试探请求，
+// 试探成功则关闭，失败则重新计时
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	open      bool
+	probing   bool // 半开状态下是否已有试探请求
+	openedAt  time.Time
+	now       func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow 判断是否允许发出请求
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+
+	b.probing = true
+	return nil
+}
+
+// success 记录一次成功请求并关闭熔断器
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.open = false
+	b.probing = false
+}
+
+// failure 记录一次失败请求，熔断器因此从关闭变为打开时返回 true
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.open {
+		// 试探请求失败，重新开始冷却
+		b.probing = false
+		b.openedAt = b.now()
+		return false
+	}
+
+	if b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = b.now()
+		return true
+	}
+	return false
+}
+
+// cancel 记录一次被调用方取消的请求：不计入失败，若是试探请求则回到打开状态，
+// 冷却时间已过，下一个请求可以重新试探
+func (b *breaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
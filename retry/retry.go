@@ -0,0 +1,257 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 重试策略的默认值
+const (
+	defaultMaxAttempts      = 3
+	defaultInitialBackoff   = 500 * time.Millisecond
+	defaultMaxBackoff       = 10 * time.Second
+	defaultMaxRetryAfter    = time.Minute
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// Config 重试和熔断配置，零值字段使用默认值
+type Config struct {
+	MaxAttempts      int           // 包括首次请求在内的最大尝试次数
+	InitialBackoff   time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff       time.Duration // 单次等待时间上限
+	MaxRetryAfter    time.Duration // 服务端要求的等待时间超过该值时不再重试
+	BreakerThreshold int           // 连续失败多少次后熔断
+	BreakerCooldown  time.Duration // 熔断后多久允许试探请求
+}
+
+// withDefaults 返回零值字段替换为默认值后的配置
+func (c Config) withDefaults() Config {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = defaultInitialBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		c.MaxBackoff = c.InitialBackoff
+	}
+	if c.MaxRetryAfter <= 0 {
+		c.MaxRetryAfter = defaultMaxRetryAfter
+	}
+	if c.BreakerThreshold <= 0 {
+		c.BreakerThreshold = defaultBreakerThreshold
+	}
+	if c.BreakerCooldown <= 0 {
+		c.BreakerCooldown = defaultBreakerCooldown
+	}
+	return c
+}
+
+// Retrier 对单个外部服务的请求进行重试，同一服务的所有请求共享一个熔断器
+type Retrier struct {
+	name    string
+	config  Config
+	breaker *breaker
+	wait    func(ctx context.Context, d time.Duration) error
+}
+
+// New 创建名为 name 的服务的重试器
+func New(name string, config Config) *Retrier {
+	config = config.withDefaults()
+	return &Retrier{
+		name:    name,
+		config:  config,
+		breaker: newBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		wait:    wait,
+	}
+}
+
+// Do 执行 fn，fn 返回可重试的错误时按指数退避重试；熔断期间直接返回 ErrCircuitOpen，
+// ctx 取消后不再重试
+func (r *Retrier) Do(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.breaker.allow(); err != nil {
+			return fmt.Errorf("%s: %w", r.name, err)
+		}
+
+		err := fn()
+		if err != nil && ctx.Err() != nil {
+			// 调用方取消导致的失败与服务状态无关，只释放试探名额
+			r.breaker.cancel()
+			return err
+		}
+		if err == nil || !IsRetryable(err) {
+			// 服务正常响应，即使是请求本身有误也不计入熔断
+			r.breaker.success()
+			return err
+		}
+
+		if r.breaker.failure() {
+			log.Printf("Circuit breaker for %s opened after %d consecutive failures", r.name, r.config.BreakerThreshold)
+		}
+
+		if attempt >= r.config.MaxAttempts {
+			return err
+		}
+
+		delay := r.backoff(attempt)
+		if after := retryAfter(err); after > 0 {
+			if after > r.config.MaxRetryAfter {
+				return err
+			}
+			delay = after
+		}
+
+		log.Printf("%s request failed (attempt %d/%d), retrying in %v: %v", r.name, attempt, r.config.MaxAttempts, delay, err)
+		if waitErr := r.wait(ctx, delay); waitErr != nil {
+			return err
+		}
+	}
+}
+
+// wait 等待 d 时间，ctx 先取消时提前返回
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff 返回第 attempt 次失败后的等待时间：指数增长并在上限内加入随机抖动，
+// 避免多个请求同时重试
+func (r *Retrier) backoff(attempt int) time.Duration {
+	delay := r.config.InitialBackoff
+	for i := 1; i < attempt && delay < r.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.config.MaxBackoff {
+		delay = r.config.MaxBackoff
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryableError 标记可以重试的错误，after 为服务端要求的等待时间
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// Retryable 将 err 标记为可重试，after 大于 0 时重试前至少等待该时间
+func Retryable(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err, after: after}
+}
+
+// IsRetryable 判断错误是否可以重试
+func IsRetryable(err error) bool {
+	var retryable *retryableError
+	return errors.As(err, &retryable)
+}
+
+// retryAfter 返回错误中服务端要求的等待时间
+func retryAfter(err error) time.Duration {
+	var retryable *retryableError
+	if errors.As(err, &retryable) {
+		return retryable.after
+	}
+	return 0
+}
+
+// Network 根据请求是否幂等标记网络错误：幂等请求在连接失败、超时或连接被关闭时重试，
+// 非幂等请求只在连接未建立时重试，避免服务端已处理的请求被重复执行；
+// 地址格式错误等配置问题不重试
+func Network(err error, idempotent bool) error {
+	if err == nil {
+		return nil
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return Retryable(err, 0)
+	}
+	if idempotent && isTransient(err) {
+		return Retryable(err, 0)
+	}
+	return err
+}
+
+// isTransient 判断错误是否由连接中断或超时引起
+func isTransient(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Status 根据 HTTP 状态码标记错误：429 和 503 表示请求未被处理，总是可以重试；
+// 其他 5xx 和 408 只对幂等请求重试
+func Status(err error, status int, header http.Header, idempotent bool) error {
+	switch {
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		return Retryable(err, ParseRetryAfter(header.Get("Retry-After")))
+	case idempotent && (status >= 500 || status == http.StatusRequestTimeout):
+		return Retryable(err, ParseRetryAfter(header.Get("Retry-After")))
+	}
+	return err
+}
+
+// ParseRetryAfter 解析 Retry-After 头，支持秒数和 HTTP 日期两种格式，无法解析时返回 0
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,238 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRetrier 创建不真正等待的重试器，返回记录的等待时间
+func newTestRetrier(config Config) (*Retrier, *[]time.Duration) {
+	r := New("test", config)
+	var delays []time.Duration
+	r.wait = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return r, &delays
+}
+
+func TestDoRetriesRetryableErrors(t *testing.T) {
+	r, delays := newTestRetrier(Config{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+
+	calls := 0
+	err := r.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return Retryable(errors.New("bad gateway"), 0)
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	require.Len(t, *delays, 2)
+	assert.GreaterOrEqual(t, (*delays)[0], 50*time.Millisecond)
+	assert.LessOrEqual(t, (*delays)[0], 100*time.Millisecond)
+	assert.GreaterOrEqual(t, (*delays)[1], 100*time.Millisecond)
+	assert.LessOrEqual(t, (*delays)[1], 200*time.Millisecond)
+}
+
+func TestDoStopsOnPermanentError(t *testing.T) {
+	r, delays := newTestRetrier(Config{})
+
+	calls := 0
+	permanent := errors.New("bad request")
+	err := r.Do(context.Background(), func() error {
+		calls++
+		return permanent
+	})
+
+	assert.ErrorIs(t, err, permanent)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, *delays)
+}
+
+func TestDoGivesUpAfterMaxAttempts(t *testing.T) {
+	r, delays := newTestRetrier(Config{MaxAttempts: 2})
+
+	calls := 0
+	err := r.Do(context.Background(), func() error {
+		calls++
+		return Retryable(errors.New("timeout"), 0)
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Len(t, *delays, 1)
+}
+
+func TestDoHonoursRetryAfter(t *testing.T) {
+	r, delays := newTestRetrier(Config{MaxRetryAfter: 10 * time.Second})
+
+	calls := 0
+	err := r.Do(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return Retryable(errors.New("too many requests"), 3*time.Second)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{3 * time.Second}, *delays)
+
+	// 要求等待的时间过长时直接放弃
+	calls = 0
+	err = r.Do(context.Background(), func() error {
+		calls++
+		return Retryable(errors.New("too many requests"), time.Minute)
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	r := New("test", Config{InitialBackoff: time.Second, MaxBackoff: 4 * time.Second})
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		delay := r.backoff(attempt)
+		assert.LessOrEqual(t, delay, 4*time.Second)
+	}
+	assert.GreaterOrEqual(t, r.backoff(10), 2*time.Second)
+}
+
+func TestStatusClassification(t *testing.T) {
+	err := errors.New("status error")
+	header := http.Header{"Retry-After": []string{"7"}}
+
+	assert.True(t, IsRetryable(Status(err, http.StatusTooManyRequests, header, false)))
+	assert.Equal(t, 7*time.Second, retryAfter(Status(err, http.StatusTooManyRequests, header, false)))
+	assert.True(t, IsRetryable(Status(err, http.StatusServiceUnavailable, nil, false)))
+	assert.True(t, IsRetryable(Status(err, http.StatusBadGateway, nil, true)))
+	assert.False(t, IsRetryable(Status(err, http.StatusBadGateway, nil, false)))
+	assert.True(t, IsRetryable(Status(err, http.StatusRequestTimeout, nil, true)))
+	assert.False(t, IsRetryable(Status(err, http.StatusBadRequest, nil, true)))
+}
+
+func TestNetworkClassification(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+
+	assert.True(t, IsRetryable(Network(dialErr, false)))
+	assert.False(t, IsRetryable(Network(readErr, false)))
+	assert.True(t, IsRetryable(Network(readErr, true)))
+	assert.True(t, IsRetryable(Network(&url.Error{Op: "Get", URL: "http://hn", Err: io.ErrUnexpectedEOF}, true)))
+	assert.False(t, IsRetryable(Network(&url.Error{Op: "Get", URL: "/item", Err: errors.New("unsupported protocol scheme")}, true)))
+	assert.NoError(t, Network(nil, true))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 5*time.Second, ParseRetryAfter("5"))
+	assert.Zero(t, ParseRetryAfter(""))
+	assert.Zero(t, ParseRetryAfter("-1"))
+	assert.Zero(t, ParseRetryAfter("soon"))
+
+	date := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	wait := ParseRetryAfter(date)
+	assert.Greater(t, wait, 20*time.Second)
+	assert.LessOrEqual(t, wait, 30*time.Second)
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	r, _ := newTestRetrier(Config{MaxAttempts: 1, BreakerThreshold: 2, BreakerCooldown: time.Minute})
+	now := time.Now()
+	r.breaker.now = func() time.Time { return now }
+
+	failing := func() error { return Retryable(errors.New("unavailable"), 0) }
+	assert.Error(t, r.Do(context.Background(), failing))
+	assert.Error(t, r.Do(context.Background(), failing))
+
+	// 熔断期间不调用服务
+	calls := 0
+	err := r.Do(context.Background(), func() error {
+		calls++
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Zero(t, calls)
+
+	// 冷却后试探失败则继续熔断
+	now = now.Add(time.Minute)
+	assert.Error(t, r.Do(context.Background(), failing))
+	assert.ErrorIs(t, r.Do(context.Background(), func() error { return nil }), ErrCircuitOpen)
+
+	// 再次冷却后试探成功则恢复
+	now = now.Add(time.Minute)
+	require.NoError(t, r.Do(context.Background(), func() error { return nil }))
+	require.NoError(t, r.Do(context.Background(), func() error { return nil }))
+}
+
+func TestBreakerRecoversAfterCancelledProbe(t *testing.T) {
+	r, _ := newTestRetrier(Config{MaxAttempts: 1, BreakerThreshold: 1, BreakerCooldown: time.Minute})
+	now := time.Now()
+	r.breaker.now = func() time.Time { return now }
+
+	assert.Error(t, r.Do(context.Background(), func() error { return Retryable(errors.New("unavailable"), 0) }))
+	now = now.Add(time.Minute)
+
+	// 试探请求被调用方取消，不计入失败也不占用试探名额
+	ctx, cancel := context.WithCancel(context.Background())
+	err := r.Do(ctx, func() error {
+		cancel()
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	require.NoError(t, r.Do(context.Background(), func() error { return nil }))
+	require.NoError(t, r.Do(context.Background(), func() error { return nil }))
+}
+
+func TestBreakerIgnoresPermanentErrors(t *testing.T) {
+	r, _ := newTestRetrier(Config{MaxAttempts: 1, BreakerThreshold: 2})
+
+	for range 5 {
+		assert.Error(t, r.Do(context.Background(), func() error { return errors.New("bad request") }))
+	}
+	assert.NoError(t, r.Do(context.Background(), func() error { return nil }))
+}
+
+func TestDoStopsWhenContextCancelled(t *testing.T) {
+	r := New("test", Config{MaxAttempts: 5, InitialBackoff: time.Minute, MaxBackoff: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Do(ctx, func() error {
+			calls++
+			return Retryable(errors.New("unavailable"), 0)
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	case <-time.After(time.Second):
+		t.Fatal("Do did not return after context was cancelled")
+	}
+
+	// 已取消的 ctx 不再发出请求
+	calls = 0
+	assert.ErrorIs(t, r.Do(ctx, func() error {
+		calls++
+		return nil
+	}), context.Canceled)
+	assert.Zero(t, calls)
+}
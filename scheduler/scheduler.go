@@ -1,26 +1,34 @@
 package scheduler
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/robfig/cron/v3"
 )
 
 type Scheduler struct {
 	cron *cron.Cron
+	ctx  context.Context // 传给每个任务，取消后正在执行的任务随之中止
 }
 
-type JobFunc func() error
+type JobFunc func(ctx context.Context) error
 
-func NewScheduler() *Scheduler {
-	c := cron.New(cron.WithSeconds())
-	return &Scheduler{cron: c}
+// NewScheduler 创建调度器，cron 表达式按 location 时区解析，为空时使用本地时区；
+// ctx 取消时正在执行的任务随之中止
+func NewScheduler(ctx context.Context, location *time.Location) *Scheduler {
+	if location == nil {
+		location = time.Local
+	}
+	c := cron.New(cron.WithSeconds(), cron.WithLocation(location))
+	return &Scheduler{cron: c, ctx: ctx}
 }
 
 // AddJob 添加定时任务
 func (s *Scheduler) AddJob(cronExpr string, job JobFunc) error {
 	_, err := s.cron.AddFunc(cronExpr, func() {
-		if err := job(); err != nil {
+		if err := job(s.ctx); err != nil {
 			log.Printf("Job execution failed: %v", err)
 		}
 	})
@@ -33,14 +41,14 @@ func (s *Scheduler) Start() {
 	log.Println("Scheduler started")
 }
 
-// Stop 停止调度器
+// Stop 停止调度器，不再触发新任务，并等待正在执行的任务完成
 func (s *Scheduler) Stop() {
-	s.cron.Stop()
+	<-s.cron.Stop().Done()
 	log.Println("Scheduler stopped")
 }
 
 // RunOnce 立即执行一次任务（用于测试）
 func (s *Scheduler) RunOnce(job JobFunc) error {
 	log.Println("Running job once...")
-	return job()
+	return job(s.ctx)
 }
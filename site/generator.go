@@ -0,0 +1,210 @@
+package site
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"hacker-news-daily/feed"
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/publisher"
+	"hacker-news-daily/storage"
+)
+
+const (
+	defaultPageSize = 10 // 首页每页显示的天数
+	feedSize        = 30 // 订阅中保留的最近天数
+	siteTitle       = "Hacker News 每日热点"
+)
+
+// Generator 将存储中的每日总结渲染为静态网站
+type Generator struct {
+	store     storage.Store
+	outputDir string
+	baseURL   string // 网站部署地址，用于订阅中的绝对链接
+	pageSize  int
+	model     string // 读取该模型生成的详细总结
+}
+
+func NewGenerator(store storage.Store, outputDir, baseURL string, pageSize int) *Generator {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if baseURL != "" && !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	return &Generator{
+		store:     store,
+		outputDir: outputDir,
+		baseURL:   baseURL,
+		pageSize:  pageSize,
+	}
+}
+
+// SetSummaryModel 设置页面中显示的详细总结由哪个模型生成，与机器人使用的模型标识一致
+func (g *Generator) SetSummaryModel(model string) {
+	g.model = model
+}
+
+// digest 一天的总结中渲染用的故事列表
+type digest struct {
+	Date  string
+	Items []publisher.Item
+}
+
+// Generate 生成所有页面：每日页面、故事页面、分页首页和 RSS/Atom 订阅
+func (g *Generator) Generate() error {
+	dates, err := g.store.ListDates()
+	if err != nil {
+		return fmt.Errorf("failed to list dates: %w", err)
+	}
+
+	// 最新的日期在前
+	digests := make([]digest, 0, len(dates))
+	summaries := make([]*hackernews.DailySummaryWithNumbers, 0, len(dates))
+	for i := len(dates) - 1; i >= 0; i-- {
+		summary, err := g.store.GetDailySummary(dates[i])
+		if err != nil {
+			return fmt.Errorf("failed to get daily summary for %s: %w", dates[i], err)
+		}
+		digests = append(digests, digest{Date: summary.Date, Items: publisher.Items(summary)})
+		summaries = append(summaries, summary)
+	}
+
+	for i, d := range digests {
+		var newer, older string
+		if i > 0 {
+			newer = digests[i-1].Date
+		}
+		if i < len(digests)-1 {
+			older = digests[i+1].Date
+		}
+		if err := g.generateDay(d, newer, older); err != nil {
+			return err
+		}
+	}
+
+	if err := g.generateIndex(digests); err != nil {
+		return err
+	}
+	if err := g.generateFeed(summaries); err != nil {
+		return err
+	}
+
+	log.Printf("Generated site for %d days in %s", len(digests), g.outputDir)
+	return nil
+}
+
+// generateDay 生成某一天的页面和该天每个故事的页面
+func (g *Generator) generateDay(d digest, newer, older string) error {
+	data := map[string]any{
+		"PageTitle": fmt.Sprintf("%s - %s", siteTitle, d.Date),
+		"SiteTitle": siteTitle,
+		"Root":      "../",
+		"Date":      d.Date,
+		"Items":     d.Items,
+		"Newer":     newer,
+		"Older":     older,
+	}
+	if err := g.render("day", filepath.Join(d.Date, "index.html"), data); err != nil {
+		return err
+	}
+
+	for _, item := range d.Items {
+		detailed, err := g.store.GetDetailedSummary(item.StoryID, g.model)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to read detailed summary for story %d: %v", item.StoryID, err)
+		}
+
+		data := map[string]any{
+			"PageTitle": fmt.Sprintf("%s - %s", item.Title, siteTitle),
+			"SiteTitle": siteTitle,
+			"Root":      "../",
+			"Date":      d.Date,
+			"Item":      item,
+			"Detailed":  detailed,
+		}
+		if err := g.render("story", filepath.Join(d.Date, fmt.Sprintf("%d.html", item.Number)), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateIndex 生成分页首页：index.html、page/2/index.html ...
+func (g *Generator) generateIndex(digests []digest) error {
+	totalPages := max(1, (len(digests)+g.pageSize-1)/g.pageSize)
+
+	for page := 1; page <= totalPages; page++ {
+		start := (page - 1) * g.pageSize
+		end := min(start+g.pageSize, len(digests))
+
+		root := "../../"
+		if page == 1 {
+			root = ""
+		}
+		var prevPage, nextPage string
+		if page > 1 {
+			prevPage = pagePath(page - 1)
+		}
+		if page < totalPages {
+			nextPage = pagePath(page + 1)
+		}
+
+		data := map[string]any{
+			"PageTitle":  siteTitle,
+			"SiteTitle":  siteTitle,
+			"Root":       root,
+			"Digests":    digests[start:end],
+			"Page":       page,
+			"TotalPages": totalPages,
+			"PrevPage":   prevPage,
+			"NextPage":   nextPage,
+		}
+		if err := g.render("index", pagePath(page), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateFeed 生成最近若干天的 RSS 和 Atom 订阅，每个故事一个条目
+func (g *Generator) generateFeed(summaries []*hackernews.DailySummaryWithNumbers) error {
+	return feed.WriteFiles(g.outputDir, summaries[:min(feedSize, len(summaries))], feed.Options{
+		Title:   siteTitle,
+		BaseURL: g.baseURL,
+	})
+}
+
+// render 渲染模板并写入输出目录下的相对路径
+func (g *Generator) render(name, path string, data any) error {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return fmt.Errorf("failed to render %s: %w", path, err)
+	}
+	return g.write(path, buf.Bytes())
+}
+
+func (g *Generator) write(path string, data []byte) error {
+	fullPath := filepath.Join(g.outputDir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(fullPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// pagePath 返回首页第 page 页的相对路径
+func pagePath(page int) string {
+	if page == 1 {
+		return "index.html"
+	}
+	return fmt.Sprintf("page/%d/index.html", page)
+}
@@ -0,0 +1,94 @@
+package site
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/storage"
+)
+
+func newTestStore(t *testing.T, dates ...string) storage.Store {
+	store := storage.NewMemoryStore()
+	for i, date := range dates {
+		id := i + 1
+		require.NoError(t, store.SaveDailySummary(&hackernews.DailySummaryWithNumbers{
+			Date: date,
+			Stories: []hackernews.Story{{
+				ID:            id,
+				Title:         fmt.Sprintf("Story %d", id),
+				URL:           fmt.Sprintf("https://example.com/%d", id),
+				HackerNewsURL: fmt.Sprintf("https://news.ycombinator.com/item?id=%d", id),
+			}},
+			StorySummaries: []hackernews.StoryWithNumber{{
+				Number:  1,
+				StoryID: id,
+				Title:   fmt.Sprintf("Story %d", id),
+				Summary: fmt.Sprintf("**故事 %d** 总结 <script>", id),
+			}},
+		}))
+	}
+	return store
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestGenerate(t *testing.T) {
+	store := newTestStore(t, "2024-01-13", "2024-01-14", "2024-01-15")
+	require.NoError(t, store.SaveDetailedSummary(3, "", "第三个故事的详细总结"))
+
+	outputDir := t.TempDir()
+	require.NoError(t, NewGenerator(store, outputDir, "https://hn.example.com", 2).Generate())
+
+	// 每日页面，包含前后日期导航
+	day := readFile(t, filepath.Join(outputDir, "2024-01-14", "index.html"))
+	assert.Contains(t, day, "Hacker News 每日热点 - 2024-01-14")
+	assert.Contains(t, day, `<a href="1.html">故事 2</a>`)
+	assert.Contains(t, day, `href="../2024-01-15/index.html"`)
+	assert.Contains(t, day, `href="../2024-01-13/index.html"`)
+	assert.Contains(t, day, "&lt;script&gt;")
+
+	// 故事页面，包含已生成的详细总结
+	story := readFile(t, filepath.Join(outputDir, "2024-01-15", "1.html"))
+	assert.Contains(t, story, `<a href="https://example.com/3">故事 3</a>`)
+	assert.Contains(t, story, "第三个故事的详细总结")
+	assert.NotContains(t, readFile(t, filepath.Join(outputDir, "2024-01-14", "1.html")), "详细总结</h2>")
+
+	// 首页按最新日期在前分页
+	index := readFile(t, filepath.Join(outputDir, "index.html"))
+	assert.Contains(t, index, `href="2024-01-15/1.html"`)
+	assert.Contains(t, index, `href="2024-01-14/index.html"`)
+	assert.NotContains(t, index, "2024-01-13")
+	assert.Contains(t, index, `href="page/2/index.html"`)
+
+	page2 := readFile(t, filepath.Join(outputDir, "page", "2", "index.html"))
+	assert.Contains(t, page2, `href="../../2024-01-13/index.html"`)
+	assert.Contains(t, page2, `href="../../index.html"`)
+
+	// 订阅每个故事一个条目，使用绝对链接
+	atom := readFile(t, filepath.Join(outputDir, "atom.xml"))
+	assert.Equal(t, 3, strings.Count(atom, "<entry>"))
+	assert.Contains(t, atom, `<link href="https://hn.example.com/atom.xml" rel="self">`)
+	assert.Contains(t, atom, `<link href="https://example.com/3" rel="alternate">`)
+	assert.Contains(t, atom, "<updated>2024-01-15T00:00:00Z</updated>")
+	assert.FileExists(t, filepath.Join(outputDir, "rss.xml"))
+}
+
+func TestGenerateEmpty(t *testing.T) {
+	outputDir := t.TempDir()
+	require.NoError(t, NewGenerator(storage.NewMemoryStore(), outputDir, "", 0).Generate())
+
+	assert.FileExists(t, filepath.Join(outputDir, "index.html"))
+	assert.FileExists(t, filepath.Join(outputDir, "atom.xml"))
+	assert.FileExists(t, filepath.Join(outputDir, "rss.xml"))
+}
@@ -0,0 +1,89 @@
+package site
+
+import "html/template"
+
+var templates = template.Must(template.New("site").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.PageTitle}}</title>
+<link rel="alternate" type="application/atom+xml" title="{{.SiteTitle}}" href="{{.Root}}atom.xml">
+<link rel="alternate" type="application/rss+xml" title="{{.SiteTitle}}" href="{{.Root}}rss.xml">
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 760px; margin: 0 auto; padding: 0 16px 48px; line-height: 1.7; color: #222; }
+header { border-bottom: 2px solid #ff6600; margin-bottom: 24px; }
+header a { color: #222; text-decoration: none; }
+a { color: #b34700; }
+.meta { color: #888; font-size: 13px; }
+.story { margin-bottom: 28px; }
+.story h2 { font-size: 18px; margin-bottom: 4px; }
+.digest { margin-bottom: 32px; }
+.digest ol { padding-left: 20px; }
+.tags span { background: #f4f4f4; border-radius: 3px; padding: 0 6px; margin-right: 4px; font-size: 12px; }
+nav.pagination { display: flex; justify-content: space-between; margin-top: 32px; }
+.detailed { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<header><h1><a href="{{.Root}}index.html">🗞️ {{.SiteTitle}}</a></h1></header>
+{{end}}
+
+{{define "footer"}}
+<footer class="meta"><a href="{{.Root}}rss.xml">RSS</a> · <a href="{{.Root}}atom.xml">Atom</a> 订阅</footer>
+</body>
+</html>
+{{end}}
+
+{{define "links"}}<a href="{{.URL}}">原文</a> · <a href="{{.HackerNewsURL}}">HN 讨论</a>{{if .Score}} · {{.Score}} points{{end}}{{end}}
+
+{{define "tags"}}{{if .Tags}}<div class="tags">{{range .Tags}}<span>{{.}}</span>{{end}}</div>{{end}}{{end}}
+
+{{define "index"}}{{template "header" .}}
+{{range .Digests}}{{$date := .Date}}
+<section class="digest">
+  <h2><a href="{{$.Root}}{{$date}}/index.html">{{$date}}</a></h2>
+  <ol>
+  {{range .Items}}<li value="{{.Number}}"><a href="{{$.Root}}{{$date}}/{{.Number}}.html">{{.Title}}</a></li>
+  {{end}}
+  </ol>
+</section>
+{{end}}
+<nav class="pagination">
+  <span>{{if .PrevPage}}<a href="{{.Root}}{{.PrevPage}}">← 较新</a>{{end}}</span>
+  <span class="meta">第 {{.Page}} / {{.TotalPages}} 页</span>
+  <span>{{if .NextPage}}<a href="{{.Root}}{{.NextPage}}">较早 →</a>{{end}}</span>
+</nav>
+{{template "footer" .}}{{end}}
+
+{{define "day"}}{{template "header" .}}
+<h1>Hacker News 每日热点 - {{.Date}}</h1>
+{{range .Items}}
+<article class="story" id="story-{{.Number}}">
+  <h2>[{{.Number}}] <a href="{{.Number}}.html">{{.Title}}</a></h2>
+  {{if ne .Title .OriginalTitle}}<div class="meta">{{.OriginalTitle}}</div>{{end}}
+  <p>{{.Summary}}</p>
+  {{template "tags" .}}
+  <div class="meta">{{template "links" .}}</div>
+</article>
+{{end}}
+<nav class="pagination">
+  <span>{{if .Newer}}<a href="{{.Root}}{{.Newer}}/index.html">← {{.Newer}}</a>{{end}}</span>
+  <span>{{if .Older}}<a href="{{.Root}}{{.Older}}/index.html">{{.Older}} →</a>{{end}}</span>
+</nav>
+{{template "footer" .}}{{end}}
+
+{{define "story"}}{{template "header" .}}
+<article class="story">
+  <div class="meta"><a href="index.html">{{.Date}}</a> · 故事 [{{.Item.Number}}]</div>
+  <h1><a href="{{.Item.URL}}">{{.Item.Title}}</a></h1>
+  {{if ne .Item.Title .Item.OriginalTitle}}<div class="meta">{{.Item.OriginalTitle}}</div>{{end}}
+  <p>{{.Item.Summary}}</p>
+  {{template "tags" .Item}}
+  <div class="meta">{{template "links" .Item}}</div>
+  {{if .Detailed}}<h2>详细总结</h2>
+  <div class="detailed">{{.Detailed}}</div>{{end}}
+</article>
+{{template "footer" .}}{{end}}
+`))
@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"hacker-news-daily/hackernews"
+)
+
+var (
+	summariesBucket       = []byte("daily_summaries")
+	contentsBucket        = []byte("story_contents")
+	detailedSummaryBucket = []byte("detailed_summaries")
+	subscribersBucket     = []byte("subscribers")
+	digestMessagesBucket  = []byte("digest_messages")
+	sentStoriesBucket     = []byte("sent_stories")
+)
+
+// BoltStore 基于 BoltDB 文件的存储实现
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database: %w", err)
+	}
+
+	// 初始化所有 bucket
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{summariesBucket, contentsBucket, detailedSummaryBucket, subscribersBucket, digestMessagesBucket, sentStoriesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize buckets: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// SaveDailySummary 保存某一天的带编号总结
+func (s *BoltStore) SaveDailySummary(summary *hackernews.DailySummaryWithNumbers) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal daily summary: %w", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(summariesBucket)
+		if err := bucket.Put([]byte(summary.Key()), data); err != nil {
+			return err
+		}
+		if summary.Revision == "" {
+			return nil
+		}
+		if err := bucket.Put([]byte(summary.SnapshotKey()), data); err != nil {
+			return err
+		}
+
+		// 游标按键有序遍历，生成批次按时间递增，先收集再删除以免遍历时修改桶
+		prefix := []byte(snapshotPrefix(summary))
+		var snapshots []string
+		c := bucket.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			snapshots = append(snapshots, string(k))
+		}
+		for _, key := range staleSnapshots(snapshots) {
+			if err := bucket.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save daily summary: %w", err)
+	}
+	return nil
+}
+
+// GetDailySummary 按存储键获取带编号总结
+func (s *BoltStore) GetDailySummary(key string) (*hackernews.DailySummaryWithNumbers, error) {
+	data, err := s.get(summariesBucket, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+
+	var summary hackernews.DailySummaryWithNumbers
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal daily summary: %w", err)
+	}
+	return &summary, nil
+}
+
+// ListDates 按日期升序列出所有已保存的默认每日总结的日期，bolt 的键按字节序排列
+func (s *BoltStore) ListDates() ([]string, error) {
+	var dates []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(summariesBucket).ForEach(func(key, _ []byte) error {
+			if isDate(string(key)) {
+				dates = append(dates, string(key))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dates: %w", err)
+	}
+	return dates, nil
+}
+
+// SaveStoryContent 保存故事的原始内容
+func (s *BoltStore) SaveStoryContent(storyID int, content string) error {
+	return s.put(contentsBucket, storyKey(storyID), []byte(content))
+}
+
+// GetStoryContent 获取故事的原始内容
+func (s *BoltStore) GetStoryContent(storyID int) (string, error) {
+	data, err := s.get(contentsBucket, storyKey(storyID))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SaveDetailedSummary 保存 model 生成的故事详细总结
+func (s *BoltStore) SaveDetailedSummary(storyID int, model, summary string) error {
+	return s.put(detailedSummaryBucket, detailedSummaryKey(storyID, model), []byte(summary))
+}
+
+// GetDetailedSummary 获取 model 生成的故事详细总结
+func (s *BoltStore) GetDetailedSummary(storyID int, model string) (string, error) {
+	data, err := s.get(detailedSummaryBucket, detailedSummaryKey(storyID, model))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SaveSubscriber 保存订阅者及其偏好
+func (s *BoltStore) SaveSubscriber(subscriber *Subscriber) error {
+	data, err := json.Marshal(subscriber)
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscriber: %w", err)
+	}
+	return s.put(subscribersBucket, chatKey(subscriber.ChatID), data)
+}
+
+// GetSubscriber 获取订阅者
+func (s *BoltStore) GetSubscriber(chatID int64) (*Subscriber, error) {
+	data, err := s.get(subscribersBucket, chatKey(chatID))
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriber Subscriber
+	if err := json.Unmarshal(data, &subscriber); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal subscriber: %w", err)
+	}
+	return &subscriber, nil
+}
+
+// DeleteSubscriber 删除订阅者
+func (s *BoltStore) DeleteSubscriber(chatID int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(subscribersBucket)
+		if bucket.Get(chatKey(chatID)) == nil {
+			return ErrNotFound
+		}
+		return bucket.Delete(chatKey(chatID))
+	})
+}
+
+// ListSubscribers 按订阅时间列出所有订阅者
+func (s *BoltStore) ListSubscribers() ([]*Subscriber, error) {
+	var subscribers []*Subscriber
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscribersBucket).ForEach(func(_, value []byte) error {
+			var subscriber Subscriber
+			if err := json.Unmarshal(value, &subscriber); err != nil {
+				return fmt.Errorf("failed to unmarshal subscriber: %w", err)
+			}
+			subscribers = append(subscribers, &subscriber)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortSubscribers(subscribers)
+	return subscribers, nil
+}
+
+// SaveDigestMessage 记录消息对应的总结存储键
+func (s *BoltStore) SaveDigestMessage(chatID int64, messageID int, key string) error {
+	return s.put(digestMessagesBucket, digestMessageKey(chatID, messageID), []byte(key))
+}
+
+// GetDigestMessage 获取消息对应的总结存储键
+func (s *BoltStore) GetDigestMessage(chatID int64, messageID int) (string, error) {
+	data, err := s.get(digestMessagesBucket, digestMessageKey(chatID, messageID))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SaveSentStories 在一个事务中记录发送到聊天的故事
+func (s *BoltStore) SaveSentStories(chatID int64, stories []SentStory) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sentStoriesBucket)
+		for _, story := range stories {
+			data, err := json.Marshal(story)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(sentStoryKey(chatID, story.StoryID), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", sentStoriesBucket, err)
+	}
+	return nil
+}
+
+// GetSentStories 返回聊天中发送过的故事
+func (s *BoltStore) GetSentStories(chatID int64, storyIDs []int) (map[int]SentStory, error) {
+	result := make(map[int]SentStory)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sentStoriesBucket)
+		for _, id := range storyIDs {
+			data := bucket.Get(sentStoryKey(chatID, id))
+			if data == nil {
+				continue
+			}
+			var story SentStory
+			if err := json.Unmarshal(data, &story); err != nil {
+				return err
+			}
+			result[id] = story
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", sentStoriesBucket, err)
+	}
+	return result, nil
+}
+
+// Close 关闭数据库文件
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) put(bucket, key, value []byte) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", bucket, err)
+	}
+	return nil
+}
+
+func (s *BoltStore) get(bucket, key []byte) ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucket).Get(key)
+		if value == nil {
+			return ErrNotFound
+		}
+		// bolt 返回的切片只在事务内有效，需要复制
+		data = append([]byte(nil), value...)
+		return nil
+	})
+	return data, err
+}
+
+func storyKey(storyID int) []byte {
+	return []byte(strconv.Itoa(storyID))
+}
+
+// detailedSummaryKey 详细总结的键为 模型:故事ID，未指定模型时与旧数据一样只使用故事 ID
+func detailedSummaryKey(storyID int, model string) []byte {
+	if model == "" {
+		return storyKey(storyID)
+	}
+	return []byte(model + ":" + strconv.Itoa(storyID))
+}
+
+func chatKey(chatID int64) []byte {
+	return []byte(strconv.FormatInt(chatID, 10))
+}
+
+func digestMessageKey(chatID int64, messageID int) []byte {
+	return []byte(strconv.FormatInt(chatID, 10) + ":" + strconv.Itoa(messageID))
+}
+
+func sentStoryKey(chatID int64, storyID int) []byte {
+	return []byte(strconv.FormatInt(chatID, 10) + ":" + strconv.Itoa(storyID))
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"hacker-news-daily/hackernews"
+)
+
+// MemoryStore 基于内存的存储实现，进程重启后数据丢失，主要用于测试
+type MemoryStore struct {
+	mu              sync.RWMutex
+	summaries       map[string]*hackernews.DailySummaryWithNumbers
+	contents        map[int]string
+	detailedSummary map[detailedKey]string
+	subscribers     map[int64]*Subscriber
+	digestMessages  map[messageKey]string
+	sentStories     map[int64]map[int]SentStory
+}
+
+// detailedKey 某个模型生成的故事详细总结
+type detailedKey struct {
+	storyID int
+	model   string
+}
+
+// messageKey 聊天中一条消息的唯一标识
+type messageKey struct {
+	chatID    int64
+	messageID int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		summaries:       make(map[string]*hackernews.DailySummaryWithNumbers),
+		contents:        make(map[int]string),
+		detailedSummary: make(map[detailedKey]string),
+		subscribers:     make(map[int64]*Subscriber),
+		digestMessages:  make(map[messageKey]string),
+		sentStories:     make(map[int64]map[int]SentStory),
+	}
+}
+
+// SaveDailySummary 保存某一天的带编号总结
+func (s *MemoryStore) SaveDailySummary(summary *hackernews.DailySummaryWithNumbers) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summaries[summary.Key()] = summary
+	if summary.Revision == "" {
+		return nil
+	}
+	s.summaries[summary.SnapshotKey()] = summary
+
+	// 生成批次按时间递增，按键排序即为生成顺序
+	prefix := snapshotPrefix(summary)
+	var snapshots []string
+	for key := range s.summaries {
+		if strings.HasPrefix(key, prefix) {
+			snapshots = append(snapshots, key)
+		}
+	}
+	sort.Strings(snapshots)
+	for _, key := range staleSnapshots(snapshots) {
+		delete(s.summaries, key)
+	}
+	return nil
+}
+
+// GetDailySummary 按存储键获取带编号总结
+func (s *MemoryStore) GetDailySummary(key string) (*hackernews.DailySummaryWithNumbers, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	summary, ok := s.summaries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return summary, nil
+}
+
+// ListDates 按日期升序列出所有已保存的默认每日总结的日期
+func (s *MemoryStore) ListDates() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dates := make([]string, 0, len(s.summaries))
+	for key := range s.summaries {
+		if isDate(key) {
+			dates = append(dates, key)
+		}
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+// SaveStoryContent 保存故事的原始内容
+func (s *MemoryStore) SaveStoryContent(storyID int, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contents[storyID] = content
+	return nil
+}
+
+// GetStoryContent 获取故事的原始内容
+func (s *MemoryStore) GetStoryContent(storyID int) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	content, ok := s.contents[storyID]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return content, nil
+}
+
+// SaveDetailedSummary 保存 model 生成的故事详细总结
+func (s *MemoryStore) SaveDetailedSummary(storyID int, model, summary string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.detailedSummary[detailedKey{storyID: storyID, model: model}] = summary
+	return nil
+}
+
+// GetDetailedSummary 获取 model 生成的故事详细总结
+func (s *MemoryStore) GetDetailedSummary(storyID int, model string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	summary, ok := s.detailedSummary[detailedKey{storyID: storyID, model: model}]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return summary, nil
+}
+
+// SaveSubscriber 保存订阅者及其偏好
+func (s *MemoryStore) SaveSubscriber(subscriber *Subscriber) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *subscriber
+	s.subscribers[subscriber.ChatID] = &copied
+	return nil
+}
+
+// GetSubscriber 获取订阅者
+func (s *MemoryStore) GetSubscriber(chatID int64) (*Subscriber, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subscriber, ok := s.subscribers[chatID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *subscriber
+	return &copied, nil
+}
+
+// DeleteSubscriber 删除订阅者
+func (s *MemoryStore) DeleteSubscriber(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[chatID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.subscribers, chatID)
+	return nil
+}
+
+// ListSubscribers 按订阅时间列出所有订阅者
+func (s *MemoryStore) ListSubscribers() ([]*Subscriber, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subscribers := make([]*Subscriber, 0, len(s.subscribers))
+	for _, subscriber := range s.subscribers {
+		copied := *subscriber
+		subscribers = append(subscribers, &copied)
+	}
+	sortSubscribers(subscribers)
+	return subscribers, nil
+}
+
+// SaveDigestMessage 记录消息对应的总结存储键
+func (s *MemoryStore) SaveDigestMessage(chatID int64, messageID int, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.digestMessages[messageKey{chatID: chatID, messageID: messageID}] = key
+	return nil
+}
+
+// GetDigestMessage 获取消息对应的总结存储键
+func (s *MemoryStore) GetDigestMessage(chatID int64, messageID int) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.digestMessages[messageKey{chatID: chatID, messageID: messageID}]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return key, nil
+}
+
+// SaveSentStories 记录发送到聊天的故事
+func (s *MemoryStore) SaveSentStories(chatID int64, stories []SentStory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent, ok := s.sentStories[chatID]
+	if !ok {
+		sent = make(map[int]SentStory)
+		s.sentStories[chatID] = sent
+	}
+	for _, story := range stories {
+		sent[story.StoryID] = story
+	}
+	return nil
+}
+
+// GetSentStories 返回聊天中发送过的故事
+func (s *MemoryStore) GetSentStories(chatID int64, storyIDs []int) (map[int]SentStory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make(map[int]SentStory)
+	for _, id := range storyIDs {
+		if story, ok := s.sentStories[chatID][id]; ok {
+			result[id] = story
+		}
+	}
+	return result, nil
+}
+
+// Close 内存存储无需释放资源
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"hacker-news-daily/hackernews"
+)
+
+// ErrNotFound 表示请求的记录不存在
+var ErrNotFound = errors.New("record not found")
+
+// maxSnapshots 每个总结保留的最近生成批次数量，更早批次的快照在保存新总结时删除，
+// 避免按分钟推送和重新生成时快照无限增长
+const maxSnapshots = 10
+
+// Subscriber 订阅每日总结的聊天及其推送偏好
+type Subscriber struct {
+	ChatID       int64    `json:"chat_id"`
+	Title        string   `json:"title"`         // 群组名称或用户名
+	DeliveryTime string   `json:"delivery_time"` // 每日推送时间（HH:MM），为空时跟随默认定时任务
+	MaxStories   int      `json:"max_stories"`   // 推送的故事数量，0 表示全部
+	Language     string   `json:"language"`      // 总结语言，为空时使用中文原文
+	Keywords     []string `json:"keywords"`      // 只推送匹配关键词的故事，为空时不过滤
+	CreatedAt    int64    `json:"created_at"`
+}
+
+// SentStory 已发送到聊天的故事，用于识别之后摘要中重复出现的故事
+type SentStory struct {
+	StoryID int   `json:"story_id"`
+	Score   int   `json:"score"`   // 发送时的分数
+	SentAt  int64 `json:"sent_at"` // 最近一次发送的时间
+}
+
+// Store 每日总结的持久化存储接口
+type Store interface {
+	// SaveDailySummary 以 summary.Key() 保存带编号总结，有生成批次时同时以 summary.SnapshotKey() 保存，
+	// 并且只保留同一总结最近 maxSnapshots 个生成批次的快照
+	SaveDailySummary(summary *hackernews.DailySummaryWithNumbers) error
+	// GetDailySummary 按日期获取每日总结，或按 "名称/日期" 获取其他摘要，
+	// 也可以按 SnapshotKey 获取某一次生成的总结，不存在时返回 ErrNotFound
+	GetDailySummary(key string) (*hackernews.DailySummaryWithNumbers, error)
+	// ListDates 按日期升序列出所有已保存的默认每日总结的日期，不包括其他摘要
+	ListDates() ([]string, error)
+
+	// SaveStoryContent 保存故事的原始内容（正文和评论）
+	SaveStoryContent(storyID int, content string) error
+	// GetStoryContent 获取故事的原始内容，不存在时返回 ErrNotFound
+	GetStoryContent(storyID int) (string, error)
+
+	// SaveDetailedSummary 保存 model 生成的故事详细总结，不同模型的结果分开保存
+	SaveDetailedSummary(storyID int, model, summary string) error
+	// GetDetailedSummary 获取 model 生成的故事详细总结，不存在时返回 ErrNotFound
+	GetDetailedSummary(storyID int, model string) (string, error)
+
+	// SaveSubscriber 保存订阅者及其偏好
+	SaveSubscriber(subscriber *Subscriber) error
+	// GetSubscriber 获取订阅者，不存在时返回 ErrNotFound
+	GetSubscriber(chatID int64) (*Subscriber, error)
+	// DeleteSubscriber 删除订阅者，不存在时返回 ErrNotFound
+	DeleteSubscriber(chatID int64) error
+	// ListSubscribers 按订阅时间列出所有订阅者
+	ListSubscribers() ([]*Subscriber, error)
+
+	// SaveDigestMessage 记录聊天中某条消息对应的总结存储键
+	SaveDigestMessage(chatID int64, messageID int, key string) error
+	// GetDigestMessage 获取消息对应的总结存储键，不存在时返回 ErrNotFound
+	GetDigestMessage(chatID int64, messageID int) (string, error)
+
+	// SaveSentStories 记录发送到聊天的故事，覆盖同一故事之前的记录
+	SaveSentStories(chatID int64, stories []SentStory) error
+	// GetSentStories 按故事 ID 返回聊天中发送过的故事，未发送过的故事不在结果中
+	GetSentStories(chatID int64, storyIDs []int) (map[int]SentStory, error)
+
+	// Close 释放底层资源
+	Close() error
+}
+
+// sortSubscribers 按订阅时间排序，时间相同时按 chatID 排序
+func sortSubscribers(subscribers []*Subscriber) {
+	sort.Slice(subscribers, func(i, j int) bool {
+		if subscribers[i].CreatedAt != subscribers[j].CreatedAt {
+			return subscribers[i].CreatedAt < subscribers[j].CreatedAt
+		}
+		return subscribers[i].ChatID < subscribers[j].ChatID
+	})
+}
+
+// snapshotPrefix 返回总结各生成批次快照存储键的公共前缀
+func snapshotPrefix(summary *hackernews.DailySummaryWithNumbers) string {
+	return summary.Key() + "@"
+}
+
+// staleSnapshots 返回按生成顺序排列的快照键中超出保留数量的较早部分
+func staleSnapshots(keys []string) []string {
+	if len(keys) <= maxSnapshots {
+		return nil
+	}
+	return keys[:len(keys)-maxSnapshots]
+}
+
+// isDate 判断总结的存储键是否为默认每日总结的日期，其他摘要的键包含名称，
+// 按日期范围生成的总结键为 "起始日期~结束日期"
+func isDate(key string) bool {
+	_, err := time.Parse("2006-01-02", key)
+	return err == nil
+}
+
+// Open 根据存储类型创建对应的 Store
+func Open(storeType, path string) (Store, error) {
+	switch storeType {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "bolt":
+		if path == "" {
+			return nil, fmt.Errorf("storage path is required for bolt store")
+		}
+		return NewBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", storeType)
+	}
+}
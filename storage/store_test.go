@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/hackernews"
+)
+
+// TestStores 对所有存储实现运行相同的测试用例
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store {
+			return NewMemoryStore()
+		},
+		"bolt": func(t *testing.T) Store {
+			store, err := NewBoltStore(filepath.Join(t.TempDir(), "data", "hnd.db"))
+			require.NoError(t, err)
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+
+			// 不存在的记录返回 ErrNotFound
+			_, err := store.GetDailySummary("2024-01-15")
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = store.GetStoryContent(1)
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = store.GetDetailedSummary(1, "")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			summary := &hackernews.DailySummaryWithNumbers{
+				Date:    "2024-01-15",
+				Stories: []hackernews.Story{{ID: 1, Title: "Go 1.22 发布"}},
+				StorySummaries: []hackernews.StoryWithNumber{
+					{Number: 1, StoryID: 1, Title: "Go 1.22 发布", Summary: "新版本带来了循环变量语义变更"},
+				},
+			}
+			require.NoError(t, store.SaveDailySummary(summary))
+			require.NoError(t, store.SaveStoryContent(1, "标题: Go 1.22 发布"))
+			require.NoError(t, store.SaveDetailedSummary(1, "", "详细总结"))
+
+			got, err := store.GetDailySummary("2024-01-15")
+			require.NoError(t, err)
+			assert.Equal(t, summary, got)
+
+			require.NoError(t, store.SaveDailySummary(&hackernews.DailySummaryWithNumbers{Date: "2024-01-02"}))
+
+			// 其他摘要和按日期范围生成的总结与同一天的每日总结分开保存，不出现在日期列表中
+			showHN := &hackernews.DailySummaryWithNumbers{Date: "2024-01-15", Digest: "show_hn", Title: "Show HN 每周精选"}
+			require.NoError(t, store.SaveDailySummary(showHN))
+			require.NoError(t, store.SaveDailySummary(&hackernews.DailySummaryWithNumbers{Date: "2024-01-01~2024-01-07"}))
+			got, err = store.GetDailySummary("show_hn/2024-01-15")
+			require.NoError(t, err)
+			assert.Equal(t, showHN, got)
+			got, err = store.GetDailySummary("2024-01-15")
+			require.NoError(t, err)
+			assert.Equal(t, summary, got)
+
+			// 重新生成后按日期读取最新的总结，按生成批次仍能读取之前的总结
+			first := &hackernews.DailySummaryWithNumbers{Date: "2024-01-20", Revision: "a"}
+			second := &hackernews.DailySummaryWithNumbers{Date: "2024-01-20", Revision: "b"}
+			require.NoError(t, store.SaveDailySummary(first))
+			require.NoError(t, store.SaveDailySummary(second))
+			got, err = store.GetDailySummary("2024-01-20@a")
+			require.NoError(t, err)
+			assert.Equal(t, first, got)
+			got, err = store.GetDailySummary("2024-01-20")
+			require.NoError(t, err)
+			assert.Equal(t, second, got)
+
+			// 只保留最近 maxSnapshots 个生成批次的快照
+			for i := 0; i < maxSnapshots; i++ {
+				revision := fmt.Sprintf("c%02d", i)
+				require.NoError(t, store.SaveDailySummary(&hackernews.DailySummaryWithNumbers{Date: "2024-01-20", Revision: revision}))
+			}
+			_, err = store.GetDailySummary("2024-01-20@a")
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = store.GetDailySummary("2024-01-20@b")
+			assert.ErrorIs(t, err, ErrNotFound)
+			got, err = store.GetDailySummary("2024-01-20@c00")
+			require.NoError(t, err)
+			assert.Equal(t, "c00", got.Revision)
+			got, err = store.GetDailySummary("2024-01-20")
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("c%02d", maxSnapshots-1), got.Revision)
+
+			dates, err := store.ListDates()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"2024-01-02", "2024-01-15", "2024-01-20"}, dates)
+
+			content, err := store.GetStoryContent(1)
+			require.NoError(t, err)
+			assert.Equal(t, "标题: Go 1.22 发布", content)
+
+			detailed, err := store.GetDetailedSummary(1, "")
+			require.NoError(t, err)
+			assert.Equal(t, "详细总结", detailed)
+
+			// 不同模型生成的详细总结分开保存
+			_, err = store.GetDetailedSummary(1, "openai/gpt-4o")
+			assert.ErrorIs(t, err, ErrNotFound)
+			require.NoError(t, store.SaveDetailedSummary(1, "openai/gpt-4o", "新模型的详细总结"))
+			detailed, err = store.GetDetailedSummary(1, "openai/gpt-4o")
+			require.NoError(t, err)
+			assert.Equal(t, "新模型的详细总结", detailed)
+			detailed, err = store.GetDetailedSummary(1, "")
+			require.NoError(t, err)
+			assert.Equal(t, "详细总结", detailed)
+
+			testSubscribers(t, store)
+
+			// 消息与每日总结日期的对应关系按聊天区分
+			_, err = store.GetDigestMessage(100, 1)
+			assert.ErrorIs(t, err, ErrNotFound)
+			require.NoError(t, store.SaveDigestMessage(100, 1, "2024-01-15"))
+			require.NoError(t, store.SaveDigestMessage(-200, 1, "2024-01-02"))
+			date, err := store.GetDigestMessage(100, 1)
+			require.NoError(t, err)
+			assert.Equal(t, "2024-01-15", date)
+			date, err = store.GetDigestMessage(-200, 1)
+			require.NoError(t, err)
+			assert.Equal(t, "2024-01-02", date)
+
+			// 已发送的故事按聊天记录，再次发送时覆盖
+			require.NoError(t, store.SaveSentStories(100, []SentStory{{StoryID: 1, Score: 10, SentAt: 1}, {StoryID: 2, Score: 20, SentAt: 1}}))
+			require.NoError(t, store.SaveSentStories(100, []SentStory{{StoryID: 1, Score: 50, SentAt: 2}}))
+			sent, err := store.GetSentStories(100, []int{1, 2, 3})
+			require.NoError(t, err)
+			assert.Equal(t, map[int]SentStory{1: {StoryID: 1, Score: 50, SentAt: 2}, 2: {StoryID: 2, Score: 20, SentAt: 1}}, sent)
+			sent, err = store.GetSentStories(-200, []int{1, 2})
+			require.NoError(t, err)
+			assert.Empty(t, sent)
+		})
+	}
+}
+
+// testSubscribers 测试订阅者的增删改查
+func testSubscribers(t *testing.T, store Store) {
+	_, err := store.GetSubscriber(100)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, store.DeleteSubscriber(100), ErrNotFound)
+
+	subscriber := &Subscriber{ChatID: 100, Title: "team", DeliveryTime: "09:00", MaxStories: 5, Keywords: []string{"go"}, CreatedAt: 2}
+	require.NoError(t, store.SaveSubscriber(subscriber))
+	require.NoError(t, store.SaveSubscriber(&Subscriber{ChatID: -200, Title: "group", CreatedAt: 1}))
+
+	got, err := store.GetSubscriber(100)
+	require.NoError(t, err)
+	assert.Equal(t, subscriber, got)
+
+	subscribers, err := store.ListSubscribers()
+	require.NoError(t, err)
+	require.Len(t, subscribers, 2)
+	assert.Equal(t, int64(-200), subscribers[0].ChatID)
+	assert.Equal(t, int64(100), subscribers[1].ChatID)
+
+	require.NoError(t, store.DeleteSubscriber(-200))
+	subscribers, err = store.ListSubscribers()
+	require.NoError(t, err)
+	assert.Len(t, subscribers, 1)
+}
+
+// TestBoltStorePersistence 测试重新打开数据库后数据仍然存在
+func TestBoltStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hnd.db")
+
+	store, err := NewBoltStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveDailySummary(&hackernews.DailySummaryWithNumbers{Date: "2024-01-15"}))
+	require.NoError(t, store.Close())
+
+	reopened, err := NewBoltStore(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	summary, err := reopened.GetDailySummary("2024-01-15")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-15", summary.Date)
+}
+
+func TestOpen(t *testing.T) {
+	store, err := Open("memory", "")
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryStore{}, store)
+
+	_, err = Open("bolt", "")
+	assert.Error(t, err)
+
+	_, err = Open("redis", "")
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package telegram
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,23 +15,44 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"hacker-news-daily/ai"
 	"hacker-news-daily/hackernews"
+	"hacker-news-daily/publisher"
+	"hacker-news-daily/retry"
+	"hacker-news-daily/storage"
 )
 
+// httpTimeout Telegram API 请求的超时时间，需要大于获取更新时 60 秒的长轮询
+const httpTimeout = 90 * time.Second
+
+// maxConcurrentHandlers 同时处理的消息和按钮回调数量上限，超出时后续更新排队等待
+const maxConcurrentHandlers = 8
+
 type Bot struct {
 	api            *tgbotapi.BotAPI
 	chatID         int64
-	aiClient       *ai.Client
+	aiClient       ai.Summarizer
+	summaryModel   string // 存储详细总结时使用的模型标识，切换模型后不复用旧结果
 	hnClient       *hackernews.Client
-	storySummaries map[string]*hackernews.DailySummaryWithNumbers // 按日期存储的故事总结
-	mu             sync.RWMutex                                   // 读写锁保护共享数据
-	messageHandler chan tgbotapi.Update                           // 消息处理通道
-	stopHandler    chan struct{}                                  // 停止处理器通道
-	maxStories     int                                            // 最大故事数量配置
+	store          storage.Store                 // 故事总结、原始内容和详细总结的存储
+	messageHandler chan tgbotapi.Update          // 消息处理通道
+	stopHandler    chan struct{}                 // 停止处理器通道
+	handlerDone    chan struct{}                 // 消息分发协程退出后关闭
+	handlers       sync.WaitGroup                // 正在处理的消息和按钮回调
+	handlerSlots   chan struct{}                 // 限制同时处理的更新数量
+	maxStories     int                           // 最大故事数量配置
+	publishers     []publisher.Publisher         // Telegram 之外的输出渠道
+	retrier        *retry.Retrier                // 发送消息的重试和熔断
+	timeouts       StageTimeouts                 // 每日总结各阶段的超时时间
+	source         hackernews.StorySource        // 每日总结的故事来源，为空时使用首页故事
+	dedup          DedupPolicy                   // 同一聊天中重复出现的故事的处理方式
+	subscription   SubscriptionPolicy            // 哪些聊天可以订阅
+	approvals      map[int64]subscriptionRequest // 等待管理员批准的订阅请求
+	approvalsMu    sync.Mutex
+	scheduledMu    sync.Mutex // 按订阅者推送时间发送时，同时只生成一次当日总结
 }
 
 func NewBot(token, chatIDStr, proxyURL string, maxStories int) (*Bot, error) {
-	var bot *tgbotapi.BotAPI
-	var err error
+	// 设置请求超时，避免网络异常时请求一直挂起
+	client := &http.Client{Timeout: httpTimeout}
 
 	// 如果配置了代理，使用代理创建 bot
 	if proxyURL != "" {
@@ -38,23 +61,15 @@ func NewBot(token, chatIDStr, proxyURL string, maxStories int) (*Bot, error) {
 			return nil, fmt.Errorf("invalid proxy URL: %w", err)
 		}
 
-		client := &http.Client{
-			Transport: &http.Transport{
-				Proxy: http.ProxyURL(proxyURLParsed),
-			},
+		client.Transport = &http.Transport{
+			Proxy: http.ProxyURL(proxyURLParsed),
 		}
-
-		bot, err = tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, client)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create telegram bot with proxy: %w", err)
-		}
-
 		log.Printf("Telegram bot using proxy: %s", proxyURL)
-	} else {
-		bot, err = tgbotapi.NewBotAPI(token)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create telegram bot: %w", err)
-		}
+	}
+
+	bot, err := tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telegram bot: %w", err)
 	}
 
 	chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
@@ -67,196 +82,194 @@ func NewBot(token, chatIDStr, proxyURL string, maxStories int) (*Bot, error) {
 	return &Bot{
 		api:            bot,
 		chatID:         chatID,
-		storySummaries: make(map[string]*hackernews.DailySummaryWithNumbers),
+		store:          storage.NewMemoryStore(),
 		messageHandler: make(chan tgbotapi.Update, 100),
 		stopHandler:    make(chan struct{}),
+		handlerSlots:   make(chan struct{}, maxConcurrentHandlers),
 		maxStories:     maxStories,
+		retrier:        retry.New("telegram", retry.Config{}),
+		timeouts:       StageTimeouts{}.withDefaults(),
 	}, nil
 }
 
-// SendDailySummary 发送每日总结
-func (b *Bot) SendDailySummary(date, summary string) error {
-	// Telegram 消息长度限制为 4096 字符
-	const maxMessageLength = 4000
-
-	title := fmt.Sprintf("🗞️ Hacker News 每日热点 - %s", date)
+// SetRetryConfig 设置发送消息失败时的重试和熔断策略
+func (b *Bot) SetRetryConfig(config retry.Config) {
+	b.retrier = retry.New("telegram", config)
+}
 
-	// 如果消息太长，需要分割发送
-	if len(summary) <= maxMessageLength-len(title)-20 {
-		message := fmt.Sprintf("%s\n\n%s", title, summary)
-		return b.sendMessage(message)
-	}
+// sendMessage 向默认聊天发送单条消息
+func (b *Bot) sendMessage(ctx context.Context, text string) error {
+	return b.sendMessageTo(ctx, b.chatID, text)
+}
 
-	// 发送标题
-	if err := b.sendMessage(title); err != nil {
-		return err
-	}
+// sendMessageTo 向指定聊天发送单条纯文本消息
+func (b *Bot) sendMessageTo(ctx context.Context, chatID int64, text string) error {
+	_, err := b.send(ctx, tgbotapi.NewMessage(chatID, text))
+	return err
+}
 
-	// 分割内容发送
-	return b.sendLongMessage(summary, maxMessageLength)
+// sendHTMLTo 向指定聊天发送单条 HTML 格式消息
+func (b *Bot) sendHTMLTo(ctx context.Context, chatID int64, text string) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	_, err := b.send(ctx, msg)
+	return err
 }
 
-// sendMessage 发送单条消息
-func (b *Bot) sendMessage(text string) error {
-	msg := tgbotapi.NewMessage(b.chatID, text)
-	// 移除Markdown格式设置，避免解析错误
-	// msg.ParseMode = tgbotapi.ModeMarkdown
+// send 发送消息并返回已发送的消息，Telegram 无法解析格式实体时降级为纯文本重新发送
+func (b *Bot) send(ctx context.Context, msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
 	msg.DisableWebPagePreview = true
 
-	_, err := b.api.Send(msg)
+	sent, err := b.sendWithRetry(ctx, msg)
+	if err != nil && msg.ParseMode != "" && isParseError(err) {
+		log.Printf("Telegram failed to parse formatted message, falling back to plain text: %v", err)
+		msg.Text = plainText(msg.Text)
+		msg.ParseMode = ""
+		sent, err = b.sendWithRetry(ctx, msg)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to send telegram message: %w", err)
+		return tgbotapi.Message{}, fmt.Errorf("failed to send telegram message: %w", err)
 	}
 
-	return nil
+	return sent, nil
 }
 
-// sendLongMessage 发送长消息（分割发送）
-func (b *Bot) sendLongMessage(text string, maxLength int) error {
-	if len(text) <= maxLength {
-		return b.sendMessage(text)
-	}
-
-	// 按段落分割
-	paragraphs := strings.Split(text, "\n\n")
-	var currentMessage strings.Builder
-
-	for _, paragraph := range paragraphs {
-		// 如果单个段落就超过长度限制，需要进一步分割
-		if len(paragraph) > maxLength {
-			if currentMessage.Len() > 0 {
-				if err := b.sendMessage(currentMessage.String()); err != nil {
-					return err
-				}
-				currentMessage.Reset()
-			}
-
-			// 按句子分割长段落
-			if err := b.sendVeryLongParagraph(paragraph, maxLength); err != nil {
-				return err
-			}
-			continue
+// sendWithRetry 发送消息，被限流时按 Telegram 返回的 retry_after 等待后重试；
+// 发送消息不是幂等操作，其他错误只在连接未建立时重试，避免重复发送
+func (b *Bot) sendWithRetry(ctx context.Context, msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+	var sent tgbotapi.Message
+	err := b.retrier.Do(ctx, func() error {
+		var err error
+		sent, err = b.api.Send(msg)
+		if err == nil {
+			return nil
 		}
 
-		// 检查加入当前段落后是否超长
-		if currentMessage.Len()+len(paragraph)+2 > maxLength {
-			if currentMessage.Len() > 0 {
-				if err := b.sendMessage(currentMessage.String()); err != nil {
-					return err
-				}
-				currentMessage.Reset()
+		var tgErr *tgbotapi.Error
+		if errors.As(err, &tgErr) {
+			if tgErr.Code == http.StatusTooManyRequests {
+				return retry.Retryable(err, time.Duration(tgErr.RetryAfter)*time.Second)
 			}
+			return err
 		}
-
-		if currentMessage.Len() > 0 {
-			currentMessage.WriteString("\n\n")
-		}
-		currentMessage.WriteString(paragraph)
-	}
-
-	// 发送剩余内容
-	if currentMessage.Len() > 0 {
-		return b.sendMessage(currentMessage.String())
-	}
-
-	return nil
+		return retry.Network(err, false)
+	})
+	return sent, err
 }
 
-// sendVeryLongParagraph 发送超长段落
-func (b *Bot) sendVeryLongParagraph(paragraph string, maxLength int) error {
-	// 按句子分割
-	sentences := strings.Split(paragraph, "。")
-	var currentMessage strings.Builder
-
-	for i, sentence := range sentences {
-		if i < len(sentences)-1 {
-			sentence += "。"
+// sendLongMessage 发送 HTML 格式的长消息，超过 Telegram 长度限制时分割为多条发送，
+// keyboard 非空时附加在最后一条消息上，返回已发送消息的 ID
+func (b *Bot) sendLongMessage(ctx context.Context, chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) ([]int, error) {
+	parts := splitMessage(text, maxMessageLength)
+	messageIDs := make([]int, 0, len(parts))
+	for i, part := range parts {
+		msg := tgbotapi.NewMessage(chatID, part)
+		msg.ParseMode = tgbotapi.ModeHTML
+		if keyboard != nil && i == len(parts)-1 {
+			msg.ReplyMarkup = *keyboard
 		}
-
-		if currentMessage.Len()+len(sentence) > maxLength {
-			if currentMessage.Len() > 0 {
-				if err := b.sendMessage(currentMessage.String()); err != nil {
-					return err
-				}
-				currentMessage.Reset()
-			}
+		sent, err := b.send(ctx, msg)
+		if err != nil {
+			return messageIDs, err
 		}
-
-		currentMessage.WriteString(sentence)
-	}
-
-	if currentMessage.Len() > 0 {
-		return b.sendMessage(currentMessage.String())
+		messageIDs = append(messageIDs, sent.MessageID)
 	}
-
-	return nil
+	return messageIDs, nil
 }
 
 // SendError 发送错误消息
-func (b *Bot) SendError(errorMsg string) error {
+func (b *Bot) SendError(ctx context.Context, errorMsg string) error {
 	message := fmt.Sprintf("❌ 错误: %s", errorMsg)
-	return b.sendMessage(message)
+	return b.sendMessage(ctx, message)
 }
 
 // SetClients 设置AI和Hacker News客户端
-func (b *Bot) SetClients(aiClient *ai.Client, hnClient *hackernews.Client) {
+func (b *Bot) SetClients(aiClient ai.Summarizer, hnClient *hackernews.Client) {
 	b.aiClient = aiClient
 	b.hnClient = hnClient
 }
 
-// SendDailySummaryWithNumbers 发送带编号的每日总结
-func (b *Bot) SendDailySummaryWithNumbers(summary *hackernews.DailySummaryWithNumbers) error {
-	// 保存总结到内存中供后续查询
-	b.mu.Lock()
-	b.storySummaries[summary.Date] = summary
-	b.mu.Unlock()
+// SetSummaryModel 设置生成详细总结的模型标识，已存储的详细总结只在模型一致时复用
+func (b *Bot) SetSummaryModel(model string) {
+	b.summaryModel = model
+}
+
+// SetStorySource 设置每日总结的故事来源，未设置时使用首页故事
+func (b *Bot) SetStorySource(source hackernews.StorySource) {
+	b.source = source
+}
 
-	// Telegram 消息长度限制为 4096 字符
-	const maxMessageLength = 4000
+// SetStore 设置持久化存储，未设置时使用内存存储
+func (b *Bot) SetStore(store storage.Store) {
+	b.store = store
+}
 
-	title := fmt.Sprintf("🗞️ Hacker News 每日热点 - %s\n\n💡 回复故事编号（如 1、2、3）获取详细总结", summary.Date)
+// SetPublishers 设置 Telegram 之外的输出渠道，每日总结发送后依次发布
+func (b *Bot) SetPublishers(publishers ...publisher.Publisher) {
+	b.publishers = publishers
+}
 
-	// 构建带编号的故事列表
-	var storiesBuilder strings.Builder
-	for _, storySummary := range summary.StorySummaries {
-		storiesBuilder.WriteString(fmt.Sprintf("[%d] %s\n\n", storySummary.Number, storySummary.Summary))
-	}
+// Name 渠道名称
+func (b *Bot) Name() string {
+	return "telegram"
+}
 
-	storiesText := storiesBuilder.String()
+// Publish 发布每日总结到 Telegram
+func (b *Bot) Publish(ctx context.Context, summary *hackernews.DailySummaryWithNumbers) error {
+	return b.SendDailySummaryWithNumbers(ctx, summary)
+}
 
-	// 如果消息太长，需要分割发送
-	if len(storiesText) <= maxMessageLength-len(title)-20 {
-		message := fmt.Sprintf("%s\n%s", title, storiesText)
-		return b.sendMessage(message)
+// SendDailySummaryWithNumbers 发送带编号的每日总结到默认聊天和跟随默认定时任务的订阅者
+func (b *Bot) SendDailySummaryWithNumbers(ctx context.Context, summary *hackernews.DailySummaryWithNumbers) error {
+	if err := b.sendDeduped(ctx, b.chatID, summary, nil); err != nil {
+		return err
 	}
 
-	// 发送标题
-	if err := b.sendMessage(title); err != nil {
-		return err
+	b.deliverToSubscribers(ctx, summary, func(subscriber *storage.Subscriber) bool {
+		return subscriber.DeliveryTime == "" && subscriber.ChatID != b.chatID
+	})
+	return nil
+}
+
+// sendDigest 向指定聊天发送带编号的每日总结
+func (b *Bot) sendDigest(ctx context.Context, chatID int64, summary *hackernews.DailySummaryWithNumbers) error {
+	title := fmt.Sprintf("<b>🗞️ %s</b>\n\n💡 点击下方按钮或回复故事编号（如 1、2、3）获取详细总结", escapeHTML(summary.Heading()))
+
+	// 周报和月报在故事列表前附上趋势概述
+	if summary.Overview != "" {
+		title += "\n\n📈 <b>本期趋势</b>\n" + formatMarkdown(summary.Overview)
 	}
 
-	// 分割内容发送
-	return b.sendLongMessage(storiesText, maxMessageLength)
+	// 构建带编号的故事列表，标题链接到原文并附带 HN 讨论链接
+	storiesText := strings.Join(formatStories(summary), "\n\n")
+
+	messageIDs, err := b.sendLongMessage(ctx, chatID, fmt.Sprintf("%s\n\n%s", title, storiesText), digestKeyboard(summary))
+
+	// 记录消息对应的总结，回复这些消息时在该总结中查找故事
+	for _, messageID := range messageIDs {
+		if err := b.store.SaveDigestMessage(chatID, messageID, summary.SnapshotKey()); err != nil {
+			log.Printf("Failed to save digest message %d for chat %d: %v", messageID, chatID, err)
+		}
+	}
+	return err
 }
 
-// SendDetailedSummary 发送单个故事的详细总结
-func (b *Bot) SendDetailedSummary(storyNumber int, date string) error {
+// SendDetailedSummary 向指定聊天发送单个故事的详细总结
+func (b *Bot) SendDetailedSummary(ctx context.Context, chatID int64, storyNumber int, date string) error {
 	// 获取对应的故事总结
-	b.mu.RLock()
-	summary, exists := b.storySummaries[date]
-	b.mu.RUnlock()
-
-	if !exists {
+	summary, err := b.store.GetDailySummary(date)
+	if errors.Is(err, storage.ErrNotFound) {
 		return fmt.Errorf("找不到 %s 的故事总结", date)
 	}
+	if err != nil {
+		return fmt.Errorf("读取 %s 的故事总结失败: %w", date, err)
+	}
 
 	// 查找对应编号的故事
 	var targetStory *hackernews.StoryWithNumber
-	var targetFullStory *hackernews.Story
 	for i, storySummary := range summary.StorySummaries {
 		if storySummary.Number == storyNumber {
 			targetStory = &summary.StorySummaries[i]
-			targetFullStory = &summary.Stories[i]
 			break
 		}
 	}
@@ -265,41 +278,79 @@ func (b *Bot) SendDetailedSummary(storyNumber int, date string) error {
 		return fmt.Errorf("找不到编号为 %d 的故事", storyNumber)
 	}
 
-	// 获取故事的详细内容
-	log.Printf("Fetching detailed content for story %d: %s", targetStory.StoryID, targetStory.Title)
-	content, err := b.hnClient.GetStoryContent(*targetFullStory)
-	if err != nil {
-		return fmt.Errorf("获取故事内容失败: %w", err)
+	targetFullStory := findStory(summary.Stories, targetStory.StoryID)
+	if targetFullStory == nil {
+		return fmt.Errorf("找不到编号为 %d 的故事详情", storyNumber)
 	}
 
-	// 使用AI生成详细总结
-	log.Printf("Generating detailed summary for story %d", storyNumber)
-	detailedSummary, err := b.aiClient.GenerateDetailedSummary(*targetFullStory, content)
+	detailedSummary, err := b.getDetailedSummary(ctx, *targetFullStory)
 	if err != nil {
-		return fmt.Errorf("生成详细总结失败: %w", err)
+		return err
 	}
 
-	// 发送详细总结
-	title := fmt.Sprintf("📖 故事 [%d] 详细总结 - %s", storyNumber, targetStory.Title)
+	// 发送详细总结，标题链接到原文，末尾附带 HN 讨论链接
+	title := fmt.Sprintf("📖 故事 [%d] 详细总结 - %s", storyNumber, link(targetFullStory.URL, "<b>"+escapeHTML(targetStory.Title)+"</b>"))
+	body := formatMarkdown(detailedSummary)
+	if targetFullStory.HackerNewsURL != "" {
+		body += "\n\n💬 " + link(targetFullStory.HackerNewsURL, "HN 讨论")
+	}
 
-	// 如果消息太长，分割发送
-	const maxMessageLength = 4000
-	if len(detailedSummary) <= maxMessageLength-len(title)-20 {
-		message := fmt.Sprintf("%s\n\n%s", title, detailedSummary)
-		return b.sendMessage(message)
+	_, err = b.sendLongMessage(ctx, chatID, fmt.Sprintf("%s\n\n%s", title, body), nil)
+	return err
+}
+
+// getDetailedSummary 获取故事的详细总结，优先使用当前模型已存储的结果
+func (b *Bot) getDetailedSummary(ctx context.Context, story hackernews.Story) (string, error) {
+	if detailedSummary, err := b.store.GetDetailedSummary(story.ID, b.summaryModel); err == nil {
+		log.Printf("Using stored detailed summary for story %d", story.ID)
+		return detailedSummary, nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Failed to read stored detailed summary for story %d: %v", story.ID, err)
 	}
 
-	// 发送标题
-	if err := b.sendMessage(title); err != nil {
-		return err
+	// 获取故事的详细内容，优先使用生成每日总结时保存的内容
+	content, err := b.store.GetStoryContent(story.ID)
+	if err != nil {
+		log.Printf("Fetching detailed content for story %d: %s", story.ID, story.Title)
+		fetchCtx, cancel := context.WithTimeout(ctx, b.timeouts.FetchContent)
+		content, err = b.hnClient.GetStoryContent(fetchCtx, story)
+		cancel()
+		if err != nil {
+			return "", fmt.Errorf("获取故事内容失败: %w", err)
+		}
+		if err := b.store.SaveStoryContent(story.ID, content); err != nil {
+			log.Printf("Failed to save content for story %d: %v", story.ID, err)
+		}
+	}
+
+	// 使用AI生成详细总结
+	log.Printf("Generating detailed summary for story %d", story.ID)
+	summarizeCtx, cancel := context.WithTimeout(ctx, b.timeouts.Summarize)
+	defer cancel()
+	detailedSummary, err := b.aiClient.GenerateDetailedSummary(summarizeCtx, story, content)
+	if err != nil {
+		return "", fmt.Errorf("生成详细总结失败: %w", err)
+	}
+
+	if err := b.store.SaveDetailedSummary(story.ID, b.summaryModel, detailedSummary); err != nil {
+		log.Printf("Failed to save detailed summary for story %d: %v", story.ID, err)
 	}
 
-	// 分割内容发送
-	return b.sendLongMessage(detailedSummary, maxMessageLength)
+	return detailedSummary, nil
 }
 
-// StartMessageHandler 启动消息处理器
-func (b *Bot) StartMessageHandler() {
+// findStory 按ID查找故事
+func findStory(stories []hackernews.Story, storyID int) *hackernews.Story {
+	for i := range stories {
+		if stories[i].ID == storyID {
+			return &stories[i]
+		}
+	}
+	return nil
+}
+
+// StartMessageHandler 启动消息处理器，ctx 取消时正在处理的消息随之中止
+func (b *Bot) StartMessageHandler(ctx context.Context) {
 	log.Println("Starting Telegram message handler...")
 
 	// 获取更新通道
@@ -309,31 +360,49 @@ func (b *Bot) StartMessageHandler() {
 	updates := b.api.GetUpdatesChan(u)
 
 	// 启动消息处理协程
-	go b.processMessages(updates)
+	b.handlerDone = make(chan struct{})
+	go b.processMessages(ctx, updates)
 }
 
-// StopMessageHandler 停止消息处理器
+// StopMessageHandler 停止接收新消息，并等待正在处理的消息完成；未启动消息处理器时直接返回
 func (b *Bot) StopMessageHandler() {
+	if b.handlerDone == nil {
+		return
+	}
+
 	log.Println("Stopping Telegram message handler...")
+	b.api.StopReceivingUpdates()
 	close(b.stopHandler)
+
+	// 分发协程退出后不会再有新的处理协程，此时才能等待
+	<-b.handlerDone
+	b.handlers.Wait()
+	log.Println("Telegram message handler stopped")
 }
 
 // processMessages 处理消息
-func (b *Bot) processMessages(updates tgbotapi.UpdatesChannel) {
+func (b *Bot) processMessages(ctx context.Context, updates tgbotapi.UpdatesChannel) {
+	defer close(b.handlerDone)
+
 	for {
 		select {
-		case update := <-updates:
-			if update.Message == nil {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			// 处理内联键盘按钮
+			if update.CallbackQuery != nil {
+				b.handle(func() { b.handleCallbackQuery(ctx, update.CallbackQuery) })
 				continue
 			}
 
-			// 只处理指定chatID的消息
-			if update.Message.Chat.ID != b.chatID {
+			if update.Message == nil {
 				continue
 			}
 
-			// 处理用户消息
-			go b.HandleUserMessage(update)
+			// 处理用户消息，未订阅的聊天只能使用订阅相关命令
+			b.handle(func() { b.HandleUserMessage(ctx, update) })
 
 		case <-b.stopHandler:
 			return
@@ -341,21 +410,77 @@ func (b *Bot) processMessages(updates tgbotapi.UpdatesChannel) {
 	}
 }
 
+// handle 在新协程中处理单条更新，同时处理的更新达到上限时等待空位，停止处理器时等待其完成
+func (b *Bot) handle(fn func()) {
+	b.handlerSlots <- struct{}{}
+	b.handlers.Add(1)
+	go func() {
+		defer func() {
+			<-b.handlerSlots
+			b.handlers.Done()
+		}()
+		fn()
+	}()
+}
+
 // HandleUserMessage 处理用户消息
-func (b *Bot) HandleUserMessage(update tgbotapi.Update) {
+func (b *Bot) HandleUserMessage(ctx context.Context, update tgbotapi.Update) {
 	message := strings.TrimSpace(update.Message.Text)
-	log.Printf("Received message: %s", message)
+	log.Printf("Received message from chat %d: %s", update.Message.Chat.ID, message)
 
-	// 处理 resend 命令
-	if strings.ToLower(message) == "resend" {
-		b.handleResendRequest(update)
+	// 处理订阅相关命令
+	if update.Message.IsCommand() {
+		switch update.Message.Command() {
+		case "subscribe":
+			b.handleSubscribe(ctx, update.Message)
+			return
+		case "unsubscribe":
+			b.handleUnsubscribe(ctx, update.Message)
+			return
+		case "settings":
+			b.handleSettings(ctx, update.Message)
+			return
+		case "set":
+			b.handleSet(ctx, update.Message)
+			return
+		case "approve":
+			b.handleApprove(ctx, update.Message)
+			return
+		}
+	}
+
+	if !b.isAuthorized(update.Message.Chat.ID) {
+		b.sendReply(ctx, update.Message, "🤖 Hacker News 每日总结机器人\n\n发送 /subscribe 订阅每日热点推送。")
+		return
+	}
+
+	// 处理按日期查询的命令
+	if update.Message.IsCommand() {
+		switch update.Message.Command() {
+		case "story":
+			b.handleStoryCommand(ctx, update.Message)
+			return
+		case "digest":
+			b.handleDigestCommand(ctx, update.Message)
+			return
+		}
+	}
+
+	// 处理 resend 命令，重新生成总结需要调用外部服务，只允许管理员使用；
+	// resend full 不按去重策略跳过已发送过的故事
+	if lower := strings.ToLower(message); lower == "resend" || lower == "resend full" {
+		if update.Message.Chat.ID != b.chatID {
+			b.sendReply(ctx, update.Message, "❌ 只有管理员可以重新生成总结，发送 /digest 查看最近一次的每日总结。")
+			return
+		}
+		b.handleResendRequest(ctx, update, lower == "resend full")
 		return
 	}
 
 	// 尝试解析为纯数字
 	if storyNumber, err := strconv.Atoi(message); err == nil {
-		// 用户发送了纯数字编号
-		b.handleStoryRequest(update, storyNumber, message)
+		// 用户发送了纯数字编号，按回复的总结消息或最近一次总结确定日期
+		b.handleStoryRequest(ctx, update.Message, storyNumber, b.digestDateFor(update.Message))
 		return
 	}
 
@@ -363,149 +488,302 @@ func (b *Bot) HandleUserMessage(update tgbotapi.Update) {
 	helpMessage := `🤖 Hacker News 每日总结机器人
 
 💡 使用方法：
-- 回复故事编号获取详细总结，例如：1、2、3
-- 发送 "resend" 重新获取过去24小时的热点总结
+- 点击总结下方的按钮，或回复故事编号获取详细总结，例如：1、2、3
+- 引用某天的总结消息回复编号，查看当天的故事
+- /story 2025-01-10 3 查看指定日期第 3 个故事的详细总结
+- /digest 2025-01-10 重新发送指定日期的每日总结，加 full 包含已推送过的故事
+- 发送 "resend" 重新获取过去24小时的热点总结，"resend full" 包含已推送过的故事（仅管理员）
 - 每日18:00会自动推送当日热门故事总结
 
+⚙️ 订阅管理：
+- /subscribe 订阅每日推送（可能需要管理员批准），/unsubscribe 取消订阅
+- /approve 123456789 批准聊天的订阅请求（仅管理员）
+- /settings 查看当前推送设置
+- /set time 09:00 设置每日推送时间（/set time default 恢复默认）
+- /set count 5 设置推送的故事数量（0 表示全部）
+- /set lang en 设置总结语言（zh 为中文原文）
+- /set keywords go,rust 只推送匹配关键词的故事（不带参数清除）
+
 📝 当前支持的操作：
 - 查看当日故事详细总结
 - 重新获取过去24小时热点总结
 - 自动接收每日热点推送
 
 如有问题请联系管理员。`
-	b.sendReply(update.Message, helpMessage)
+	b.sendReply(ctx, update.Message, helpMessage)
 }
 
-// handleStoryRequest 处理故事详细总结请求
-func (b *Bot) handleStoryRequest(update tgbotapi.Update, storyNumber int, _ string) {
-	// 立即发送正在处理的提示信息
-	processingMsg := fmt.Sprintf("🔄 正在为您生成故事 [%d] 的详细总结，请稍候...", storyNumber)
-	if err := b.sendReply(update.Message, processingMsg); err != nil {
+// handleStoryRequest 处理某一天故事的详细总结请求
+func (b *Bot) handleStoryRequest(ctx context.Context, message *tgbotapi.Message, storyNumber int, date string) {
+	// 立即发送正在处理的提示信息，提示中不显示生成批次
+	label, _, _ := strings.Cut(date, "@")
+	processingMsg := fmt.Sprintf("🔄 正在为您生成 %s 故事 [%d] 的详细总结，请稍候...", label, storyNumber)
+	if err := b.sendReply(ctx, message, processingMsg); err != nil {
 		log.Printf("Failed to send processing message: %v", err)
 		return
 	}
 
-	// 获取今天的日期
-	today := time.Now().Format("2006-01-02")
-
 	// 发送详细总结
-	if err := b.SendDetailedSummary(storyNumber, today); err != nil {
+	if err := b.SendDetailedSummary(ctx, message.Chat.ID, storyNumber, date); err != nil {
 		log.Printf("Failed to send detailed summary: %v", err)
 		// 发送错误信息
 		errorMsg := fmt.Sprintf("❌ 获取故事 [%d] 的详细总结失败: %v", storyNumber, err)
-		b.sendReply(update.Message, errorMsg)
+		b.sendReply(ctx, message, errorMsg)
 		return
 	}
 
 	// 发送完成确认消息
 	completionMsg := fmt.Sprintf("✅ 故事 [%d] 的详细总结已发送完成！", storyNumber)
-	b.sendReply(update.Message, completionMsg)
+	b.sendReply(ctx, message, completionMsg)
 }
 
-// handleResendRequest 处理重新发送请求
-func (b *Bot) handleResendRequest(update tgbotapi.Update) {
+// handleResendRequest 处理重新发送请求，full 为 true 时发送完整总结
+func (b *Bot) handleResendRequest(ctx context.Context, update tgbotapi.Update, full bool) {
 	// 立即发送正在处理的提示信息
 	processingMsg := "🔄 正在重新获取过去24小时的热点总结，请稍候..."
-	if err := b.sendReply(update.Message, processingMsg); err != nil {
+	if err := b.sendReply(ctx, update.Message, processingMsg); err != nil {
 		log.Printf("Failed to send processing message: %v", err)
 		return
 	}
 
-	// 获取今天的日期
-	today := time.Now().Format("2006-01-02")
-
 	// 执行重新发送流程
-	if err := b.ResendDailySummary(today); err != nil {
+	if err := b.ResendDailySummary(ctx, update.Message.Chat.ID, "", full); err != nil {
 		log.Printf("Failed to resend daily summary: %v", err)
 		// 发送错误信息
 		errorMsg := fmt.Sprintf("❌ 重新获取热点总结失败: %v", err)
-		b.sendReply(update.Message, errorMsg)
+		b.sendReply(ctx, update.Message, errorMsg)
 		return
 	}
 
 	// 发送完成确认消息
 	completionMsg := "✅ 过去24小时的热点总结已重新发送完成！"
-	b.sendReply(update.Message, completionMsg)
+	b.sendReply(ctx, update.Message, completionMsg)
 }
 
 // ProcessDailySummary 处理每日总结的核心逻辑
-func (b *Bot) ProcessDailySummary(date string, maxStories int) error {
+func (b *Bot) ProcessDailySummary(ctx context.Context, date string, maxStories int) error {
+	return b.ProcessDigest(ctx, Digest{MaxStories: maxStories}, date)
+}
+
+// ProcessDigest 生成截止到 date 当天结束的摘要，发送到 Telegram 并发布到其他渠道；
+// date 为空时生成截止到当前时间的摘要，日期标签为今天
+func (b *Bot) ProcessDigest(ctx context.Context, digest Digest, date string) error {
+	label, window, err := b.digestWindow(digest, date)
+	if err != nil {
+		return err
+	}
+	return b.ProcessDigestWindow(ctx, digest, label, window)
+}
+
+// ProcessDigestRange 生成发布时间在 from 到 to 这几天（含两端）的摘要并发送，日期标签为 "from~to"
+func (b *Bot) ProcessDigestRange(ctx context.Context, digest Digest, from, to string) error {
+	if b.hnClient == nil {
+		return fmt.Errorf("Hacker News客户端未初始化")
+	}
+	window, err := b.hnClient.RangeWindow(from, to)
+	if err != nil {
+		return err
+	}
+	return b.ProcessDigestWindow(ctx, digest, hackernews.RangeLabel(from, to), window)
+}
+
+// ProcessDigestWindow 生成时间段内的摘要，以 label 作为日期标签保存，发送到 Telegram 并发布到其他渠道
+func (b *Bot) ProcessDigestWindow(ctx context.Context, digest Digest, label string, window hackernews.Window) error {
+	dailySummaryWithNumbers, err := b.GenerateDigestWindow(ctx, digest, label, window)
+	if err != nil {
+		return err
+	}
+	if dailySummaryWithNumbers == nil {
+		return nil
+	}
+
+	// 发送到 Telegram (带编号)
+	log.Println("Sending numbered summary to Telegram...")
+	publishCtx, cancel := context.WithTimeout(ctx, b.timeouts.Publish)
+	err = b.SendDailySummaryWithNumbers(publishCtx, dailySummaryWithNumbers)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to send numbered summary to telegram: %w", err)
+	}
+
+	// 发布到其他输出渠道，单个渠道失败不影响整体流程，每个渠道单独计时
+	for _, p := range b.publishers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Printf("Publishing summary to %s...", p.Name())
+		publishCtx, cancel := context.WithTimeout(ctx, b.timeouts.Publish)
+		if err := p.Publish(publishCtx, dailySummaryWithNumbers); err != nil {
+			log.Printf("Failed to publish summary to %s: %v", p.Name(), err)
+		}
+		cancel()
+	}
+
+	log.Println("Successfully processed and sent numbered daily summary")
+	return nil
+}
+
+// GenerateDailySummary 获取热门故事并生成带编号的总结，保存后返回；没有故事时返回 nil
+func (b *Bot) GenerateDailySummary(ctx context.Context, date string, maxStories int) (*hackernews.DailySummaryWithNumbers, error) {
+	return b.GenerateDigest(ctx, Digest{MaxStories: maxStories}, date)
+}
+
+// GenerateDigest 从摘要的故事来源获取截止到 date 当天结束的故事并生成带编号的总结，保存后返回；
+// date 为空时获取截止到当前时间的故事；没有故事时返回 nil
+func (b *Bot) GenerateDigest(ctx context.Context, digest Digest, date string) (*hackernews.DailySummaryWithNumbers, error) {
+	label, window, err := b.digestWindow(digest, date)
+	if err != nil {
+		return nil, err
+	}
+	return b.GenerateDigestWindow(ctx, digest, label, window)
+}
+
+// digestWindow 返回摘要的日期标签和故事时间段：date 为空时为截止到当前时间的 Days 个 24 小时，标签为今天；
+// 否则为截止到 date 当天结束的 Days 个自然日，自然日按 Hacker News 客户端的时区计算。周报和月报见 rollupWindow
+func (b *Bot) digestWindow(digest Digest, date string) (string, hackernews.Window, error) {
+	if b.hnClient == nil {
+		return "", hackernews.Window{}, fmt.Errorf("Hacker News客户端未初始化")
+	}
+	if digest.Period != "" {
+		return b.rollupWindow(digest.Period, date)
+	}
+	if date == "" {
+		return b.hnClient.Today(), b.hnClient.RecentWindow(time.Now(), digest.Days), nil
+	}
+	window, err := b.hnClient.DayWindow(date, digest.Days)
+	return date, window, err
+}
+
+// GenerateDigestWindow 生成时间段内的带编号总结，以 label 作为日期标签保存后返回；没有故事时返回 nil。
+// 周报和月报汇总时间段内已保存的每日总结，其他摘要从故事来源获取故事
+func (b *Bot) GenerateDigestWindow(ctx context.Context, digest Digest, label string, window hackernews.Window) (*hackernews.DailySummaryWithNumbers, error) {
 	// 检查客户端是否已设置
 	if b.aiClient == nil || b.hnClient == nil {
-		return fmt.Errorf("AI或Hacker News客户端未初始化")
+		return nil, fmt.Errorf("AI或Hacker News客户端未初始化")
+	}
+
+	maxStories := digest.MaxStories
+	if maxStories <= 0 {
+		maxStories = b.maxStories
+	}
+
+	var summary *hackernews.DailySummaryWithNumbers
+	var err error
+	if digest.Period != "" {
+		summary, err = b.generateRollup(ctx, label, window, maxStories)
+	} else {
+		summary, err = b.generateStories(ctx, digest, label, window, maxStories)
+	}
+	if err != nil || summary == nil {
+		return nil, err
+	}
+	summary.Digest = digest.Name
+	summary.Period = digest.Period
+	summary.Revision = newRevision()
+	summary.Title = digest.Title
+	if summary.Title == "" {
+		summary.Title = defaultPeriodTitles[digest.Period]
+	}
+
+	// 保存总结供后续查询
+	if err := b.store.SaveDailySummary(summary); err != nil {
+		return nil, fmt.Errorf("failed to save daily summary: %w", err)
+	}
+
+	return summary, nil
+}
+
+// newRevision 生成总结的生成批次，重新生成同一日期的总结时已发送的消息仍指向原来的总结
+func newRevision() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// generateStories 从摘要的故事来源获取时间段内的故事，获取正文和评论后生成带编号的总结
+func (b *Bot) generateStories(ctx context.Context, digest Digest, label string, window hackernews.Window, maxStories int) (*hackernews.DailySummaryWithNumbers, error) {
+	source := digest.Source
+	if source == nil {
+		source = b.source
+	}
+	if source == nil {
+		source, _ = b.hnClient.Source(hackernews.SourceFrontPage)
 	}
 
 	// 1. 获取热门故事
-	log.Println("Fetching top stories")
+	log.Printf("Fetching %s stories published in %s", source.Name(), window)
 
-	stories, err := b.hnClient.GetTopStoriesByDate(date, maxStories)
+	fetchCtx, cancel := context.WithTimeout(ctx, b.timeouts.FetchStories)
+	stories, err := b.hnClient.GetStoriesInWindow(fetchCtx, source, window, maxStories)
+	cancel()
 	if err != nil {
-		return fmt.Errorf("failed to get top stories: %w", err)
+		return nil, fmt.Errorf("failed to get top stories: %w", err)
 	}
 
 	if len(stories) == 0 {
 		log.Println("No stories found")
-		return nil
+		return nil, nil
 	}
 
 	log.Printf("Found %d top stories", len(stories))
 
-	// 2. 获取每个故事的详细内容
+	// 2. 并发获取每个故事的详细内容，请求速率由 Hacker News 客户端限制
+	contentCtx, cancel := context.WithTimeout(ctx, b.timeouts.FetchContent)
+	contents, errs := b.hnClient.GetStoryContents(contentCtx, stories)
+	cancel()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	storyContents := make([]string, 0, len(stories))
+	fetchedStories := make([]hackernews.Story, 0, len(stories))
 	for i, story := range stories {
-		log.Printf("Processing story %d/%d: %s", i+1, len(stories), story.Title)
-
-		content, err := b.hnClient.GetStoryContent(story)
-		if err != nil {
-			log.Printf("Failed to get content for story %d: %v", story.ID, err)
+		if errs[i] != nil {
+			log.Printf("Failed to get content for story %d: %v", story.ID, errs[i])
 			continue
 		}
 
-		storyContents = append(storyContents, content)
+		storyContents = append(storyContents, contents[i])
+		fetchedStories = append(fetchedStories, story)
 
-		// 添加延迟避免请求过快
-		time.Sleep(1 * time.Second)
+		// 保存原始内容，供重启后生成详细总结使用
+		if err := b.store.SaveStoryContent(story.ID, contents[i]); err != nil {
+			log.Printf("Failed to save content for story %d: %v", story.ID, err)
+		}
 	}
 
 	if len(storyContents) == 0 {
-		return fmt.Errorf("no story content retrieved")
+		return nil, fmt.Errorf("no story content retrieved")
 	}
 
 	// 3. 使用 AI 生成带编号的故事总结
 	log.Println("Generating AI summary with numbers...")
-	dailySummaryWithNumbers, err := b.aiClient.SummarizeStoriesWithNumbers(storyContents, stories, date)
+	summarizeCtx, cancel := context.WithTimeout(ctx, b.timeouts.Summarize)
+	dailySummaryWithNumbers, err := b.aiClient.SummarizeStoriesWithNumbers(summarizeCtx, storyContents, fetchedStories, label)
+	cancel()
 	if err != nil {
-		return fmt.Errorf("failed to summarize stories with numbers: %w", err)
+		return nil, fmt.Errorf("failed to summarize stories with numbers: %w", err)
 	}
 
-	// 4. 发送到 Telegram (带编号)
-	log.Println("Sending numbered summary to Telegram...")
-	if err := b.SendDailySummaryWithNumbers(dailySummaryWithNumbers); err != nil {
-		return fmt.Errorf("failed to send numbered summary to telegram: %w", err)
-	}
-
-	log.Println("Successfully processed and sent numbered daily summary")
-	return nil
+	return dailySummaryWithNumbers, nil
 }
 
-// ResendDailySummary 重新发送每日总结
-func (b *Bot) ResendDailySummary(date string) error {
+// ResendDailySummary 重新生成每日总结并发送到指定聊天，date 为空时为截止到当前时间的 24 小时。
+// 默认按去重策略处理已发送过的故事，full 为 true 时发送完整总结
+func (b *Bot) ResendDailySummary(ctx context.Context, chatID int64, date string, full bool) error {
 	// 使用配置的最大故事数量
-	return b.ProcessDailySummary(date, b.maxStories)
+	summary, err := b.GenerateDailySummary(ctx, date, b.maxStories)
+	if err != nil {
+		return err
+	}
+	if summary == nil {
+		return fmt.Errorf("没有找到热门故事")
+	}
+	return b.deliverTo(ctx, chatID, summary, make(map[string][]hackernews.StoryWithNumber), !full)
 }
 
-// sendReply 回复消息
-func (b *Bot) sendReply(message *tgbotapi.Message, text string) error {
+// sendReply 以纯文本回复消息
+func (b *Bot) sendReply(ctx context.Context, message *tgbotapi.Message, text string) error {
 	reply := tgbotapi.NewMessage(message.Chat.ID, text)
 	reply.ReplyToMessageID = message.MessageID
-	// 移除Markdown格式设置，避免解析错误
-	// reply.ParseMode = tgbotapi.ModeMarkdown
-	reply.DisableWebPagePreview = true
-
-	_, err := b.api.Send(reply)
-	if err != nil {
-		return fmt.Errorf("failed to send reply: %w", err)
-	}
-
-	return nil
+	_, err := b.send(ctx, reply)
+	return err
 }
@@ -0,0 +1,219 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/ai"
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/retry"
+	"hacker-news-daily/storage"
+)
+
+// sentMessage 假 Telegram 服务收到的一次请求
+type sentMessage struct {
+	Method string
+	Params map[string]string
+}
+
+// fakeTelegram 记录机器人发出的请求，reject 返回非空描述时以 400 拒绝该请求
+type fakeTelegram struct {
+	mu       sync.Mutex
+	messages []sentMessage
+	reject   func(params map[string]string) string
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := make(map[string]string)
+	for key := range r.PostForm {
+		params[key] = r.PostForm.Get(key)
+	}
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	f.mu.Lock()
+	f.messages = append(f.messages, sentMessage{Method: method, Params: params})
+	id := len(f.messages)
+	f.mu.Unlock()
+
+	if f.reject != nil {
+		if description := f.reject(params); description != "" {
+			json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": 400, "description": description})
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": map[string]any{"message_id": id, "chat": map[string]any{"id": 1}}})
+}
+
+// sent 返回已收到的请求副本
+func (f *fakeTelegram) sent() []sentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentMessage(nil), f.messages...)
+}
+
+// newTestBot 创建连接到假 Telegram 服务的机器人
+func newTestBot(t *testing.T, fake *fakeTelegram) *Bot {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	api := &tgbotapi.BotAPI{Token: "test", Client: server.Client(), Buffer: 100}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+
+	return &Bot{
+		api:            api,
+		chatID:         1,
+		store:          storage.NewMemoryStore(),
+		messageHandler: make(chan tgbotapi.Update, 100),
+		stopHandler:    make(chan struct{}),
+		handlerSlots:   make(chan struct{}, maxConcurrentHandlers),
+		retrier:        retry.New("telegram", retry.Config{}),
+		timeouts:       StageTimeouts{}.withDefaults(),
+	}
+}
+
+func TestSendHTMLFallsBackToPlainText(t *testing.T) {
+	fake := &fakeTelegram{reject: func(params map[string]string) string {
+		if params["parse_mode"] == tgbotapi.ModeHTML {
+			return "Bad Request: can't parse entities: unsupported start tag"
+		}
+		return ""
+	}}
+	bot := newTestBot(t, fake)
+
+	require.NoError(t, bot.sendHTMLTo(context.Background(), 1, `<b>标题</b> 1 &lt; 2 <a href="https://example.com">链接</a>`))
+
+	sent := fake.sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "HTML", sent[0].Params["parse_mode"])
+	assert.Empty(t, sent[1].Params["parse_mode"])
+	assert.Equal(t, "标题 1 < 2 链接", sent[1].Params["text"])
+}
+
+func TestSendHTMLDoesNotRetryOtherErrors(t *testing.T) {
+	fake := &fakeTelegram{reject: func(map[string]string) string {
+		return "Forbidden: bot was blocked by the user"
+	}}
+	bot := newTestBot(t, fake)
+
+	assert.Error(t, bot.sendHTMLTo(context.Background(), 1, "<b>标题</b>"))
+	assert.Len(t, fake.sent(), 1)
+}
+
+func TestSendLongMessageSplits(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+
+	paragraph := "<b>标题</b> " + strings.Repeat("内容", 1500)
+	messageIDs, err := bot.sendLongMessage(context.Background(), 1, paragraph+"\n\n"+paragraph, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, messageIDs)
+
+	sent := fake.sent()
+	require.Len(t, sent, 2)
+	assert.True(t, strings.HasSuffix(sent[0].Params["text"], "(1/2)"))
+	assert.True(t, strings.HasSuffix(sent[1].Params["text"], "(2/2)"))
+	for _, message := range sent {
+		assert.Equal(t, "HTML", message.Params["parse_mode"])
+		assert.LessOrEqual(t, utf16Length(plainText(message.Params["text"])), maxMessageLength)
+	}
+}
+
+func TestProcessMessagesWaitsForHandlers(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+
+	updates := make(chan tgbotapi.Update, 10)
+	bot.handlerDone = make(chan struct{})
+	go bot.processMessages(context.Background(), updates)
+
+	for i := range 3 {
+		updates <- tgbotapi.Update{Message: &tgbotapi.Message{MessageID: i + 1, Text: "help", Chat: &tgbotapi.Chat{ID: 1}}}
+	}
+	// 等待分发协程取走所有更新后再停止
+	require.Eventually(t, func() bool { return len(updates) == 0 }, time.Second, time.Millisecond)
+
+	close(bot.stopHandler)
+	<-bot.handlerDone
+	bot.handlers.Wait()
+
+	assert.Len(t, fake.sent(), 3)
+}
+
+func TestHandleLimitsConcurrency(t *testing.T) {
+	bot := newTestBot(t, &fakeTelegram{})
+	bot.handlerSlots = make(chan struct{}, 2)
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	dispatched := make(chan struct{})
+	go func() {
+		for range 5 {
+			bot.handle(func() {
+				n := running.Add(1)
+				for {
+					if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				<-release
+				running.Add(-1)
+			})
+		}
+		close(dispatched)
+	}()
+
+	// 达到上限后分发协程等待空位
+	require.Eventually(t, func() bool { return running.Load() == 2 }, time.Second, time.Millisecond)
+	select {
+	case <-dispatched:
+		t.Fatal("handle did not wait for a free slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-dispatched
+	bot.handlers.Wait()
+	assert.Equal(t, int32(2), peak.Load())
+}
+
+func TestStopMessageHandlerWithoutStart(t *testing.T) {
+	bot := newTestBot(t, &fakeTelegram{})
+
+	done := make(chan struct{})
+	go func() {
+		bot.StopMessageHandler()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("StopMessageHandler blocked although the handler was never started")
+	}
+}
+
+func TestGenerateDailySummaryCancelled(t *testing.T) {
+	bot := newTestBot(t, &fakeTelegram{})
+	bot.SetClients(ai.NewClient("", "", "", 0), hackernews.NewClient(5, 10, 5))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := bot.GenerateDailySummary(ctx, "2025-01-10", 5)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,163 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/publisher"
+	"hacker-news-daily/storage"
+)
+
+// 同一聊天中重复出现的故事的处理方式
+const (
+	DedupOff     = "off"     // 不处理，每次都完整发送
+	DedupExclude = "exclude" // 不再发送已发送过的故事
+	DedupMark    = "mark"    // 保留故事并标注为持续热门，附带分数变化
+)
+
+// defaultDedupDays 默认只与最近 7 天内发送过的故事比较
+const defaultDedupDays = 7
+
+// DedupPolicy 同一聊天中重复出现的故事的处理方式，零值不处理。
+// 周报和月报本身就是已发送故事的汇总，不受影响
+type DedupPolicy struct {
+	Mode string // off、exclude 或 mark，为空时为 off
+	Days int    // 只与最近多少天内发送过的故事比较，不大于 0 时为 7 天
+}
+
+// Validate 检查处理方式是否有效
+func (p DedupPolicy) Validate() error {
+	switch p.Mode {
+	case "", DedupOff, DedupExclude, DedupMark:
+		return nil
+	default:
+		return fmt.Errorf("unknown dedup mode %q, available: %s, %s, %s", p.Mode, DedupOff, DedupExclude, DedupMark)
+	}
+}
+
+// enabled 判断是否需要处理该总结中的重复故事
+func (p DedupPolicy) enabled(summary *hackernews.DailySummaryWithNumbers) bool {
+	return p.Mode != "" && p.Mode != DedupOff && summary.Period == ""
+}
+
+// SetDedupPolicy 设置重复故事的处理方式，未设置时不处理
+func (b *Bot) SetDedupPolicy(policy DedupPolicy) {
+	if policy.Days <= 0 {
+		policy.Days = defaultDedupDays
+	}
+	b.dedup = policy
+}
+
+// repeatedStories 返回总结中最近已发送到该聊天的故事，值为上次发送时的分数
+func (b *Bot) repeatedStories(chatID int64, summary *hackernews.DailySummaryWithNumbers, now time.Time) map[int]int {
+	if !b.dedup.enabled(summary) {
+		return nil
+	}
+
+	ids := make([]int, 0, len(summary.StorySummaries))
+	for _, storySummary := range summary.StorySummaries {
+		ids = append(ids, storySummary.StoryID)
+	}
+	sent, err := b.store.GetSentStories(chatID, ids)
+	if err != nil {
+		log.Printf("Failed to get sent stories for chat %d, sending all stories: %v", chatID, err)
+		return nil
+	}
+
+	cutoff := now.AddDate(0, 0, -b.dedup.Days).Unix()
+	repeats := make(map[int]int)
+	for id, story := range sent {
+		if story.SentAt >= cutoff {
+			repeats[id] = story.Score
+		}
+	}
+	return repeats
+}
+
+// excludeRepeats 去掉已发送过的故事，保留原始编号以便回复编号查看详情
+func excludeRepeats(summary *hackernews.DailySummaryWithNumbers, repeats map[int]int) *hackernews.DailySummaryWithNumbers {
+	filtered := *summary
+	filtered.StorySummaries = nil
+	for _, storySummary := range summary.StorySummaries {
+		if _, ok := repeats[storySummary.StoryID]; !ok {
+			filtered.StorySummaries = append(filtered.StorySummaries, storySummary)
+		}
+	}
+	return &filtered
+}
+
+// markRepeats 在已发送过的故事的总结前标注持续热门和上次发送以来的分数变化
+func markRepeats(summary *hackernews.DailySummaryWithNumbers, repeats map[int]int) *hackernews.DailySummaryWithNumbers {
+	marked := *summary
+	marked.StorySummaries = make([]hackernews.StoryWithNumber, len(summary.StorySummaries))
+	for i, storySummary := range summary.StorySummaries {
+		if previous, ok := repeats[storySummary.StoryID]; ok {
+			note := "🔥 持续热门"
+			if story := findStory(summary.Stories, storySummary.StoryID); story != nil && story.Score > previous {
+				note = fmt.Sprintf("🔥 持续热门（比上次推送 ↑ %d 分）", story.Score-previous)
+			}
+			// 标注放在加粗标题之后，标题仍作为故事链接的文字
+			if title, body := publisher.SplitTitle(storySummary.Summary); title != "" {
+				storySummary.Summary = "**" + title + "** " + note + "\n" + body
+			} else {
+				storySummary.Summary = note + "\n" + storySummary.Summary
+			}
+		}
+		marked.StorySummaries[i] = storySummary
+	}
+	return &marked
+}
+
+// sendDeduped 按去重策略处理已发送到该聊天的故事后发送总结，并记录这次发送的故事。
+// prepare 不为空时在去掉重复故事之后、标注之前调整总结，返回 nil 时不发送
+func (b *Bot) sendDeduped(ctx context.Context, chatID int64, summary *hackernews.DailySummaryWithNumbers, prepare func(*hackernews.DailySummaryWithNumbers) (*hackernews.DailySummaryWithNumbers, error)) error {
+	now := time.Now()
+	repeats := b.repeatedStories(chatID, summary, now)
+
+	if b.dedup.Mode == DedupExclude && len(repeats) > 0 {
+		summary = excludeRepeats(summary, repeats)
+		if len(summary.StorySummaries) == 0 {
+			log.Printf("All stories of %s were already sent to chat %d", summary.Key(), chatID)
+			return b.sendMessageTo(ctx, chatID, fmt.Sprintf("✅ %s 的故事都已推送过，暂时没有新的热门故事", summary.Heading()))
+		}
+	}
+
+	if prepare != nil {
+		var err error
+		if summary, err = prepare(summary); err != nil || summary == nil {
+			return err
+		}
+	}
+
+	if b.dedup.Mode == DedupMark && len(repeats) > 0 {
+		summary = markRepeats(summary, repeats)
+	}
+
+	if err := b.sendDigest(ctx, chatID, summary); err != nil {
+		return err
+	}
+	b.recordSent(chatID, summary, now)
+	return nil
+}
+
+// recordSent 记录发送到聊天的故事和当前分数，下次以此计算分数变化
+func (b *Bot) recordSent(chatID int64, summary *hackernews.DailySummaryWithNumbers, now time.Time) {
+	if !b.dedup.enabled(summary) {
+		return
+	}
+
+	stories := make([]storage.SentStory, 0, len(summary.StorySummaries))
+	for _, storySummary := range summary.StorySummaries {
+		sent := storage.SentStory{StoryID: storySummary.StoryID, SentAt: now.Unix()}
+		if story := findStory(summary.Stories, storySummary.StoryID); story != nil {
+			sent.Score = story.Score
+		}
+		stories = append(stories, sent)
+	}
+	if err := b.store.SaveSentStories(chatID, stories); err != nil {
+		log.Printf("Failed to record sent stories for chat %d: %v", chatID, err)
+	}
+}
@@ -0,0 +1,191 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/ai/aitest"
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/storage"
+)
+
+// scoredDigest 生成每日总结并按故事 ID 设置分数
+func scoredDigest(date string, n int, scores map[int]int) *hackernews.DailySummaryWithNumbers {
+	summary := newDigest(date, n)
+	for i := range summary.Stories {
+		summary.Stories[i].Score = scores[summary.Stories[i].ID]
+	}
+	return summary
+}
+
+func TestDedupPolicyValidate(t *testing.T) {
+	for _, mode := range []string{"", DedupOff, DedupExclude, DedupMark} {
+		assert.NoError(t, DedupPolicy{Mode: mode}.Validate())
+	}
+	assert.ErrorContains(t, DedupPolicy{Mode: "hide"}.Validate(), "unknown dedup mode")
+}
+
+func TestDedupExcludeRepeats(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	bot.SetDedupPolicy(DedupPolicy{Mode: DedupExclude})
+	ctx := context.Background()
+
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, scoredDigest("2025-01-09", 2, nil)))
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, scoredDigest("2025-01-10", 3, nil)))
+
+	sent := fake.sent()
+	require.Len(t, sent, 2)
+	text := sent[1].Params["text"]
+	assert.NotContains(t, text, "故事 1")
+	assert.NotContains(t, text, "故事 2")
+	// 保留原始编号，回复编号仍能找到对应的故事
+	assert.Contains(t, text, "[3] ")
+
+	// 全部故事都已发送过时只发送提示
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, scoredDigest("2025-01-11", 3, nil)))
+	sent = fake.sent()
+	require.Len(t, sent, 3)
+	assert.Equal(t, "✅ Hacker News 每日热点 - 2025-01-11 的故事都已推送过，暂时没有新的热门故事", sent[2].Params["text"])
+}
+
+func TestDedupAppliesToResend(t *testing.T) {
+	hnServer := newHNServer(t)
+	aiServer := aitest.NewServer(t, summarizeResponder)
+	fake := &fakeTelegram{}
+	bot := newPipelineBot(t, fake, hnServer.URL, aiServer.URL, http.DefaultTransport)
+	bot.maxStories = 5
+	bot.SetDedupPolicy(DedupPolicy{Mode: DedupExclude})
+	ctx := context.Background()
+
+	require.NoError(t, bot.ProcessDailySummary(ctx, "2025-01-10", 5))
+
+	// 重新发送同样按去重策略处理
+	require.NoError(t, bot.ResendDailySummary(ctx, bot.chatID, "2025-01-10", false))
+	sent := fake.sent()
+	require.Len(t, sent, 2)
+	assert.Contains(t, sent[1].Params["text"], "都已推送过")
+
+	// 明确要求完整总结时包含刚推送过的故事
+	require.NoError(t, bot.ResendDailySummary(ctx, bot.chatID, "2025-01-10", true))
+	sent = fake.sent()
+	require.Len(t, sent, 3)
+	assert.Contains(t, sent[2].Params["text"], "译：Rust in the kernel")
+	assert.Contains(t, sent[2].Params["text"], "译：SQLite turns 25")
+	assert.NotContains(t, sent[2].Params["text"], "都已推送过")
+}
+
+func TestDedupAppliesToDigestCommand(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	bot.SetDedupPolicy(DedupPolicy{Mode: DedupExclude})
+	ctx := context.Background()
+
+	summary := scoredDigest("2025-01-10", 2, nil)
+	require.NoError(t, bot.store.SaveDailySummary(summary))
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, summary))
+
+	bot.HandleUserMessage(ctx, tgbotapi.Update{Message: newCommand("/digest 2025-01-10")})
+	bot.HandleUserMessage(ctx, tgbotapi.Update{Message: newCommand("/digest 2025-01-10 full")})
+
+	sent := fake.sent()
+	require.Len(t, sent, 3)
+	assert.Contains(t, sent[1].Params["text"], "都已推送过")
+	assert.Contains(t, sent[2].Params["text"], "故事 1")
+	assert.Contains(t, sent[2].Params["text"], "故事 2")
+}
+
+func TestDedupMarkRepeats(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	bot.SetDedupPolicy(DedupPolicy{Mode: DedupMark})
+	ctx := context.Background()
+
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, scoredDigest("2025-01-09", 2, map[int]int{1: 100, 2: 80})))
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, scoredDigest("2025-01-10", 3, map[int]int{1: 220, 2: 60, 3: 50})))
+
+	sent := fake.sent()
+	require.Len(t, sent, 2)
+	text := sent[1].Params["text"]
+	assert.Contains(t, text, "<b>故事 1</b></a> 🔥 持续热门（比上次推送 ↑ 120 分）")
+	// 分数下降时只标注持续热门
+	assert.Contains(t, text, "<b>故事 2</b></a> 🔥 持续热门\n")
+	assert.Contains(t, text, "[3] ")
+	assert.Equal(t, 2, strings.Count(text, "持续热门"))
+
+	// 再次发送时与最近一次发送的分数比较
+	sentStories, err := bot.store.GetSentStories(bot.chatID, []int{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, 220, sentStories[1].Score)
+	assert.Equal(t, 50, sentStories[3].Score)
+}
+
+func TestDedupIgnoresOldSends(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	bot.SetDedupPolicy(DedupPolicy{Mode: DedupExclude, Days: 3})
+
+	require.NoError(t, bot.store.SaveSentStories(bot.chatID, []storage.SentStory{
+		{StoryID: 1, SentAt: time.Now().AddDate(0, 0, -4).Unix()},
+		{StoryID: 2, SentAt: time.Now().AddDate(0, 0, -1).Unix()},
+	}))
+	require.NoError(t, bot.SendDailySummaryWithNumbers(context.Background(), scoredDigest("2025-01-10", 2, nil)))
+
+	sent := fake.sent()
+	require.Len(t, sent, 1)
+	assert.Contains(t, sent[0].Params["text"], "故事 1")
+	assert.NotContains(t, sent[0].Params["text"], "故事 2")
+}
+
+func TestDedupSkipsRollupsAndOff(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	ctx := context.Background()
+
+	// 未设置时不记录也不处理
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, scoredDigest("2025-01-09", 2, nil)))
+	sentStories, err := bot.store.GetSentStories(bot.chatID, []int{1, 2})
+	require.NoError(t, err)
+	assert.Empty(t, sentStories)
+
+	bot.SetDedupPolicy(DedupPolicy{Mode: DedupExclude})
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, scoredDigest("2025-01-10", 2, nil)))
+
+	// 周报汇总的正是已发送的故事，完整发送
+	weekly := scoredDigest("2025-01-06~2025-01-12", 2, nil)
+	weekly.Period = PeriodWeekly
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, weekly))
+
+	sent := fake.sent()
+	require.Len(t, sent, 3)
+	assert.Contains(t, sent[2].Params["text"], "故事 1")
+	assert.Contains(t, sent[2].Params["text"], "故事 2")
+}
+
+func TestDedupFillsSubscriberLimitWithNewStories(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	bot.SetDedupPolicy(DedupPolicy{Mode: DedupExclude})
+	ctx := context.Background()
+	require.NoError(t, bot.store.SaveSubscriber(&storage.Subscriber{ChatID: 2, MaxStories: 1}))
+
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, scoredDigest("2025-01-09", 1, nil)))
+	require.NoError(t, bot.SendDailySummaryWithNumbers(ctx, scoredDigest("2025-01-10", 2, nil)))
+
+	var texts []string
+	for _, message := range fake.sent() {
+		if message.Params["chat_id"] == "2" {
+			texts = append(texts, message.Params["text"])
+		}
+	}
+	require.Len(t, texts, 2)
+	assert.Contains(t, texts[1], "故事 2")
+	assert.NotContains(t, texts[1], "故事 1")
+}
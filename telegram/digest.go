@@ -0,0 +1,19 @@
+package telegram
+
+import "hacker-news-daily/hackernews"
+
+// 汇总已保存的每日总结的摘要周期
+const (
+	PeriodWeekly  = "weekly"  // 截止日期前 7 天
+	PeriodMonthly = "monthly" // 截止日期所在月份的 1 日到截止日期
+)
+
+// Digest 按计划生成的摘要，默认每日总结的所有字段均为零值
+type Digest struct {
+	Name       string                 // 唯一名称，用于保存和查找，为空时为默认每日总结
+	Title      string                 // 消息标题，为空时使用 hackernews.DefaultTitle，周报和月报使用各自的默认标题
+	Source     hackernews.StorySource // 故事来源，为空时使用机器人的每日总结来源，周报和月报不使用
+	Days       int                    // 包括截止日期前多少天的故事，不大于 0 时为 1 天，周报和月报不使用
+	MaxStories int                    // 故事数量，不大于 0 时使用机器人配置的数量
+	Period     string                 // weekly 或 monthly 时汇总周期内已保存的每日总结，为空时从故事来源获取
+}
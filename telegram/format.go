@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/publisher"
+)
+
+var (
+	// inlineMarkdown 匹配模型输出中常见的行内格式：代码、粗体和链接
+	inlineMarkdown = regexp.MustCompile("`([^`\n]+)`|\\*\\*([^*\n]+?)\\*\\*|\\[([^\\]\n]+)\\]\\((https?://[^)\\s]+)\\)")
+	headingLine    = regexp.MustCompile(`^#{1,6}\s+(.+)$`)
+	bulletLine     = regexp.MustCompile(`^(\s*)[-*]\s+`)
+	htmlTag        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// escapeHTML 转义 Telegram HTML 模式中的特殊字符
+func escapeHTML(text string) string {
+	return tgbotapi.EscapeText(tgbotapi.ModeHTML, text)
+}
+
+// escapeAttr 转义 HTML 属性值
+func escapeAttr(text string) string {
+	return strings.ReplaceAll(escapeHTML(text), `"`, "&quot;")
+}
+
+// link 生成 HTML 链接，地址为空时只输出文本
+func link(url, text string) string {
+	if url == "" {
+		return text
+	}
+	return fmt.Sprintf(`<a href="%s">%s</a>`, escapeAttr(url), text)
+}
+
+// formatMarkdown 将模型输出的 Markdown 转换为 Telegram HTML：
+// 转义特殊字符，粗体、行内代码和链接转换为对应标签，标题转换为粗体，列表符号转换为圆点
+func formatMarkdown(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if match := headingLine.FindStringSubmatch(line); match != nil {
+			lines[i] = "<b>" + formatInline(strings.Trim(match[1], "* ")) + "</b>"
+			continue
+		}
+		line = bulletLine.ReplaceAllString(line, "$1• ")
+		lines[i] = formatInline(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatInline 转换单行中的行内格式，未匹配的部分原样转义
+func formatInline(line string) string {
+	var result strings.Builder
+	last := 0
+	for _, match := range inlineMarkdown.FindAllStringSubmatchIndex(line, -1) {
+		result.WriteString(escapeHTML(line[last:match[0]]))
+		switch {
+		case match[2] >= 0:
+			result.WriteString("<code>" + escapeHTML(line[match[2]:match[3]]) + "</code>")
+		case match[4] >= 0:
+			result.WriteString("<b>" + escapeHTML(line[match[4]:match[5]]) + "</b>")
+		default:
+			result.WriteString(link(line[match[8]:match[9]], escapeHTML(line[match[6]:match[7]])))
+		}
+		last = match[1]
+	}
+	result.WriteString(escapeHTML(line[last:]))
+	return result.String()
+}
+
+// formatStories 将每日总结的故事格式化为 HTML 段落，每个故事一段，
+// 标题链接到原文，并附带 HN 讨论链接
+func formatStories(summary *hackernews.DailySummaryWithNumbers) []string {
+	items := publisher.Items(summary)
+	paragraphs := make([]string, 0, len(items))
+	for _, item := range items {
+		paragraph := fmt.Sprintf("[%d] %s %s", item.Number, link(item.URL, "<b>"+escapeHTML(item.Title)+"</b>"), formatMarkdown(item.Summary))
+		if item.HackerNewsURL != "" && item.HackerNewsURL != item.URL {
+			paragraph += "\n💬 " + link(item.HackerNewsURL, "HN 讨论")
+		}
+		paragraphs = append(paragraphs, paragraph)
+	}
+	return paragraphs
+}
+
+// isParseError 判断 Telegram 是否因为无法解析格式实体而拒绝了消息
+func isParseError(err error) bool {
+	var tgErr *tgbotapi.Error
+	return errors.As(err, &tgErr) && strings.Contains(tgErr.Message, "can't parse entities")
+}
+
+// plainText 去掉 HTML 标签并还原转义字符，用于格式解析失败时的降级发送
+func plainText(text string) string {
+	return html.UnescapeString(htmlTag.ReplaceAllString(text, ""))
+}
@@ -0,0 +1,46 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/hackernews"
+)
+
+func TestFormatMarkdown(t *testing.T) {
+	assert.Equal(t, "<b>Go</b> 在 1 &lt; 2 &amp;&amp; 3 &gt; 2 时", formatMarkdown("**Go** 在 1 < 2 && 3 > 2 时"))
+	assert.Equal(t, "使用 <code>a **b** &lt;c&gt;</code>", formatMarkdown("使用 `a **b** <c>`"))
+	assert.Equal(t, `见 <a href="https://example.com/?a=1&amp;b=&quot;2&quot;">文档 &lt;1&gt;</a>`, formatMarkdown(`见 [文档 <1>](https://example.com/?a=1&b="2")`))
+	assert.Equal(t, "<b>核心观点</b>\n• 第一点\n  • 子项", formatMarkdown("## **核心观点**\n- 第一点\n  * 子项"))
+
+	// 不完整的标记保持原样
+	assert.Equal(t, "5 * 3 = 15, **未闭合", formatMarkdown("5 * 3 = 15, **未闭合"))
+	assert.Equal(t, "[文本](javascript:alert(1))", formatMarkdown("[文本](javascript:alert(1))"))
+}
+
+func TestFormatStories(t *testing.T) {
+	summary := &hackernews.DailySummaryWithNumbers{
+		Stories: []hackernews.Story{
+			{ID: 1, Title: "Go 1.22", URL: "https://go.dev/blog/go1.22", HackerNewsURL: "https://news.ycombinator.com/item?id=1"},
+			{ID: 2, Title: "Ask HN: <Anything>", HackerNewsURL: "https://news.ycombinator.com/item?id=2"},
+		},
+		StorySummaries: []hackernews.StoryWithNumber{
+			{Number: 1, StoryID: 1, Summary: "**Go 1.22 发布** 修改了 <for> 循环"},
+			{Number: 2, StoryID: 2, Title: "Ask HN: <Anything>", Summary: "大家的讨论"},
+		},
+	}
+
+	paragraphs := formatStories(summary)
+	require.Len(t, paragraphs, 2)
+	assert.Equal(t, `[1] <a href="https://go.dev/blog/go1.22"><b>Go 1.22 发布</b></a> 修改了 &lt;for&gt; 循环`+
+		"\n💬 "+`<a href="https://news.ycombinator.com/item?id=1">HN 讨论</a>`, paragraphs[0])
+
+	// 没有外链的故事标题直接指向 HN 讨论，不重复附加链接
+	assert.Equal(t, `[2] <a href="https://news.ycombinator.com/item?id=2"><b>Ask HN: &lt;Anything&gt;</b></a> 大家的讨论`, paragraphs[1])
+}
+
+func TestPlainText(t *testing.T) {
+	assert.Equal(t, "[1] Go 1.22 & <for>\n💬 HN 讨论", plainText(`[1] <a href="https://go.dev"><b>Go 1.22</b></a> &amp; &lt;for&gt;`+"\n💬 <a href=\"https://news.ycombinator.com\">HN 讨论</a>"))
+}
@@ -0,0 +1,125 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/storage"
+)
+
+// digestDateFor 确定消息中故事编号所属的总结：优先使用所回复的总结消息，
+// 其次使用最近一次保存的每日总结，都没有时使用今天。返回总结的存储键，每日总结的存储键即日期
+func (b *Bot) digestDateFor(message *tgbotapi.Message) string {
+	if reply := message.ReplyToMessage; reply != nil {
+		return b.digestKeyFor(message.Chat.ID, reply.MessageID)
+	}
+	return b.latestDigestDate()
+}
+
+// digestKeyFor 返回总结消息对应的总结存储键，不是总结消息时使用最近一次保存的每日总结
+func (b *Bot) digestKeyFor(chatID int64, messageID int) string {
+	key, err := b.store.GetDigestMessage(chatID, messageID)
+	if err == nil {
+		return key
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Failed to get digest date for message %d: %v", messageID, err)
+	}
+	return b.latestDigestDate()
+}
+
+// latestDigestDate 返回最近一次保存的每日总结的日期，没有时返回今天
+func (b *Bot) latestDigestDate() string {
+	dates, err := b.store.ListDates()
+	if err != nil {
+		log.Printf("Failed to list digest dates: %v", err)
+	}
+	if len(dates) > 0 {
+		return dates[len(dates)-1]
+	}
+	return b.today()
+}
+
+// today 返回 Hacker News 客户端时区中今天的日期，未设置客户端时使用本地时区
+func (b *Bot) today() string {
+	if b.hnClient == nil {
+		return time.Now().Format("2006-01-02")
+	}
+	return b.hnClient.Today()
+}
+
+// handleStoryCommand 处理 /story [日期] <编号> 命令
+func (b *Bot) handleStoryCommand(ctx context.Context, message *tgbotapi.Message) {
+	const usage = "用法: /story [日期] <编号>，例如 /story 2025-01-10 3"
+
+	args := strings.Fields(message.CommandArguments())
+	var date, numberArg string
+	switch len(args) {
+	case 1:
+		date, numberArg = b.digestDateFor(message), args[0]
+	case 2:
+		date, numberArg = args[0], args[1]
+		if !validDate(date) {
+			b.sendReply(ctx, message, fmt.Sprintf("❌ 日期格式应为 YYYY-MM-DD: %s\n\n%s", date, usage))
+			return
+		}
+	default:
+		b.sendReply(ctx, message, usage)
+		return
+	}
+
+	storyNumber, err := strconv.Atoi(numberArg)
+	if err != nil || storyNumber <= 0 {
+		b.sendReply(ctx, message, fmt.Sprintf("❌ 故事编号应为正整数: %s\n\n%s", numberArg, usage))
+		return
+	}
+
+	b.handleStoryRequest(ctx, message, storyNumber, date)
+}
+
+// handleDigestCommand 处理 /digest [日期] [full] 命令，从存储中重新发送某一天的每日总结；
+// 默认按去重策略处理已发送过的故事，带 full 时发送完整总结
+func (b *Bot) handleDigestCommand(ctx context.Context, message *tgbotapi.Message) {
+	args := strings.Fields(message.CommandArguments())
+	full := len(args) > 0 && strings.EqualFold(args[len(args)-1], "full")
+	if full {
+		args = args[:len(args)-1]
+	}
+
+	date := strings.Join(args, " ")
+	if date == "" {
+		date = b.digestDateFor(message)
+	} else if !validDate(date) {
+		b.sendReply(ctx, message, fmt.Sprintf("❌ 日期格式应为 YYYY-MM-DD: %s\n\n用法: /digest [日期] [full]，例如 /digest 2025-01-10", date))
+		return
+	}
+
+	summary, err := b.store.GetDailySummary(date)
+	if errors.Is(err, storage.ErrNotFound) {
+		b.sendReply(ctx, message, fmt.Sprintf("❌ 找不到 %s 的每日总结", date))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get daily summary for %s: %v", date, err)
+		b.sendReply(ctx, message, fmt.Sprintf("❌ 读取 %s 的每日总结失败: %v", date, err))
+		return
+	}
+
+	if err := b.deliverTo(ctx, message.Chat.ID, summary, make(map[string][]hackernews.StoryWithNumber), !full); err != nil {
+		log.Printf("Failed to send daily summary for %s: %v", date, err)
+		b.sendReply(ctx, message, fmt.Sprintf("❌ 发送 %s 的每日总结失败: %v", date, err))
+	}
+}
+
+// validDate 检查日期是否为 YYYY-MM-DD 格式
+func validDate(date string) bool {
+	_, err := time.Parse("2006-01-02", date)
+	return err == nil
+}
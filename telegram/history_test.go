@@ -0,0 +1,154 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCommand 构造聊天 1 中的命令消息
+func newCommand(text string) *tgbotapi.Message {
+	command, _, _ := strings.Cut(text, " ")
+	return &tgbotapi.Message{
+		MessageID: 100,
+		Chat:      &tgbotapi.Chat{ID: 1},
+		Text:      text,
+		Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+	}
+}
+
+func TestDigestDateFor(t *testing.T) {
+	bot := newTestBot(t, &fakeTelegram{})
+	message := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 1}}
+
+	// 没有任何总结时使用今天
+	assert.Equal(t, time.Now().Format("2006-01-02"), bot.digestDateFor(message))
+
+	require.NoError(t, bot.store.SaveDailySummary(newDigest("2024-01-14", 1)))
+	require.NoError(t, bot.store.SaveDailySummary(newDigest("2024-01-15", 1)))
+	assert.Equal(t, "2024-01-15", bot.digestDateFor(message))
+
+	// 回复总结消息时使用该消息的日期，其他聊天的记录不影响
+	require.NoError(t, bot.store.SaveDigestMessage(1, 7, "2024-01-14"))
+	require.NoError(t, bot.store.SaveDigestMessage(2, 8, "2024-01-13"))
+	message.ReplyToMessage = &tgbotapi.Message{MessageID: 7}
+	assert.Equal(t, "2024-01-14", bot.digestDateFor(message))
+	message.ReplyToMessage = &tgbotapi.Message{MessageID: 8}
+	assert.Equal(t, "2024-01-15", bot.digestDateFor(message))
+}
+
+func TestReplyToDigestUsesItsDate(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	require.NoError(t, bot.store.SaveDailySummary(newDigest("2024-01-14", 2)))
+	require.NoError(t, bot.store.SaveDailySummary(newDigest("2024-01-15", 1)))
+	require.NoError(t, bot.store.SaveDetailedSummary(2, "", "昨天第二个故事的详细总结"))
+
+	// 发送昨天的总结并记录消息 ID
+	yesterday, err := bot.store.GetDailySummary("2024-01-14")
+	require.NoError(t, err)
+	require.NoError(t, bot.sendDigest(context.Background(), 1, yesterday))
+	require.Len(t, fake.sent(), 1)
+
+	bot.HandleUserMessage(context.Background(), tgbotapi.Update{Message: &tgbotapi.Message{
+		MessageID:      100,
+		Chat:           &tgbotapi.Chat{ID: 1},
+		Text:           "2",
+		ReplyToMessage: &tgbotapi.Message{MessageID: 1},
+	}})
+
+	sent := fake.sent()
+	require.Len(t, sent, 4)
+	assert.Contains(t, sent[1].Params["text"], "2024-01-14 故事 [2]")
+	assert.Contains(t, sent[2].Params["text"], "昨天第二个故事的详细总结")
+}
+
+func TestHandleStoryCommand(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	require.NoError(t, bot.store.SaveDailySummary(newDigest("2024-01-10", 3)))
+	require.NoError(t, bot.store.SaveDetailedSummary(3, "", "第三个故事的详细总结"))
+
+	bot.HandleUserMessage(context.Background(), tgbotapi.Update{Message: newCommand("/story 2024-01-10 3")})
+	sent := fake.sent()
+	require.Len(t, sent, 3)
+	assert.Contains(t, sent[1].Params["text"], "第三个故事的详细总结")
+
+	// 参数错误时返回用法说明
+	for _, text := range []string{"/story", "/story 2024-1-10 3", "/story 2024-01-10 x"} {
+		before := len(fake.sent())
+		bot.HandleUserMessage(context.Background(), tgbotapi.Update{Message: newCommand(text)})
+		sent = fake.sent()
+		require.Len(t, sent, before+1, text)
+		assert.Contains(t, sent[len(sent)-1].Params["text"], "用法: /story", text)
+	}
+}
+
+func TestDetailedSummaryKeyedByModel(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	bot.SetSummaryModel("openai/gpt-4o")
+	require.NoError(t, bot.store.SaveDailySummary(newDigest("2024-01-10", 1)))
+	require.NoError(t, bot.store.SaveDetailedSummary(1, "openai/gpt-3.5", "旧模型的详细总结"))
+	require.NoError(t, bot.store.SaveDetailedSummary(1, "openai/gpt-4o", "当前模型的详细总结"))
+
+	bot.HandleUserMessage(context.Background(), tgbotapi.Update{Message: newCommand("/story 2024-01-10 1")})
+	sent := fake.sent()
+	require.Len(t, sent, 3)
+	assert.Contains(t, sent[1].Params["text"], "当前模型的详细总结")
+	assert.NotContains(t, sent[1].Params["text"], "旧模型")
+}
+
+func TestReplyAfterRegenerationUsesSentDigest(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	require.NoError(t, bot.store.SaveDetailedSummary(1, "", "第一个故事的详细总结"))
+	require.NoError(t, bot.store.SaveDetailedSummary(2, "", "第二个故事的详细总结"))
+
+	first := newDigest("2024-01-15", 2)
+	first.Revision = "first"
+	require.NoError(t, bot.store.SaveDailySummary(first))
+	require.NoError(t, bot.sendDigest(context.Background(), 1, first))
+
+	// 重新生成同一天的总结，故事顺序发生变化
+	second := newDigest("2024-01-15", 2)
+	second.Revision = "second"
+	second.StorySummaries[0].StoryID, second.StorySummaries[1].StoryID = 2, 1
+	require.NoError(t, bot.store.SaveDailySummary(second))
+
+	reply := newCommand("1")
+	reply.ReplyToMessage = &tgbotapi.Message{MessageID: 1}
+	assert.Equal(t, "2024-01-15@first", bot.digestDateFor(reply))
+
+	bot.HandleUserMessage(context.Background(), tgbotapi.Update{Message: reply})
+	sent := fake.sent()
+	require.Len(t, sent, 4)
+	assert.Contains(t, sent[1].Params["text"], "正在为您生成 2024-01-15 故事 [1]")
+	assert.Contains(t, sent[2].Params["text"], "第一个故事的详细总结")
+}
+
+func TestHandleDigestCommand(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	require.NoError(t, bot.store.SaveDailySummary(newDigest("2024-01-10", 2)))
+
+	bot.HandleUserMessage(context.Background(), tgbotapi.Update{Message: newCommand("/digest 2024-01-10")})
+	sent := fake.sent()
+	require.Len(t, sent, 1)
+	assert.Contains(t, sent[0].Params["text"], "Hacker News 每日热点 - 2024-01-10")
+
+	// 重新发送的消息同样记录日期
+	date, err := bot.store.GetDigestMessage(1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-10", date)
+
+	bot.HandleUserMessage(context.Background(), tgbotapi.Update{Message: newCommand("/digest 2024-01-09")})
+	sent = fake.sent()
+	require.Len(t, sent, 2)
+	assert.Contains(t, sent[1].Params["text"], "找不到 2024-01-09 的每日总结")
+}
@@ -0,0 +1,126 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/publisher"
+)
+
+const (
+	storyCallbackPrefix = "story:" // 故事按钮的回调数据前缀，格式为 story:<编号>，早期按钮为 story:<存储键>:<编号>
+	maxKeyboardButtons  = 100      // Telegram 单个内联键盘的按钮数量上限
+	numberButtonsPerRow = 8        // 只显示编号按钮时每行的按钮数量
+)
+
+// digestKeyboard 为每日总结生成内联键盘：每个故事一行，包含查看详细总结、原文和 HN 讨论按钮，
+// 按钮数量超过上限时其余故事只显示编号按钮
+func digestKeyboard(summary *hackernews.DailySummaryWithNumbers) *tgbotapi.InlineKeyboardMarkup {
+	items := publisher.Items(summary)
+	if len(items) == 0 {
+		return nil
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var numberRow []tgbotapi.InlineKeyboardButton
+	buttons := 0
+	for i, item := range items {
+		detail := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📖 %d", item.Number), storyCallbackData(item.Number))
+
+		row := []tgbotapi.InlineKeyboardButton{detail}
+		if item.URL != "" && item.URL != item.HackerNewsURL {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonURL("🔗 原文", item.URL))
+		}
+		if item.HackerNewsURL != "" {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonURL("💬 HN", item.HackerNewsURL))
+		}
+
+		// 为剩余故事的编号按钮预留位置
+		if numberRow == nil && buttons+len(row)+len(items)-i-1 <= maxKeyboardButtons {
+			rows = append(rows, row)
+			buttons += len(row)
+			continue
+		}
+
+		numberRow = append(numberRow, detail)
+		buttons++
+		if len(numberRow) == numberButtonsPerRow {
+			rows = append(rows, numberRow)
+			numberRow = []tgbotapi.InlineKeyboardButton{}
+		}
+	}
+	if len(numberRow) > 0 {
+		rows = append(rows, numberRow)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// storyCallbackData 生成故事按钮的回调数据。Telegram 限制回调数据不超过 64 字节，
+// 因此只包含编号，所属总结由按钮所在的消息确定
+func storyCallbackData(number int) string {
+	return fmt.Sprintf("%s%d", storyCallbackPrefix, number)
+}
+
+// parseStoryCallbackData 解析故事按钮的回调数据，早期按钮的回调数据中带有总结的存储键，
+// 新按钮的 key 为空
+func parseStoryCallbackData(data string) (key string, number int, ok bool) {
+	rest, found := strings.CutPrefix(data, storyCallbackPrefix)
+	if !found {
+		return "", 0, false
+	}
+	numberStr := rest
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		key, numberStr = rest[:i], rest[i+1:]
+		if key == "" {
+			return "", 0, false
+		}
+	}
+	number, err := strconv.Atoi(numberStr)
+	if err != nil {
+		return "", 0, false
+	}
+	return key, number, true
+}
+
+// handleCallbackQuery 处理内联键盘按钮的回调，发送对应故事的详细总结
+func (b *Bot) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	if query.Message == nil {
+		return
+	}
+	chatID := query.Message.Chat.ID
+	log.Printf("Received callback from chat %d: %s", chatID, query.Data)
+
+	key, storyNumber, ok := parseStoryCallbackData(query.Data)
+	if !ok || !b.isAuthorized(chatID) {
+		b.answerCallback(query.ID, "")
+		return
+	}
+	if key == "" {
+		key = b.digestKeyFor(chatID, query.Message.MessageID)
+	}
+
+	// 先应答回调，避免按钮一直显示加载状态
+	b.answerCallback(query.ID, fmt.Sprintf("🔄 正在生成故事 [%d] 的详细总结...", storyNumber))
+
+	if err := b.SendDetailedSummary(ctx, chatID, storyNumber, key); err != nil {
+		log.Printf("Failed to send detailed summary: %v", err)
+		errorMsg := fmt.Sprintf("❌ 获取故事 [%d] 的详细总结失败: %v", storyNumber, err)
+		if err := b.sendMessageTo(ctx, chatID, errorMsg); err != nil {
+			log.Printf("Failed to send error message: %v", err)
+		}
+	}
+}
+
+// answerCallback 应答回调查询，text 非空时在客户端显示提示
+func (b *Bot) answerCallback(queryID, text string) {
+	if _, err := b.api.Request(tgbotapi.NewCallback(queryID, text)); err != nil {
+		log.Printf("Failed to answer callback query: %v", err)
+	}
+}
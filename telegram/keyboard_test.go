@@ -0,0 +1,166 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/hackernews"
+)
+
+// newDigest 生成包含 n 个故事的每日总结，偶数编号的故事没有外链
+func newDigest(date string, n int) *hackernews.DailySummaryWithNumbers {
+	summary := &hackernews.DailySummaryWithNumbers{Date: date}
+	for i := 1; i <= n; i++ {
+		story := hackernews.Story{ID: i, Title: fmt.Sprintf("Story %d", i), HackerNewsURL: fmt.Sprintf("https://news.ycombinator.com/item?id=%d", i)}
+		if i%2 == 1 {
+			story.URL = fmt.Sprintf("https://example.com/%d", i)
+		}
+		summary.Stories = append(summary.Stories, story)
+		summary.StorySummaries = append(summary.StorySummaries, hackernews.StoryWithNumber{
+			Number: i, StoryID: i, Title: story.Title, Summary: fmt.Sprintf("**故事 %d** 总结", i),
+		})
+	}
+	return summary
+}
+
+func TestDigestKeyboard(t *testing.T) {
+	keyboard := digestKeyboard(newDigest("2024-01-15", 2))
+	require.NotNil(t, keyboard)
+	require.Len(t, keyboard.InlineKeyboard, 2)
+
+	row := keyboard.InlineKeyboard[0]
+	require.Len(t, row, 3)
+	assert.Equal(t, "📖 1", row[0].Text)
+	assert.Equal(t, "story:1", *row[0].CallbackData)
+	assert.Equal(t, "https://example.com/1", *row[1].URL)
+	assert.Equal(t, "https://news.ycombinator.com/item?id=1", *row[2].URL)
+
+	// 没有外链的故事不显示原文按钮
+	require.Len(t, keyboard.InlineKeyboard[1], 2)
+	assert.Equal(t, "https://news.ycombinator.com/item?id=2", *keyboard.InlineKeyboard[1][1].URL)
+
+	assert.Nil(t, digestKeyboard(newDigest("2024-01-15", 0)))
+}
+
+func TestDigestKeyboardButtonLimit(t *testing.T) {
+	keyboard := digestKeyboard(newDigest("2024-01-15", 60))
+	require.NotNil(t, keyboard)
+
+	buttons := 0
+	numbers := make(map[string]bool)
+	for _, row := range keyboard.InlineKeyboard {
+		buttons += len(row)
+		for _, button := range row {
+			if button.CallbackData != nil {
+				numbers[*button.CallbackData] = true
+			}
+		}
+	}
+	assert.LessOrEqual(t, buttons, maxKeyboardButtons)
+	assert.Len(t, numbers, 60)
+
+	// 超出部分每行只显示编号按钮
+	last := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1]
+	assert.Equal(t, "story:60", *last[len(last)-1].CallbackData)
+}
+
+func TestParseStoryCallbackData(t *testing.T) {
+	key, number, ok := parseStoryCallbackData(storyCallbackData(12))
+	assert.True(t, ok)
+	assert.Empty(t, key)
+	assert.Equal(t, 12, number)
+
+	// 早期按钮带有总结的存储键
+	key, number, ok = parseStoryCallbackData("story:show_hn_weekly/2024-01-15:3")
+	assert.True(t, ok)
+	assert.Equal(t, "show_hn_weekly/2024-01-15", key)
+	assert.Equal(t, 3, number)
+
+	for _, data := range []string{"", "story:", "story::1", "story:2024-01-15", "story:2024-01-15:x", "other:2024-01-15:1"} {
+		_, _, ok := parseStoryCallbackData(data)
+		assert.False(t, ok, data)
+	}
+}
+
+func TestSendDigestAttachesKeyboard(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+
+	require.NoError(t, bot.sendDigest(context.Background(), 1, newDigest("2024-01-15", 2)))
+
+	sent := fake.sent()
+	require.Len(t, sent, 1)
+	var markup tgbotapi.InlineKeyboardMarkup
+	require.NoError(t, json.Unmarshal([]byte(sent[0].Params["reply_markup"]), &markup))
+	assert.Len(t, markup.InlineKeyboard, 2)
+}
+
+func TestHandleCallbackQuery(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	require.NoError(t, bot.store.SaveDailySummary(newDigest("2024-01-14", 2)))
+	require.NoError(t, bot.store.SaveDetailedSummary(2, "", "第二个故事的详细总结"))
+
+	bot.handleCallbackQuery(context.Background(), &tgbotapi.CallbackQuery{
+		ID:      "query",
+		Data:    "story:2024-01-14:2",
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 1}},
+	})
+
+	sent := fake.sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "answerCallbackQuery", sent[0].Method)
+	assert.Equal(t, "query", sent[0].Params["callback_query_id"])
+	assert.Equal(t, "sendMessage", sent[1].Method)
+	assert.Contains(t, sent[1].Params["text"], "第二个故事的详细总结")
+	assert.Contains(t, sent[1].Params["text"], "故事 [2]")
+}
+
+func TestHandleCallbackQueryUsesMessageDigest(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	require.NoError(t, bot.store.SaveDetailedSummary(2, "", "第二个故事的详细总结"))
+
+	// 摘要名称较长时回调数据也不超过 Telegram 的 64 字节限制
+	digest := newDigest("2024-01-10~2024-01-14", 2)
+	digest.Digest = "a_rather_long_digest_name_for_show_hn_weekly"
+	require.NoError(t, bot.store.SaveDailySummary(digest))
+	require.NoError(t, bot.store.SaveDailySummary(newDigest("2024-01-15", 1)))
+	require.NoError(t, bot.sendDigest(context.Background(), 1, digest))
+
+	var markup tgbotapi.InlineKeyboardMarkup
+	require.NoError(t, json.Unmarshal([]byte(fake.sent()[0].Params["reply_markup"]), &markup))
+	data := *markup.InlineKeyboard[1][0].CallbackData
+	assert.LessOrEqual(t, len(data), 64)
+
+	bot.handleCallbackQuery(context.Background(), &tgbotapi.CallbackQuery{
+		ID:      "query",
+		Data:    data,
+		Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: 1}},
+	})
+
+	sent := fake.sent()
+	require.Len(t, sent, 3)
+	assert.Contains(t, sent[2].Params["text"], "第二个故事的详细总结")
+}
+
+func TestHandleCallbackQueryUnauthorized(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+
+	bot.handleCallbackQuery(context.Background(), &tgbotapi.CallbackQuery{
+		ID:      "query",
+		Data:    "story:2024-01-14:2",
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 99}},
+	})
+
+	sent := fake.sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "answerCallbackQuery", sent[0].Method)
+}
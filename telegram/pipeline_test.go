@@ -0,0 +1,182 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/ai"
+	"hacker-news-daily/ai/aitest"
+	"hacker-news-daily/fixture"
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/hackernews/hntest"
+	"hacker-news-daily/storage"
+)
+
+// storyPattern 匹配总结提示词中每个故事的编号、ID 和标题
+var storyPattern = regexp.MustCompile(`故事 (\d+):\nID: (\d+)\n标题: ([^\n]*)`)
+
+// summarizeResponder 按提示词中的故事返回 JSON 格式的带编号总结
+func summarizeResponder(req aitest.Request) string {
+	type item struct {
+		Number  int    `json:"number"`
+		StoryID int    `json:"story_id"`
+		Title   string `json:"title"`
+		Summary string `json:"summary"`
+	}
+	var items []item
+	for _, match := range storyPattern.FindAllStringSubmatch(req.UserPrompt(), -1) {
+		number, _ := strconv.Atoi(match[1])
+		id, _ := strconv.Atoi(match[2])
+		items = append(items, item{Number: number, StoryID: id, Title: "译：" + match[3], Summary: "关于 " + match[3] + " 的总结"})
+	}
+	data, _ := json.Marshal(map[string]any{"stories": items})
+	return string(data)
+}
+
+// newHNServer 创建包含两个 2025-01-10 发布的带评论故事的模拟 HN 服务器
+func newHNServer(t *testing.T) *hntest.Server {
+	server := hntest.NewServer(t)
+	day := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	for i, title := range []string{"Rust in the kernel", "SQLite turns 25"} {
+		id := 1000 + i
+		server.AddItem(hntest.Item{ID: id*10 + 1, By: "commenter", Text: fmt.Sprintf("comment on %s", title), Parent: id})
+		server.AddStory(hntest.Item{
+			ID:    id,
+			Title: title,
+			By:    "author",
+			Score: 100 * (i + 1),
+			Time:  day.Add(time.Duration(i+1) * time.Hour).Unix(),
+			Text:  "story text",
+			Kids:  []int{id*10 + 1},
+		})
+	}
+	return server
+}
+
+// newPipelineBot 创建通过指定 Transport 访问模拟服务的机器人
+func newPipelineBot(t *testing.T, fake *fakeTelegram, hnURL, aiURL string, transport http.RoundTripper) *Bot {
+	hnClient := hackernews.NewClient(5, 10, 5)
+	hnClient.SetBaseURLs(hnURL+"/api/v1", hnURL+"/v0")
+	hnClient.SetTransport(transport)
+
+	aiClient := ai.NewClient(aiURL, "", "test-model", 1000)
+	aiClient.SetTransport(transport)
+
+	bot := newTestBot(t, fake)
+	bot.SetClients(aiClient, hnClient)
+	return bot
+}
+
+func TestProcessDailySummaryOffline(t *testing.T) {
+	hnServer := newHNServer(t)
+	aiServer := aitest.NewServer(t, summarizeResponder)
+	dir := t.TempDir()
+
+	// 录制：请求发往模拟服务器并保存响应
+	recorded := &fakeTelegram{}
+	bot := newPipelineBot(t, recorded, hnServer.URL, aiServer.URL, fixture.NewTransport(fixture.Record, dir, nil))
+	require.NoError(t, bot.ProcessDailySummary(context.Background(), "2025-01-10", 5))
+
+	sent := recorded.sent()
+	require.Len(t, sent, 1)
+	text := sent[0].Params["text"]
+	assert.Contains(t, text, "Hacker News 每日热点 - 2025-01-10")
+	assert.Contains(t, text, "译：Rust in the kernel")
+	assert.Contains(t, text, "关于 SQLite turns 25 的总结")
+
+	// 故事内容包含评论，并保存供详细总结使用
+	content, err := bot.store.GetStoryContent(1000)
+	require.NoError(t, err)
+	assert.Contains(t, content, "comment on Rust in the kernel")
+
+	require.Len(t, aiServer.Requests(), 1)
+	prompt := aiServer.Requests()[0].UserPrompt()
+	assert.Contains(t, prompt, "comment on SQLite turns 25")
+
+	// 回放：服务器关闭后只使用录制的响应，结果与录制时一致
+	hnServer.Close()
+	aiServer.Close()
+
+	replayed := &fakeTelegram{}
+	bot = newPipelineBot(t, replayed, hnServer.URL, aiServer.URL, fixture.NewTransport(fixture.Replay, dir, nil))
+	require.NoError(t, bot.ProcessDailySummary(context.Background(), "2025-01-10", 5))
+
+	require.Len(t, replayed.sent(), 1)
+	assert.Equal(t, text, replayed.sent()[0].Params["text"])
+}
+
+func TestProcessDigestWithSource(t *testing.T) {
+	hnServer := newHNServer(t)
+	day := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	hnServer.AddItem(
+		hntest.Item{ID: 2000, Type: "story", Title: "Show HN: A tiny database", By: "maker", Time: day.AddDate(0, 0, -3).Unix()},
+		hntest.Item{ID: 2001, Type: "story", Title: "Show HN: Too old", By: "maker", Time: day.AddDate(0, 0, -10).Unix()},
+	)
+	aiServer := aitest.NewServer(t, summarizeResponder)
+
+	fake := &fakeTelegram{}
+	bot := newPipelineBot(t, fake, hnServer.URL, aiServer.URL, http.DefaultTransport)
+	source, err := bot.hnClient.Source(hackernews.SourceShowHN)
+	require.NoError(t, err)
+
+	digest := Digest{Name: "show_hn_weekly", Title: "Show HN 每周精选", Source: source, Days: 7, MaxStories: 5}
+	require.NoError(t, bot.ProcessDigest(context.Background(), digest, "2025-01-10"))
+
+	sent := fake.sent()
+	require.Len(t, sent, 1)
+	assert.Contains(t, sent[0].Params["text"], "Show HN 每周精选 - 2025-01-10")
+	assert.Contains(t, sent[0].Params["text"], "Show HN: A tiny database")
+	assert.NotContains(t, sent[0].Params["text"], "Rust in the kernel")
+
+	// 与同一天的每日总结分开保存，回复摘要消息时在该摘要中查找故事
+	summary, err := bot.store.GetDailySummary("show_hn_weekly/2025-01-10")
+	require.NoError(t, err)
+	assert.Equal(t, "show_hn_weekly", summary.Digest)
+	require.Len(t, summary.Stories, 1)
+	_, err = bot.store.GetDailySummary("2025-01-10")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	reply := newCommand("/story 1")
+	reply.ReplyToMessage = &tgbotapi.Message{MessageID: 1}
+	assert.Equal(t, summary.SnapshotKey(), bot.digestDateFor(reply))
+	assert.True(t, strings.HasPrefix(summary.SnapshotKey(), "show_hn_weekly/2025-01-10@"))
+}
+
+func TestProcessDigestRange(t *testing.T) {
+	hnServer := newHNServer(t)
+	hnServer.AddStory(hntest.Item{ID: 3000, Title: "Earlier in the week", By: "author", Score: 50, Time: time.Date(2025, 1, 7, 12, 0, 0, 0, time.UTC).Unix()})
+	hnServer.AddStory(hntest.Item{ID: 3001, Title: "Before the range", By: "author", Score: 500, Time: time.Date(2025, 1, 5, 23, 59, 0, 0, time.UTC).Unix()})
+	aiServer := aitest.NewServer(t, summarizeResponder)
+
+	fake := &fakeTelegram{}
+	bot := newPipelineBot(t, fake, hnServer.URL, aiServer.URL, http.DefaultTransport)
+	require.NoError(t, bot.ProcessDigestRange(context.Background(), Digest{MaxStories: 5}, "2025-01-06", "2025-01-10"))
+
+	sent := fake.sent()
+	require.Len(t, sent, 1)
+	text := sent[0].Params["text"]
+	assert.Contains(t, text, "Hacker News 每日热点 - 2025-01-06~2025-01-10")
+	assert.Contains(t, text, "Earlier in the week")
+	assert.Contains(t, text, "SQLite turns 25")
+	assert.NotContains(t, text, "Before the range")
+
+	// 按日期范围保存，不覆盖范围内某一天的每日总结
+	summary, err := bot.store.GetDailySummary("2025-01-06~2025-01-10")
+	require.NoError(t, err)
+	assert.Len(t, summary.Stories, 3)
+	_, err = bot.store.GetDailySummary("2025-01-10")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	assert.Error(t, bot.ProcessDigestRange(context.Background(), Digest{}, "2025-01-10", "2025-01-06"))
+}
@@ -0,0 +1,131 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"hacker-news-daily/hackernews"
+)
+
+// defaultPeriodTitles 周报和月报未配置标题时使用的标题
+var defaultPeriodTitles = map[string]string{
+	PeriodWeekly:  "Hacker News 每周回顾",
+	PeriodMonthly: "Hacker News 每月回顾",
+}
+
+// ValidatePeriod 检查摘要周期是否有效，为空表示不是周报或月报
+func ValidatePeriod(period string) error {
+	switch period {
+	case "", PeriodWeekly, PeriodMonthly:
+		return nil
+	default:
+		return fmt.Errorf("unknown digest period %q, available: %s, %s", period, PeriodWeekly, PeriodMonthly)
+	}
+}
+
+// rollupWindow 返回周报或月报的日期标签和时间段：截止到 date 当天，date 为空时截止到昨天，
+// 周报包括截止日期前 7 天，月报从截止日期所在月份的 1 日开始
+func (b *Bot) rollupWindow(period, date string) (string, hackernews.Window, error) {
+	if err := ValidatePeriod(period); err != nil {
+		return "", hackernews.Window{}, err
+	}
+
+	to := date
+	if to == "" {
+		today, _ := time.Parse("2006-01-02", b.hnClient.Today())
+		to = today.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return "", hackernews.Window{}, fmt.Errorf("invalid date format: %s", to)
+	}
+
+	start := end.AddDate(0, 0, -6)
+	if period == PeriodMonthly {
+		start = time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	from := start.Format("2006-01-02")
+
+	window, err := b.hnClient.RangeWindow(from, to)
+	return hackernews.RangeLabel(from, to), window, err
+}
+
+// generateRollup 汇总时间段内已保存的每日总结，按 Story.ID 去重后挑选分数最高的 maxStories 个故事，
+// 沿用每日总结中的故事总结，并让 AI 生成这一时期的趋势概述
+func (b *Bot) generateRollup(ctx context.Context, label string, window hackernews.Window, maxStories int) (*hackernews.DailySummaryWithNumbers, error) {
+	dates, err := b.store.ListDates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daily summaries: %w", err)
+	}
+
+	stories := make(map[int]hackernews.Story)
+	summaries := make(map[int]hackernews.StoryWithNumber)
+	var days int
+	for _, date := range dates {
+		day, err := b.hnClient.DayWindow(date, 1)
+		if err != nil || day.Start.Before(window.Start) || day.End.After(window.End) {
+			continue
+		}
+		daily, err := b.store.GetDailySummary(date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read daily summary for %s: %w", date, err)
+		}
+		days++
+
+		// 日期按升序遍历，同一故事出现在多天时保留最高分数和最近一天的总结
+		for _, summary := range daily.StorySummaries {
+			story := findStory(daily.Stories, summary.StoryID)
+			if story == nil {
+				continue
+			}
+			merged := *story
+			if existing, ok := stories[story.ID]; ok && existing.Score > merged.Score {
+				merged.Score = existing.Score
+			}
+			stories[story.ID] = merged
+			summaries[story.ID] = summary
+		}
+	}
+
+	if len(stories) == 0 {
+		log.Printf("No daily summaries stored for %s", label)
+		return nil, nil
+	}
+	log.Printf("Reviewing %d stories from %d daily summaries for %s", len(stories), days, label)
+
+	// 先按 ID 排序，分数和发布时间都相同时结果保持稳定
+	ranked := make([]hackernews.Story, 0, len(stories))
+	for _, story := range stories {
+		ranked = append(ranked, story)
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].ID < ranked[j].ID })
+	hackernews.Ranking{Formula: hackernews.RankByPoints}.Rank(ranked, window.End)
+	if len(ranked) > maxStories {
+		ranked = ranked[:maxStories]
+	}
+
+	storySummaries := make([]hackernews.StoryWithNumber, 0, len(ranked))
+	for i, story := range ranked {
+		summary := summaries[story.ID]
+		summary.Number = i + 1
+		storySummaries = append(storySummaries, summary)
+	}
+
+	log.Println("Generating AI period overview...")
+	summarizeCtx, cancel := context.WithTimeout(ctx, b.timeouts.Summarize)
+	overview, err := b.aiClient.SummarizePeriod(summarizeCtx, storySummaries, label)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize period: %w", err)
+	}
+
+	return &hackernews.DailySummaryWithNumbers{
+		Date:           label,
+		Stories:        ranked,
+		StorySummaries: storySummaries,
+		Overview:       overview,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/ai/aitest"
+	"hacker-news-daily/hackernews"
+)
+
+// saveDaily 保存一份包含指定故事的每日总结，故事总结注明日期
+func saveDaily(t *testing.T, bot *Bot, date string, stories ...hackernews.Story) {
+	t.Helper()
+	summary := &hackernews.DailySummaryWithNumbers{Date: date, Stories: stories}
+	for i, story := range stories {
+		summary.StorySummaries = append(summary.StorySummaries, hackernews.StoryWithNumber{
+			Number:  i + 1,
+			StoryID: story.ID,
+			Title:   story.Title,
+			Summary: fmt.Sprintf("%s 的总结（%s）", story.Title, date),
+		})
+	}
+	require.NoError(t, bot.store.SaveDailySummary(summary))
+}
+
+func TestProcessWeeklyRollup(t *testing.T) {
+	hnServer := newHNServer(t)
+	aiServer := aitest.NewServer(t, func(aitest.Request) string { return "本周 Rust 持续升温 [1]。" })
+	fake := &fakeTelegram{}
+	bot := newPipelineBot(t, fake, hnServer.URL, aiServer.URL, http.DefaultTransport)
+
+	story := func(id, score int, title string) hackernews.Story {
+		return hackernews.Story{ID: id, Title: title, Score: score, URL: fmt.Sprintf("https://example.com/%d", id)}
+	}
+	saveDaily(t, bot, "2025-01-05", story(5, 1000, "Last week"))
+	saveDaily(t, bot, "2025-01-06", story(1, 100, "Rust in the kernel"), story(2, 50, "Minor story"))
+	saveDaily(t, bot, "2025-01-08", story(1, 300, "Rust in the kernel"), story(3, 200, "SQLite turns 25"))
+	saveDaily(t, bot, "2025-01-12", story(4, 10, "Sunday story"))
+	saveDaily(t, bot, "2025-01-13", story(6, 900, "Next week"))
+
+	digest := Digest{Name: "weekly", Period: PeriodWeekly, MaxStories: 2}
+	require.NoError(t, bot.ProcessDigest(context.Background(), digest, "2025-01-12"))
+
+	sent := fake.sent()
+	require.Len(t, sent, 1)
+	text := sent[0].Params["text"]
+	assert.Contains(t, text, "Hacker News 每周回顾 - 2025-01-06~2025-01-12")
+	assert.Contains(t, text, "本周 Rust 持续升温 [1]。")
+	assert.Contains(t, text, "Rust in the kernel 的总结（2025-01-08）")
+	assert.Contains(t, text, "SQLite turns 25")
+	for _, title := range []string{"Minor story", "Sunday story", "Last week", "Next week"} {
+		assert.NotContains(t, text, title)
+	}
+
+	// 按分数去重排序后重新编号，保存在周报自己的键下
+	summary, err := bot.store.GetDailySummary("weekly/2025-01-06~2025-01-12")
+	require.NoError(t, err)
+	require.Len(t, summary.StorySummaries, 2)
+	assert.Equal(t, 1, summary.StorySummaries[0].Number)
+	assert.Equal(t, 1, summary.StorySummaries[0].StoryID)
+	assert.Equal(t, 300, summary.Stories[0].Score)
+	assert.Equal(t, 3, summary.StorySummaries[1].StoryID)
+	assert.Equal(t, "本周 Rust 持续升温 [1]。", summary.Overview)
+
+	// 趋势概述的提示词只包含入选的故事
+	require.Len(t, aiServer.Requests(), 1)
+	prompt := aiServer.Requests()[0].UserPrompt()
+	assert.Contains(t, prompt, "[1] Rust in the kernel")
+	assert.Contains(t, prompt, "[2] SQLite turns 25")
+	assert.NotContains(t, prompt, "Minor story")
+}
+
+func TestRollupWithoutDailySummaries(t *testing.T) {
+	hnServer := newHNServer(t)
+	aiServer := aitest.NewServer(t, func(aitest.Request) string { return "" })
+	fake := &fakeTelegram{}
+	bot := newPipelineBot(t, fake, hnServer.URL, aiServer.URL, http.DefaultTransport)
+
+	require.NoError(t, bot.ProcessDigest(context.Background(), Digest{Name: "monthly", Period: PeriodMonthly}, "2025-01-31"))
+	assert.Empty(t, fake.sent())
+	assert.Empty(t, aiServer.Requests())
+}
+
+func TestRollupWindow(t *testing.T) {
+	bot := newTestBot(t, &fakeTelegram{})
+	bot.SetClients(nil, hackernews.NewClient(5, 5, 5))
+
+	label, window, err := bot.rollupWindow(PeriodMonthly, "2025-02-14")
+	require.NoError(t, err)
+	assert.Equal(t, "2025-02-01~2025-02-14", label)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), window.Start)
+	assert.Equal(t, time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC), window.End)
+
+	// 未指定日期时截止到昨天
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	label, _, err = bot.rollupWindow(PeriodWeekly, "")
+	require.NoError(t, err)
+	assert.Regexp(t, `~`+yesterday+`$`, label)
+
+	_, _, err = bot.rollupWindow("yearly", "2025-02-14")
+	assert.ErrorContains(t, err, "unknown digest period")
+}
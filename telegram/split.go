@@ -0,0 +1,273 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	maxMessageLength  = 4096 // Telegram 单条消息解析格式后的最大长度，按 UTF-16 编码单元计算
+	partMarkerReserve = 12   // 为 "\n\n(12/34)" 分段标记预留的长度
+)
+
+// 分割层级：依次尝试段落、句子、单词边界，最后强制按字符分割
+const (
+	splitParagraph = iota
+	splitSentence
+	splitWord
+	splitRune
+)
+
+const (
+	sentenceTerminators = "。！？；…．"
+	asciiTerminators    = ".!?;"
+	sentenceClosers     = "\"'”’)）」』】》"
+)
+
+type tokenKind int
+
+const (
+	textToken tokenKind = iota
+	openTagToken
+	closeTagToken
+)
+
+// token HTML 消息中的一个标签、转义字符或字符
+type token struct {
+	text string
+	kind tokenKind
+	size int // 解析后的可见长度（UTF-16 编码单元）
+}
+
+// tokenize 将 HTML 消息拆分为标签、转义字符和单个字符
+func tokenize(text string) []token {
+	var tokens []token
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			if end := strings.IndexByte(text[i:], '>'); end > 0 {
+				tag := text[i : i+end+1]
+				kind := openTagToken
+				if strings.HasPrefix(tag, "</") {
+					kind = closeTagToken
+				}
+				tokens = append(tokens, token{text: tag, kind: kind})
+				i += end + 1
+				continue
+			}
+		case '&':
+			if end := strings.IndexByte(text[i:], ';'); end > 1 && end <= 10 {
+				entity := text[i : i+end+1]
+				tokens = append(tokens, token{text: entity, size: utf16Length(html.UnescapeString(entity))})
+				i += end + 1
+				continue
+			}
+		}
+
+		r, width := utf8.DecodeRuneInString(text[i:])
+		tokens = append(tokens, token{text: text[i : i+width], size: utf16.RuneLen(r)})
+		i += width
+	}
+	return tokens
+}
+
+// utf16Length 按 Telegram 的方式计算文本长度
+func utf16Length(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// visibleLength 计算一组 token 解析后的可见长度
+func visibleLength(tokens []token) int {
+	n := 0
+	for _, tok := range tokens {
+		n += tok.size
+	}
+	return n
+}
+
+func join(tokens []token) string {
+	var result strings.Builder
+	for _, tok := range tokens {
+		result.WriteString(tok.text)
+	}
+	return result.String()
+}
+
+// splitMessage 将 HTML 消息分割为不超过 limit 的多条消息，不会在格式标签内部分割，
+// 分割后的每条消息末尾带有 "(1/3)" 形式的分段标记
+func splitMessage(text string, limit int) []string {
+	tokens := tokenize(text)
+	if visibleLength(tokens) <= limit {
+		return []string{text}
+	}
+
+	parts := pack(tokens, limit-partMarkerReserve, splitParagraph)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%s\n\n(%d/%d)", parts[i], i+1, len(parts))
+	}
+	return parts
+}
+
+// pack 按当前层级的边界切分后尽量合并到同一条消息，超长的片段使用下一层级继续切分
+func pack(tokens []token, limit, level int) []string {
+	if level == splitRune {
+		return hardSplit(tokens, limit)
+	}
+
+	var parts []string
+	var current []token
+	currentLength := 0
+	flush := func() {
+		if part := strings.TrimSpace(join(current)); part != "" {
+			parts = append(parts, part)
+		}
+		current = nil
+		currentLength = 0
+	}
+
+	for _, segment := range segments(tokens, level) {
+		n := visibleLength(segment)
+		if n > limit {
+			flush()
+			parts = append(parts, pack(segment, limit, level+1)...)
+			continue
+		}
+		if currentLength+n > limit {
+			flush()
+		}
+		current = append(current, segment...)
+		currentLength += n
+	}
+	flush()
+
+	return parts
+}
+
+// segments 在格式标签之外的边界处切分，每个片段包含其后的分隔符
+func segments(tokens []token, level int) [][]token {
+	var result [][]token
+	start, depth := 0, 0
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].kind {
+		case openTagToken:
+			depth++
+			continue
+		case closeTagToken:
+			depth--
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+
+		if end := boundary(tokens, i, level); end > 0 {
+			result = append(result, tokens[start:end])
+			start = end
+			i = end - 1
+		}
+	}
+	if start < len(tokens) {
+		result = append(result, tokens[start:])
+	}
+	return result
+}
+
+// boundary 判断第 i 个 token 处是否为当前层级的分割点，是则返回片段结束位置，否则返回 -1
+func boundary(tokens []token, i, level int) int {
+	text := tokens[i].text
+	switch level {
+	case splitParagraph:
+		if text == "\n" && i+1 < len(tokens) && tokens[i+1].text == "\n" {
+			return skipSpace(tokens, i)
+		}
+	case splitSentence:
+		if text == "\n" {
+			return skipSpace(tokens, i)
+		}
+		if strings.Contains(sentenceTerminators, text) || strings.Contains(asciiTerminators, text) {
+			end := i + 1
+			for end < len(tokens) && strings.Contains(sentenceClosers, tokens[end].text) {
+				end++
+			}
+			// 英文标点后需要跟空白，避免在 "1.22"、"e.g" 等位置分割
+			if strings.Contains(asciiTerminators, text) && end < len(tokens) && !isSpace(tokens[end]) {
+				return -1
+			}
+			return skipSpace(tokens, end)
+		}
+	case splitWord:
+		if isSpace(tokens[i]) {
+			return skipSpace(tokens, i)
+		}
+	}
+	return -1
+}
+
+// skipSpace 返回从 i 开始跳过空白后的位置
+func skipSpace(tokens []token, i int) int {
+	for i < len(tokens) && isSpace(tokens[i]) {
+		i++
+	}
+	return i
+}
+
+func isSpace(tok token) bool {
+	return tok.kind == textToken && strings.TrimSpace(tok.text) == ""
+}
+
+// hardSplit 按字符强制分割，分割处关闭仍未闭合的标签并在下一条消息中重新打开
+func hardSplit(tokens []token, limit int) []string {
+	var parts []string
+	var current strings.Builder
+	var open []token
+	currentLength := 0
+
+	for _, tok := range tokens {
+		switch tok.kind {
+		case openTagToken:
+			open = append(open, tok)
+			current.WriteString(tok.text)
+			continue
+		case closeTagToken:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			current.WriteString(tok.text)
+			continue
+		}
+
+		if currentLength > 0 && currentLength+tok.size > limit {
+			for j := len(open) - 1; j >= 0; j-- {
+				current.WriteString(closingTag(open[j].text))
+			}
+			parts = append(parts, current.String())
+			current.Reset()
+			currentLength = 0
+			for _, tag := range open {
+				current.WriteString(tag.text)
+			}
+		}
+		current.WriteString(tok.text)
+		currentLength += tok.size
+	}
+	if currentLength > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
+
+// closingTag 根据开始标签生成对应的结束标签
+func closingTag(tag string) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(tag, "<"), ">")
+	if i := strings.IndexAny(name, " \t\n"); i >= 0 {
+		name = name[:i]
+	}
+	return "</" + name + ">"
+}
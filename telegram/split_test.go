@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertWellFormed 检查每条消息的长度不超过限制且标签成对出现
+func assertWellFormed(t *testing.T, parts []string, limit int) {
+	t.Helper()
+	for _, part := range parts {
+		assert.LessOrEqual(t, utf16Length(plainText(part)), limit, part)
+		depth := 0
+		for _, tok := range tokenize(part) {
+			switch tok.kind {
+			case openTagToken:
+				depth++
+			case closeTagToken:
+				depth--
+			}
+			require.GreaterOrEqual(t, depth, 0, part)
+		}
+		assert.Equal(t, 0, depth, part)
+	}
+}
+
+func TestSplitMessageShort(t *testing.T) {
+	assert.Equal(t, []string{"<b>短消息</b>"}, splitMessage("<b>短消息</b>", 20))
+}
+
+func TestSplitMessageCountsUTF16(t *testing.T) {
+	// 中文每个字符占一个编码单元，表情符号占两个，标签和转义字符按解析后的长度计算
+	assert.Equal(t, 3, utf16Length("中文字"))
+	assert.Equal(t, 2, utf16Length("🗞"))
+	assert.Equal(t, 6, visibleLength(tokenize(`<b>a&amp;b</b> 中文`)))
+
+	text := strings.Repeat("中", 30)
+	assert.Len(t, splitMessage(text, 30), 1)
+	assert.Len(t, splitMessage(text+"🗞", 31), 2)
+}
+
+func TestSplitMessageParagraphs(t *testing.T) {
+	paragraphs := []string{
+		"[1] " + `<a href="https://example.com/1"><b>第一个故事</b></a> ` + strings.Repeat("内容", 10),
+		"[2] " + `<a href="https://example.com/2"><b>第二个故事</b></a> ` + strings.Repeat("内容", 10),
+		"[3] " + `<a href="https://example.com/3"><b>第三个故事</b></a> ` + strings.Repeat("内容", 10),
+	}
+	parts := splitMessage(strings.Join(paragraphs, "\n\n"), 60)
+
+	require.Len(t, parts, 3)
+	assertWellFormed(t, parts, 60)
+	for i, part := range parts {
+		assert.Equal(t, fmt.Sprintf("%s\n\n(%d/3)", paragraphs[i], i+1), part)
+	}
+}
+
+func TestSplitMessageSentences(t *testing.T) {
+	chinese := "第一句话比较长一些。第二句话也不短！第三句话呢？"
+	parts := splitMessage(chinese, 22)
+	assertWellFormed(t, parts, 22)
+	require.Len(t, parts, 3)
+	assert.True(t, strings.HasPrefix(parts[0], "第一句话比较长一些。\n\n(1/3)"))
+
+	english := "Go 1.22 fixes loop variables. It also adds range over ints! Is it worth upgrading? Yes."
+	parts = splitMessage(english, 45)
+	assertWellFormed(t, parts, 45)
+	require.Len(t, parts, 3)
+	assert.Equal(t, "Go 1.22 fixes loop variables.\n\n(1/3)", parts[0])
+	assert.Equal(t, "It also adds range over ints!\n\n(2/3)", parts[1])
+	assert.Equal(t, "Is it worth upgrading? Yes.\n\n(3/3)", parts[2])
+}
+
+func TestSplitMessageWords(t *testing.T) {
+	text := strings.Repeat("word ", 20) + "end"
+	parts := splitMessage(text, 30)
+	assertWellFormed(t, parts, 30)
+	for _, part := range parts {
+		body, _, _ := strings.Cut(part, "\n\n")
+		for _, word := range strings.Fields(body) {
+			assert.Contains(t, []string{"word", "end"}, word)
+		}
+	}
+}
+
+func TestSplitMessageKeepsEntities(t *testing.T) {
+	// 链接文本中的句号和空格不能作为分割点
+	link := `<a href="https://example.com">Read more. Click here now</a>`
+	text := strings.Repeat("前文内容。", 4) + link + "。后文内容。"
+	parts := splitMessage(text, 40)
+	assertWellFormed(t, parts, 40)
+
+	found := false
+	for _, part := range parts {
+		if strings.Contains(part, link) {
+			found = true
+		}
+	}
+	assert.True(t, found, "link should not be split: %v", parts)
+}
+
+func TestSplitMessageHardSplitReopensTags(t *testing.T) {
+	text := "<b>" + strings.Repeat("长", 50) + "</b>"
+	parts := splitMessage(text, 32)
+	assertWellFormed(t, parts, 32)
+	require.Len(t, parts, 3)
+	assert.Equal(t, "<b>"+strings.Repeat("长", 20)+"</b>\n\n(1/3)", parts[0])
+	assert.Equal(t, "<b>"+strings.Repeat("长", 10)+"</b>\n\n(3/3)", parts[2])
+
+	// 不会在转义字符中间分割
+	parts = splitMessage(strings.Repeat("&amp;", 30), 20)
+	assertWellFormed(t, parts, 20)
+	for _, part := range parts {
+		assert.NotContains(t, strings.ReplaceAll(part, "&amp;", ""), "&")
+	}
+}
@@ -0,0 +1,41 @@
+package telegram
+
+import "time"
+
+// 每日总结各阶段的默认超时时间
+const (
+	defaultFetchStoriesTimeout = 2 * time.Minute
+	defaultFetchContentTimeout = 10 * time.Minute
+	defaultSummarizeTimeout    = 15 * time.Minute
+	defaultPublishTimeout      = 5 * time.Minute
+)
+
+// StageTimeouts 每日总结各阶段的超时时间，零值字段使用默认值
+type StageTimeouts struct {
+	FetchStories time.Duration // 获取热门故事列表
+	FetchContent time.Duration // 获取故事正文和评论
+	Summarize    time.Duration // AI 生成总结和详细总结
+	Publish      time.Duration // 发送到 Telegram，包括按订阅者语言翻译总结，其他渠道各自单独计时
+}
+
+// withDefaults 返回零值字段替换为默认值后的超时配置
+func (t StageTimeouts) withDefaults() StageTimeouts {
+	if t.FetchStories <= 0 {
+		t.FetchStories = defaultFetchStoriesTimeout
+	}
+	if t.FetchContent <= 0 {
+		t.FetchContent = defaultFetchContentTimeout
+	}
+	if t.Summarize <= 0 {
+		t.Summarize = defaultSummarizeTimeout
+	}
+	if t.Publish <= 0 {
+		t.Publish = defaultPublishTimeout
+	}
+	return t
+}
+
+// SetStageTimeouts 设置每日总结各阶段的超时时间
+func (b *Bot) SetStageTimeouts(timeouts StageTimeouts) {
+	b.timeouts = timeouts.withDefaults()
+}
@@ -0,0 +1,481 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/storage"
+)
+
+// defaultLanguage 总结原文的语言，订阅者选择该语言时不翻译
+const defaultLanguage = "zh"
+
+// 哪些聊天可以订阅
+const (
+	SubscribeApproval  = "approval"  // 允许列表中的聊天直接订阅，其他聊天需要管理员批准
+	SubscribeAllowlist = "allowlist" // 只有允许列表中的聊天可以订阅
+	SubscribeOpen      = "open"      // 任何聊天都可以直接订阅
+)
+
+const (
+	approvalRequestInterval = time.Hour      // 同一聊天重复请求订阅时，间隔该时间后才再次通知管理员
+	approvalExpiry          = 24 * time.Hour // 未被批准的订阅请求保留的时间
+	maxPendingApprovals     = 100            // 同时等待批准的订阅请求数量上限
+)
+
+// SubscriptionPolicy 哪些聊天可以订阅，零值时允许列表之外的聊天需要管理员批准。
+// 管理员即配置的默认聊天
+type SubscriptionPolicy struct {
+	Mode         string  // approval、allowlist 或 open，为空时为 approval
+	AllowedChats []int64 // 无需批准即可订阅的聊天
+}
+
+// Validate 检查订阅方式是否有效
+func (p SubscriptionPolicy) Validate() error {
+	switch p.Mode {
+	case "", SubscribeApproval, SubscribeAllowlist, SubscribeOpen:
+		return nil
+	default:
+		return fmt.Errorf("unknown subscription mode %q, available: %s, %s, %s", p.Mode, SubscribeApproval, SubscribeAllowlist, SubscribeOpen)
+	}
+}
+
+// allowed 判断聊天是否无需批准即可订阅
+func (p SubscriptionPolicy) allowed(chatID int64) bool {
+	return p.Mode == SubscribeOpen || slices.Contains(p.AllowedChats, chatID)
+}
+
+// subscriptionRequest 等待管理员批准的订阅请求，只保存在内存中
+type subscriptionRequest struct {
+	title       string
+	requestedAt time.Time
+}
+
+// SetSubscriptionPolicy 设置哪些聊天可以订阅，未设置时需要管理员批准
+func (b *Bot) SetSubscriptionPolicy(policy SubscriptionPolicy) {
+	b.subscription = policy
+}
+
+// isAuthorized 检查聊天是否可以使用机器人：默认聊天或已订阅的聊天
+func (b *Bot) isAuthorized(chatID int64) bool {
+	if chatID == b.chatID {
+		return true
+	}
+	_, err := b.store.GetSubscriber(chatID)
+	return err == nil
+}
+
+// handleSubscribe 处理 /subscribe 命令
+func (b *Bot) handleSubscribe(ctx context.Context, message *tgbotapi.Message) {
+	if _, err := b.store.GetSubscriber(message.Chat.ID); err == nil {
+		b.sendReply(ctx, message, "ℹ️ 当前聊天已订阅，发送 /settings 查看推送设置。")
+		return
+	}
+
+	switch {
+	case message.Chat.ID == b.chatID || b.subscription.allowed(message.Chat.ID):
+	case b.subscription.Mode == SubscribeAllowlist:
+		b.sendReply(ctx, message, "❌ 当前聊天不在允许订阅的列表中，请联系管理员。")
+		return
+	default:
+		b.requestApproval(ctx, message)
+		return
+	}
+
+	if err := b.subscribe(message.Chat.ID, chatTitle(message.Chat)); err != nil {
+		b.sendReply(ctx, message, fmt.Sprintf("❌ 订阅失败: %v", err))
+		return
+	}
+	b.sendReply(ctx, message, "✅ 订阅成功！将按默认时间推送每日热点，发送 /settings 查看或修改推送设置。")
+}
+
+// subscribe 以默认设置保存订阅者
+func (b *Bot) subscribe(chatID int64, title string) error {
+	subscriber := &storage.Subscriber{
+		ChatID:    chatID,
+		Title:     title,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := b.store.SaveSubscriber(subscriber); err != nil {
+		log.Printf("Failed to save subscriber %d: %v", chatID, err)
+		return err
+	}
+
+	log.Printf("Chat %d (%s) subscribed", subscriber.ChatID, subscriber.Title)
+	return nil
+}
+
+// requestApproval 将订阅请求发送给管理员，同一聊天在间隔时间内重复请求时不再通知；
+// 等待批准的请求达到上限时拒绝新的请求，避免大量请求占用内存和打扰管理员
+func (b *Bot) requestApproval(ctx context.Context, message *tgbotapi.Message) {
+	chatID, title := message.Chat.ID, chatTitle(message.Chat)
+	now := time.Now()
+
+	b.approvalsMu.Lock()
+	if b.approvals == nil {
+		b.approvals = make(map[int64]subscriptionRequest)
+	}
+	for id, request := range b.approvals {
+		if now.Sub(request.requestedAt) >= approvalExpiry {
+			delete(b.approvals, id)
+		}
+	}
+	previous, pending := b.approvals[chatID]
+	full := !pending && len(b.approvals) >= maxPendingApprovals
+	notify := !full && (!pending || now.Sub(previous.requestedAt) >= approvalRequestInterval)
+	if notify {
+		b.approvals[chatID] = subscriptionRequest{title: title, requestedAt: now}
+	}
+	b.approvalsMu.Unlock()
+
+	if full {
+		log.Printf("Too many pending subscription requests, rejecting chat %d (%s)", chatID, title)
+		b.sendReply(ctx, message, "❌ 等待批准的订阅请求过多，请稍后再试。")
+		return
+	}
+
+	if notify {
+		log.Printf("Chat %d (%s) requested subscription", chatID, title)
+		request := fmt.Sprintf("📝 聊天 %d（%s）请求订阅每日推送，发送 /approve %d 批准。", chatID, title, chatID)
+		if err := b.sendMessage(ctx, request); err != nil {
+			log.Printf("Failed to send subscription request of chat %d: %v", chatID, err)
+			b.sendReply(ctx, message, "❌ 发送订阅请求失败，请稍后重试。")
+			return
+		}
+	}
+	b.sendReply(ctx, message, "⏳ 已将订阅请求发送给管理员，批准后即可收到每日推送。")
+}
+
+// handleApprove 处理管理员的 /approve <聊天 ID> 命令，批准聊天的订阅请求
+func (b *Bot) handleApprove(ctx context.Context, message *tgbotapi.Message) {
+	if message.Chat.ID != b.chatID {
+		b.sendReply(ctx, message, "❌ 只有管理员可以批准订阅。")
+		return
+	}
+
+	chatID, err := strconv.ParseInt(strings.TrimSpace(message.CommandArguments()), 10, 64)
+	if err != nil {
+		b.sendReply(ctx, message, "用法: /approve <聊天 ID>，例如 /approve 123456789")
+		return
+	}
+	if _, err := b.store.GetSubscriber(chatID); err == nil {
+		b.sendReply(ctx, message, fmt.Sprintf("ℹ️ 聊天 %d 已订阅。", chatID))
+		return
+	}
+
+	b.approvalsMu.Lock()
+	request := b.approvals[chatID]
+	delete(b.approvals, chatID)
+	b.approvalsMu.Unlock()
+
+	if err := b.subscribe(chatID, request.title); err != nil {
+		b.sendReply(ctx, message, fmt.Sprintf("❌ 批准订阅失败: %v", err))
+		return
+	}
+	b.sendReply(ctx, message, fmt.Sprintf("✅ 已批准聊天 %d 的订阅。", chatID))
+	if err := b.sendMessageTo(ctx, chatID, "✅ 管理员已批准订阅！将按默认时间推送每日热点，发送 /settings 查看或修改推送设置。"); err != nil {
+		log.Printf("Failed to notify chat %d of approval: %v", chatID, err)
+	}
+}
+
+// handleUnsubscribe 处理 /unsubscribe 命令
+func (b *Bot) handleUnsubscribe(ctx context.Context, message *tgbotapi.Message) {
+	err := b.store.DeleteSubscriber(message.Chat.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		b.sendReply(ctx, message, "ℹ️ 当前聊天尚未订阅。")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete subscriber %d: %v", message.Chat.ID, err)
+		b.sendReply(ctx, message, fmt.Sprintf("❌ 取消订阅失败: %v", err))
+		return
+	}
+
+	log.Printf("Chat %d unsubscribed", message.Chat.ID)
+	b.sendReply(ctx, message, "✅ 已取消订阅，不会再收到每日推送。")
+}
+
+// handleSettings 处理 /settings 命令
+func (b *Bot) handleSettings(ctx context.Context, message *tgbotapi.Message) {
+	subscriber, err := b.store.GetSubscriber(message.Chat.ID)
+	if err != nil {
+		b.sendReply(ctx, message, "ℹ️ 当前聊天尚未订阅，发送 /subscribe 订阅每日推送。")
+		return
+	}
+	b.sendReply(ctx, message, formatSettings(subscriber))
+}
+
+// handleSet 处理 /set 命令，格式为 /set <time|count|lang|keywords> <值>
+func (b *Bot) handleSet(ctx context.Context, message *tgbotapi.Message) {
+	subscriber, err := b.store.GetSubscriber(message.Chat.ID)
+	if err != nil {
+		b.sendReply(ctx, message, "ℹ️ 当前聊天尚未订阅，发送 /subscribe 订阅每日推送。")
+		return
+	}
+
+	fields := strings.Fields(message.CommandArguments())
+	if len(fields) == 0 {
+		b.sendReply(ctx, message, "用法: /set <time|count|lang|keywords> <值>，例如 /set time 09:00")
+		return
+	}
+
+	if err := applySetting(subscriber, fields[0], strings.Join(fields[1:], " ")); err != nil {
+		b.sendReply(ctx, message, fmt.Sprintf("❌ %v", err))
+		return
+	}
+
+	if err := b.store.SaveSubscriber(subscriber); err != nil {
+		log.Printf("Failed to save subscriber %d: %v", message.Chat.ID, err)
+		b.sendReply(ctx, message, fmt.Sprintf("❌ 保存设置失败: %v", err))
+		return
+	}
+
+	b.sendReply(ctx, message, "✅ 设置已更新\n\n"+formatSettings(subscriber))
+}
+
+// applySetting 修改订阅者的单项设置
+func applySetting(subscriber *storage.Subscriber, key, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch strings.ToLower(key) {
+	case "time":
+		if value == "" || strings.EqualFold(value, "default") {
+			subscriber.DeliveryTime = ""
+			return nil
+		}
+		t, err := time.Parse("15:04", value)
+		if err != nil {
+			return fmt.Errorf("推送时间格式应为 HH:MM，例如 09:00")
+		}
+		subscriber.DeliveryTime = t.Format("15:04")
+	case "count":
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			return fmt.Errorf("故事数量应为非负整数，0 表示全部")
+		}
+		subscriber.MaxStories = count
+	case "lang", "language":
+		subscriber.Language = strings.ToLower(value)
+		if subscriber.Language == defaultLanguage {
+			subscriber.Language = ""
+		}
+	case "keywords":
+		subscriber.Keywords = nil
+		for _, keyword := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '，' }) {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				subscriber.Keywords = append(subscriber.Keywords, keyword)
+			}
+		}
+	default:
+		return fmt.Errorf("未知的设置项: %s，可选 time、count、lang、keywords", key)
+	}
+	return nil
+}
+
+// formatSettings 格式化订阅者的推送设置
+func formatSettings(subscriber *storage.Subscriber) string {
+	deliveryTime := subscriber.DeliveryTime
+	if deliveryTime == "" {
+		deliveryTime = "默认"
+	}
+	count := "全部"
+	if subscriber.MaxStories > 0 {
+		count = strconv.Itoa(subscriber.MaxStories)
+	}
+	language := subscriber.Language
+	if language == "" {
+		language = defaultLanguage
+	}
+	keywords := "无"
+	if len(subscriber.Keywords) > 0 {
+		keywords = strings.Join(subscriber.Keywords, ", ")
+	}
+
+	return fmt.Sprintf("⚙️ 推送设置\n- 推送时间: %s\n- 故事数量: %s\n- 总结语言: %s\n- 关键词: %s",
+		deliveryTime, count, language, keywords)
+}
+
+// DeliverScheduledDigests 向推送时间与 now 相同（精确到分钟）的订阅者发送当日总结，
+// 推送时间按 now 所在的时区比较。生成总结耗时较长时下一分钟的任务等待同一次生成，
+// 之后只向自己这一分钟的订阅者发送
+func (b *Bot) DeliverScheduledDigests(ctx context.Context, now time.Time) error {
+	subscribers, err := b.store.ListSubscribers()
+	if err != nil {
+		return fmt.Errorf("failed to list subscribers: %w", err)
+	}
+
+	current := now.Format("15:04")
+	var due bool
+	for _, subscriber := range subscribers {
+		if subscriber.DeliveryTime == current {
+			due = true
+			break
+		}
+	}
+	if !due {
+		return nil
+	}
+
+	date := now.Format("2006-01-02")
+	summary, err := b.scheduledSummary(ctx, date)
+	if err != nil {
+		return fmt.Errorf("failed to get daily summary for %s: %w", date, err)
+	}
+	if summary == nil {
+		return nil
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, b.timeouts.Publish)
+	defer cancel()
+	b.deliverToSubscribers(publishCtx, summary, func(subscriber *storage.Subscriber) bool {
+		return subscriber.DeliveryTime == current
+	})
+	return nil
+}
+
+// scheduledSummary 优先使用已生成的当日总结，否则生成截止到当前时间的总结；
+// 同时只有一个推送任务生成总结，其他任务等待后读取已保存的结果
+func (b *Bot) scheduledSummary(ctx context.Context, date string) (*hackernews.DailySummaryWithNumbers, error) {
+	b.scheduledMu.Lock()
+	defer b.scheduledMu.Unlock()
+
+	summary, err := b.store.GetDailySummary(date)
+	if errors.Is(err, storage.ErrNotFound) {
+		return b.GenerateDailySummary(ctx, "", b.maxStories)
+	}
+	return summary, err
+}
+
+// deliverToSubscribers 按各自偏好向满足条件的订阅者发送总结，单个订阅者失败不影响其他订阅者
+func (b *Bot) deliverToSubscribers(ctx context.Context, summary *hackernews.DailySummaryWithNumbers, match func(*storage.Subscriber) bool) {
+	subscribers, err := b.store.ListSubscribers()
+	if err != nil {
+		log.Printf("Failed to list subscribers: %v", err)
+		return
+	}
+
+	// 同一轮推送中相同语言只翻译一次
+	translations := make(map[string][]hackernews.StoryWithNumber)
+	for _, subscriber := range subscribers {
+		if ctx.Err() != nil {
+			log.Printf("Stopped delivering summary: %v", ctx.Err())
+			return
+		}
+		if !match(subscriber) {
+			continue
+		}
+		if err := b.deliverTo(ctx, subscriber.ChatID, summary, translations, true); err != nil {
+			log.Printf("Failed to deliver summary to chat %d: %v", subscriber.ChatID, err)
+		}
+	}
+}
+
+// deliverTo 按聊天的订阅偏好向其发送总结，未订阅的聊天发送完整总结；
+// dedupe 为 true 时按去重策略处理之前已发送到该聊天的故事
+func (b *Bot) deliverTo(ctx context.Context, chatID int64, summary *hackernews.DailySummaryWithNumbers, translations map[string][]hackernews.StoryWithNumber, dedupe bool) error {
+	subscriber, err := b.store.GetSubscriber(chatID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to get subscriber: %w", err)
+	}
+
+	personalize := func(candidates *hackernews.DailySummaryWithNumbers) (*hackernews.DailySummaryWithNumbers, error) {
+		if subscriber == nil {
+			return candidates, nil
+		}
+
+		personalized := filterSummary(candidates, subscriber)
+		if len(personalized.StorySummaries) == 0 {
+			log.Printf("No stories match preferences of chat %d", chatID)
+			return nil, nil
+		}
+
+		// 翻译完整总结，同一语言的译文供所有聊天共用
+		if subscriber.Language != "" && subscriber.Language != defaultLanguage {
+			translated, ok := translations[subscriber.Language]
+			if !ok {
+				var err error
+				translated, err = b.aiClient.TranslateSummaries(ctx, summary.StorySummaries, subscriber.Language)
+				if err != nil {
+					log.Printf("Failed to translate summary to %s, sending original: %v", subscriber.Language, err)
+				}
+				translations[subscriber.Language] = translated
+			}
+			personalized.StorySummaries = replaceSummaries(personalized.StorySummaries, translated)
+		}
+		return personalized, nil
+	}
+
+	if dedupe {
+		return b.sendDeduped(ctx, chatID, summary, personalize)
+	}
+	personalized, err := personalize(summary)
+	if err != nil || personalized == nil {
+		return err
+	}
+	return b.sendDigest(ctx, chatID, personalized)
+}
+
+// filterSummary 按关键词和数量筛选故事，保留原始编号以便回复编号查看详情
+func filterSummary(summary *hackernews.DailySummaryWithNumbers, subscriber *storage.Subscriber) *hackernews.DailySummaryWithNumbers {
+	filtered := *summary
+	filtered.StorySummaries = nil
+
+	for _, storySummary := range summary.StorySummaries {
+		if subscriber.MaxStories > 0 && len(filtered.StorySummaries) >= subscriber.MaxStories {
+			break
+		}
+		if len(subscriber.Keywords) > 0 && !matchesKeywords(storySummary, subscriber.Keywords) {
+			continue
+		}
+		filtered.StorySummaries = append(filtered.StorySummaries, storySummary)
+	}
+	return &filtered
+}
+
+// matchesKeywords 检查故事标题、总结或标签是否包含任一关键词（不区分大小写）
+func matchesKeywords(storySummary hackernews.StoryWithNumber, keywords []string) bool {
+	text := strings.ToLower(storySummary.Title + " " + storySummary.Summary + " " + strings.Join(storySummary.Tags, " "))
+	for _, keyword := range keywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceSummaries 用译文替换同编号故事的总结
+func replaceSummaries(summaries, translated []hackernews.StoryWithNumber) []hackernews.StoryWithNumber {
+	byNumber := make(map[int]string, len(translated))
+	for _, storySummary := range translated {
+		byNumber[storySummary.Number] = storySummary.Summary
+	}
+
+	replaced := make([]hackernews.StoryWithNumber, len(summaries))
+	for i, storySummary := range summaries {
+		replaced[i] = storySummary
+		if text, ok := byNumber[storySummary.Number]; ok {
+			replaced[i].Summary = text
+		}
+	}
+	return replaced
+}
+
+// chatTitle 返回群组名称或用户名
+func chatTitle(chat *tgbotapi.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	if chat.UserName != "" {
+		return "@" + chat.UserName
+	}
+	return strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+}
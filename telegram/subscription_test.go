@@ -0,0 +1,209 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hacker-news-daily/ai/aitest"
+	"hacker-news-daily/hackernews"
+	"hacker-news-daily/hackernews/hntest"
+	"hacker-news-daily/storage"
+)
+
+func TestApplySetting(t *testing.T) {
+	subscriber := &storage.Subscriber{ChatID: 1}
+
+	require.NoError(t, applySetting(subscriber, "time", "9:05"))
+	assert.Equal(t, "09:05", subscriber.DeliveryTime)
+	require.NoError(t, applySetting(subscriber, "time", "default"))
+	assert.Empty(t, subscriber.DeliveryTime)
+	assert.Error(t, applySetting(subscriber, "time", "25:00"))
+
+	require.NoError(t, applySetting(subscriber, "count", "5"))
+	assert.Equal(t, 5, subscriber.MaxStories)
+	assert.Error(t, applySetting(subscriber, "count", "-1"))
+
+	require.NoError(t, applySetting(subscriber, "lang", "EN"))
+	assert.Equal(t, "en", subscriber.Language)
+	require.NoError(t, applySetting(subscriber, "lang", "zh"))
+	assert.Empty(t, subscriber.Language)
+
+	require.NoError(t, applySetting(subscriber, "keywords", "Go, rust，AI"))
+	assert.Equal(t, []string{"Go", "rust", "AI"}, subscriber.Keywords)
+	require.NoError(t, applySetting(subscriber, "keywords", ""))
+	assert.Empty(t, subscriber.Keywords)
+
+	assert.Error(t, applySetting(subscriber, "color", "red"))
+}
+
+func TestFilterSummary(t *testing.T) {
+	summary := &hackernews.DailySummaryWithNumbers{
+		Date: "2024-01-15",
+		StorySummaries: []hackernews.StoryWithNumber{
+			{Number: 1, Title: "Go 1.22 Released", Summary: "新版本发布"},
+			{Number: 2, Title: "SQLite Internals", Summary: "数据库存储结构", Tags: []string{"database"}},
+			{Number: 3, Title: "Rust in the Kernel", Summary: "Linux 内核引入 Rust"},
+		},
+	}
+
+	filtered := filterSummary(summary, &storage.Subscriber{Keywords: []string{"rust", "DATABASE"}})
+	require.Len(t, filtered.StorySummaries, 2)
+	assert.Equal(t, 2, filtered.StorySummaries[0].Number)
+	assert.Equal(t, 3, filtered.StorySummaries[1].Number)
+
+	filtered = filterSummary(summary, &storage.Subscriber{MaxStories: 2})
+	assert.Len(t, filtered.StorySummaries, 2)
+
+	// 原始总结不应被修改
+	assert.Len(t, summary.StorySummaries, 3)
+}
+
+func TestReplaceSummaries(t *testing.T) {
+	replaced := replaceSummaries(
+		[]hackernews.StoryWithNumber{{Number: 1, Summary: "原文1"}, {Number: 3, Summary: "原文3"}},
+		[]hackernews.StoryWithNumber{{Number: 1, Summary: "translation 1"}, {Number: 2, Summary: "translation 2"}},
+	)
+	assert.Equal(t, "translation 1", replaced[0].Summary)
+	assert.Equal(t, "原文3", replaced[1].Summary)
+}
+
+// chatCommand 构造指定聊天中的命令消息
+func chatCommand(chatID int64, text string) tgbotapi.Update {
+	message := newCommand(text)
+	message.Chat = &tgbotapi.Chat{ID: chatID, Title: "测试群"}
+	return tgbotapi.Update{Message: message}
+}
+
+func TestSubscribeRequiresApproval(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	ctx := context.Background()
+
+	// 请求转发给管理员，重复请求不再通知
+	bot.HandleUserMessage(ctx, chatCommand(2, "/subscribe"))
+	bot.HandleUserMessage(ctx, chatCommand(2, "/subscribe"))
+	sent := fake.sent()
+	require.Len(t, sent, 3)
+	assert.Equal(t, "1", sent[0].Params["chat_id"])
+	assert.Contains(t, sent[0].Params["text"], "/approve 2")
+	assert.Contains(t, sent[1].Params["text"], "已将订阅请求发送给管理员")
+	assert.False(t, bot.isAuthorized(2))
+
+	// 只有管理员可以批准
+	bot.HandleUserMessage(ctx, chatCommand(3, "/approve 2"))
+	assert.Contains(t, fake.sent()[3].Params["text"], "只有管理员可以批准订阅")
+	assert.False(t, bot.isAuthorized(2))
+
+	bot.HandleUserMessage(ctx, chatCommand(1, "/approve 2"))
+	subscriber, err := bot.store.GetSubscriber(2)
+	require.NoError(t, err)
+	assert.Equal(t, "测试群", subscriber.Title)
+	sent = fake.sent()
+	require.Len(t, sent, 6)
+	assert.Contains(t, sent[4].Params["text"], "已批准聊天 2 的订阅")
+	assert.Equal(t, "2", sent[5].Params["chat_id"])
+	assert.Contains(t, sent[5].Params["text"], "管理员已批准订阅")
+}
+
+func TestSubscribeApprovalLimit(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	ctx := context.Background()
+
+	// 等待批准的请求达到上限时拒绝新的请求，不通知管理员
+	bot.approvals = make(map[int64]subscriptionRequest)
+	for i := range maxPendingApprovals {
+		bot.approvals[int64(100+i)] = subscriptionRequest{requestedAt: time.Now()}
+	}
+	bot.HandleUserMessage(ctx, chatCommand(2, "/subscribe"))
+	sent := fake.sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "2", sent[0].Params["chat_id"])
+	assert.Contains(t, sent[0].Params["text"], "等待批准的订阅请求过多")
+
+	// 过期的请求被清理后可以再次请求
+	bot.approvals[100] = subscriptionRequest{requestedAt: time.Now().Add(-approvalExpiry)}
+	bot.HandleUserMessage(ctx, chatCommand(2, "/subscribe"))
+	sent = fake.sent()
+	require.Len(t, sent, 3)
+	assert.Contains(t, sent[1].Params["text"], "/approve 2")
+	assert.Len(t, bot.approvals, maxPendingApprovals)
+}
+
+func TestSubscriptionPolicy(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	ctx := context.Background()
+
+	bot.SetSubscriptionPolicy(SubscriptionPolicy{Mode: SubscribeAllowlist, AllowedChats: []int64{2}})
+	bot.HandleUserMessage(ctx, chatCommand(2, "/subscribe"))
+	assert.True(t, bot.isAuthorized(2))
+	bot.HandleUserMessage(ctx, chatCommand(3, "/subscribe"))
+	assert.False(t, bot.isAuthorized(3))
+	assert.Contains(t, fake.sent()[1].Params["text"], "不在允许订阅的列表中")
+
+	bot.SetSubscriptionPolicy(SubscriptionPolicy{Mode: SubscribeOpen})
+	bot.HandleUserMessage(ctx, chatCommand(3, "/subscribe"))
+	assert.True(t, bot.isAuthorized(3))
+
+	assert.NoError(t, SubscriptionPolicy{}.Validate())
+	assert.ErrorContains(t, SubscriptionPolicy{Mode: "anyone"}.Validate(), "unknown subscription mode")
+}
+
+func TestResendIsAdminOnly(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newTestBot(t, fake)
+	require.NoError(t, bot.store.SaveSubscriber(&storage.Subscriber{ChatID: 2}))
+
+	update := chatCommand(2, "resend")
+	update.Message.Entities = nil
+	bot.HandleUserMessage(context.Background(), update)
+
+	sent := fake.sent()
+	require.Len(t, sent, 1)
+	assert.Contains(t, sent[0].Params["text"], "只有管理员可以重新生成总结")
+}
+
+func TestScheduledDeliveriesShareGeneration(t *testing.T) {
+	hnServer := hntest.NewServer(t)
+	hnServer.AddStory(hntest.Item{ID: 1000, Title: "Rust in the kernel", By: "author", Score: 100, Time: time.Now().Add(-time.Hour).Unix(), Text: "story text"})
+	aiServer := aitest.NewServer(t, func(req aitest.Request) string {
+		// 生成较慢，下一分钟的任务在生成完成前开始
+		time.Sleep(50 * time.Millisecond)
+		return summarizeResponder(req)
+	})
+	fake := &fakeTelegram{}
+	bot := newPipelineBot(t, fake, hnServer.URL, aiServer.URL, http.DefaultTransport)
+	bot.maxStories = 5
+
+	now := time.Now().UTC()
+	next := now.Add(time.Minute)
+	if next.Format("2006-01-02") != now.Format("2006-01-02") {
+		now, next = now.Add(-time.Minute), now
+	}
+	require.NoError(t, bot.store.SaveSubscriber(&storage.Subscriber{ChatID: 2, DeliveryTime: now.Format("15:04")}))
+	require.NoError(t, bot.store.SaveSubscriber(&storage.Subscriber{ChatID: 3, DeliveryTime: next.Format("15:04")}))
+
+	var wg sync.WaitGroup
+	for _, at := range []time.Time{now, next} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, bot.DeliverScheduledDigests(context.Background(), at))
+		}()
+	}
+	wg.Wait()
+
+	// 只生成一次总结，每个订阅者各收到一次
+	assert.Len(t, aiServer.Requests(), 1)
+	sent := fake.sent()
+	require.Len(t, sent, 2)
+	assert.ElementsMatch(t, []string{"2", "3"}, []string{sent[0].Params["chat_id"], sent[1].Params["chat_id"]})
+}